@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"time"
+)
+
+// A lock held by the array store.
+type arrayLockEntry struct {
+	// The owner of the lock.
+	owner string
+	// The time the lock expires, the zero time never expires.
+	expiresAt time.Time
+}
+
+type ArrayLock struct {
+	*Lock
+	// The parent array cache store.
+	store *ArrayStore
+}
+
+// Create a new lock instance.
+func NewArrayLock(store *ArrayStore, name string, seconds int, owner ...string) *ArrayLock {
+	lock := &ArrayLock{Lock: newLock(name, seconds, owner...), store: store}
+
+	lock.driver = lock
+
+	return lock
+}
+
+// Attempt to acquire the lock.
+func (l *ArrayLock) acquire() bool {
+	l.store.mutex.Lock()
+	defer l.store.mutex.Unlock()
+
+	now := l.store.currentTime()
+
+	if entry, exists := l.store.locks[l.name]; exists && (entry.expiresAt.IsZero() || now.Before(entry.expiresAt)) {
+		return false
+	}
+
+	entry := arrayLockEntry{owner: l.owner}
+
+	if l.seconds > 0 {
+		entry.expiresAt = now.Add(time.Duration(l.seconds) * time.Second)
+	}
+
+	l.store.locks[l.name] = entry
+
+	return true
+}
+
+// Release the lock.
+func (l *ArrayLock) release() bool {
+	l.store.mutex.Lock()
+	defer l.store.mutex.Unlock()
+
+	if entry, exists := l.store.locks[l.name]; !exists || entry.owner != l.owner {
+		return false
+	}
+
+	delete(l.store.locks, l.name)
+
+	return true
+}
+
+// Returns the owner value written into the driver for this lock.
+func (l *ArrayLock) getCurrentOwner() string {
+	l.store.mutex.RLock()
+	defer l.store.mutex.RUnlock()
+
+	return l.store.locks[l.name].owner
+}
+
+// Releases this lock in disregard of ownership.
+func (l *ArrayLock) forceRelease() {
+	l.store.mutex.Lock()
+	defer l.store.mutex.Unlock()
+
+	delete(l.store.locks, l.name)
+}
@@ -0,0 +1,412 @@
+package cache
+
+import (
+	"sync"
+	"time"
+
+	contracts "github.com/garavel-core/framework/contracts/cache"
+)
+
+// An item held by the array store.
+type arrayItem struct {
+	// The stored value, serialized when the store serializes values.
+	value any
+	// The time the item expires, the zero time never expires.
+	expiresAt time.Time
+}
+
+// Determine if the item has expired at the given time.
+func (i arrayItem) expired(now time.Time) bool {
+	return !i.expiresAt.IsZero() && !now.Before(i.expiresAt)
+}
+
+type ArrayStore struct {
+	interactsWithTime
+	// The array of stored values.
+	storage map[string]arrayItem
+	// The array of locks.
+	locks map[string]arrayLockEntry
+	// Guards the storage against concurrent access.
+	mutex sync.RWMutex
+	// Indicates if values are serialized within the store.
+	serializesValues bool
+	// The serializer of the stored values.
+	serializer contracts.Serializer
+}
+
+// Create a new Array store.
+func NewArrayStore(serializesValues ...bool) *ArrayStore {
+	return &ArrayStore{
+		storage:          make(map[string]arrayItem),
+		locks:            make(map[string]arrayLockEntry),
+		serializesValues: len(serializesValues) != 0 && serializesValues[0],
+		serializer:       GobSerializer{},
+	}
+}
+
+// Set the clock the store reads the current time from, the system clock when nil.
+func (s *ArrayStore) SetClock(clock func() time.Time) *ArrayStore {
+	s.clock = clock
+
+	return s
+}
+
+// Retrieve an item from the cache by key.
+func (s *ArrayStore) Get(key string) any {
+	s.mutex.RLock()
+	item, exists := s.storage[key]
+	s.mutex.RUnlock()
+
+	if !exists {
+		return nil
+	}
+
+	if item.expired(s.currentTime()) {
+		s.forgetExpired(key)
+
+		return nil
+	}
+
+	return s.unpack(item.value)
+}
+
+// Retrieve multiple items from the cache by key.
+//
+// Items not found in the cache will have a null value.
+func (s *ArrayStore) Many(keys []string) map[string]any {
+	results := make(map[string]any, len(keys))
+
+	for _, key := range keys {
+		results[key] = s.Get(key)
+	}
+
+	return results
+}
+
+// Store an item in the cache for a given number of seconds.
+func (s *ArrayStore) Put(key string, value any, seconds int) bool {
+	return s.put(key, value, s.calculateExpiration(seconds))
+}
+
+// Store multiple items in the cache for a given number of seconds.
+func (s *ArrayStore) PutMany(values map[string]any, seconds int) bool {
+	expiresAt := s.calculateExpiration(seconds)
+	result := true
+
+	for key, value := range values {
+		if !s.put(key, value, expiresAt) {
+			result = false
+		}
+	}
+
+	return result
+}
+
+// Store an item in the cache for a given duration, keeping sub-second precision.
+func (s *ArrayStore) PutFor(key string, value any, ttl time.Duration) bool {
+	return s.put(key, value, s.currentTime().Add(ttl))
+}
+
+// Store multiple items in the cache for a given duration, keeping sub-second precision.
+func (s *ArrayStore) PutManyFor(values map[string]any, ttl time.Duration) bool {
+	expiresAt := s.currentTime().Add(ttl)
+	result := true
+
+	for key, value := range values {
+		if !s.put(key, value, expiresAt) {
+			result = false
+		}
+	}
+
+	return result
+}
+
+// Store an item in the cache if the key does not exist.
+func (s *ArrayStore) Add(key string, value any, ttl ...any) bool {
+	duration, ok := s.ttlDuration(ttl...)
+
+	if !ok {
+		return false
+	}
+
+	packed, ok := s.pack(value)
+
+	if !ok {
+		return false
+	}
+
+	var expiresAt time.Time
+
+	if duration > 0 {
+		expiresAt = s.currentTime().Add(duration)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if item, exists := s.storage[key]; exists && !item.expired(s.currentTime()) {
+		return false
+	}
+
+	s.storage[key] = arrayItem{value: packed, expiresAt: expiresAt}
+
+	return true
+}
+
+// Increment the value of an item in the cache.
+func (s *ArrayStore) Increment(key string, value ...any) any {
+	step := incrementStep(value...)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if item, exists := s.storage[key]; exists && !item.expired(s.currentTime()) {
+		current, _ := toInt(s.unpack(item.value))
+		incremented := current + step
+
+		if packed, ok := s.pack(incremented); ok {
+			item.value = packed
+			s.storage[key] = item
+		}
+
+		return incremented
+	}
+
+	if packed, ok := s.pack(step); ok {
+		s.storage[key] = arrayItem{value: packed}
+	}
+
+	return step
+}
+
+// Decrement the value of an item in the cache.
+func (s *ArrayStore) Decrement(key string, value ...any) any {
+	return s.Increment(key, incrementStep(value...)*-1)
+}
+
+// Add the given step to an integer counter and return the new value.
+func (s *ArrayStore) IncrementBy(key string, value int64) (int64, error) {
+	return integerResult(s.count(key, incrementBy(value)))
+}
+
+// Subtract the given step from an integer counter, stopping at zero when floor is set.
+func (s *ArrayStore) DecrementBy(key string, value int64, floor bool) (int64, error) {
+	return integerResult(s.count(key, decrementBy(value, floor)))
+}
+
+// Add the given step to a floating point counter and return the new value.
+func (s *ArrayStore) IncrementFloat(key string, value float64) (float64, error) {
+	return floatResult(s.count(key, incrementFloatBy(value)))
+}
+
+// Apply a counter change to an item while holding the storage lock.
+func (s *ArrayStore) count(key string, change counterChange) (any, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	item, exists := s.storage[key]
+
+	if exists && item.expired(s.currentTime()) {
+		item, exists = arrayItem{}, false
+	}
+
+	var current any
+
+	if exists {
+		current = s.unpack(item.value)
+	}
+
+	result, err := change(current, exists)
+
+	if err != nil {
+		return nil, err
+	}
+
+	packed, ok := s.pack(result)
+
+	if !ok {
+		return nil, ErrStoreFailed
+	}
+
+	item.value = packed
+	s.storage[key] = item
+
+	return result, nil
+}
+
+// Store an item in the cache indefinitely.
+func (s *ArrayStore) Forever(key string, value any) bool {
+	return s.Put(key, value, 0)
+}
+
+// Remove an item from the cache.
+func (s *ArrayStore) Forget(key string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.storage[key]; exists {
+		delete(s.storage, key)
+
+		return true
+	}
+
+	return false
+}
+
+// Remove all items from the cache.
+func (s *ArrayStore) Flush() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.storage = make(map[string]arrayItem)
+
+	return true
+}
+
+// Begin executing a new tags operation.
+func (s *ArrayStore) Tags(names ...string) contracts.TaggedCache {
+	return NewTaggedCache(s, NewTagSet(s, names))
+}
+
+// Get a lock instance.
+func (s *ArrayStore) Lock(name string, seconds int, owner ...string) contracts.Lock {
+	return NewArrayLock(s, name, seconds, owner...)
+}
+
+// Restore a lock instance using the owner identifier.
+func (s *ArrayStore) RestoreLock(name string, owner string) contracts.Lock {
+	return s.Lock(name, 0, owner)
+}
+
+// Get the serializer of the stored values.
+func (s *ArrayStore) GetSerializer() contracts.Serializer {
+	return s.serializer
+}
+
+// Set the serializer of the stored values.
+func (s *ArrayStore) SetSerializer(serializer contracts.Serializer) *ArrayStore {
+	s.serializer = serializer
+
+	return s
+}
+
+// Get the cache key prefix.
+func (s *ArrayStore) GetPrefix() string {
+	return ""
+}
+
+// Remove every expired item from the cache and return how many were removed.
+func (s *ArrayStore) Prune() int {
+	now := s.currentTime()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	removed := 0
+
+	for key, item := range s.storage {
+		if item.expired(now) {
+			delete(s.storage, key)
+			removed++
+		}
+	}
+
+	return removed
+}
+
+// Prune expired items in the background on the given interval.
+//
+// Expired items are always hidden from readers, pruning only reclaims the
+// memory held by items nobody asks for again. Call the returned function to
+// stop the background pruning.
+func (s *ArrayStore) PruneEvery(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				s.Prune()
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			close(done)
+		})
+	}
+}
+
+// Store the packed value under the given key.
+func (s *ArrayStore) put(key string, value any, expiresAt time.Time) bool {
+	packed, ok := s.pack(value)
+
+	if !ok {
+		return false
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.storage[key] = arrayItem{value: packed, expiresAt: expiresAt}
+
+	return true
+}
+
+// Remove the given key if it is still expired.
+func (s *ArrayStore) forgetExpired(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if item, exists := s.storage[key]; exists && item.expired(s.currentTime()) {
+		delete(s.storage, key)
+	}
+}
+
+// Serialize the value when the store serializes values.
+func (s *ArrayStore) pack(value any) (any, bool) {
+	if !s.serializesValues {
+		return value, true
+	}
+
+	data, err := s.serializer.Serialize(value)
+
+	return data, err == nil
+}
+
+// Unserialize the value when the store serializes values.
+func (s *ArrayStore) unpack(value any) any {
+	if !s.serializesValues {
+		return value
+	}
+
+	data, ok := value.([]byte)
+
+	if !ok {
+		return nil
+	}
+
+	result, err := s.serializer.Unserialize(data)
+
+	if err != nil {
+		return nil
+	}
+
+	return result
+}
+
+// Get the expiration time of the key.
+func (s *ArrayStore) calculateExpiration(seconds int) time.Time {
+	if seconds > 0 {
+		return s.availableAt(seconds)
+	}
+
+	return time.Time{}
+}
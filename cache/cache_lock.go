@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"fmt"
+
+	contracts "github.com/garavel-core/framework/contracts/cache"
+)
+
+type CacheLock struct {
+	*Lock
+	// The cache store implementation.
+	store contracts.Store
+}
+
+// Create a new lock instance.
+func NewCacheLock(store contracts.Store, name string, seconds int, owner ...string) *CacheLock {
+	lock := &CacheLock{Lock: newLock(name, seconds, owner...), store: store}
+
+	lock.driver = lock
+
+	return lock
+}
+
+// Attempt to acquire the lock.
+func (l *CacheLock) acquire() bool {
+	if store, ok := l.store.(contracts.AtomicStore); ok {
+		if l.seconds > 0 {
+			return store.Add(l.name, l.owner, l.seconds)
+		}
+
+		return store.Add(l.name, l.owner)
+	}
+
+	if l.store.Get(l.name) != nil {
+		return false
+	}
+
+	if l.seconds > 0 {
+		return l.store.Put(l.name, l.owner, l.seconds)
+	}
+
+	return l.store.Forever(l.name, l.owner)
+}
+
+// Release the lock.
+func (l *CacheLock) release() bool {
+	if l.isOwnedByCurrentProcess() {
+		return l.store.Forget(l.name)
+	}
+
+	return false
+}
+
+// Releases this lock in disregard of ownership.
+func (l *CacheLock) forceRelease() {
+	l.store.Forget(l.name)
+}
+
+// Returns the owner value written into the driver for this lock.
+func (l *CacheLock) getCurrentOwner() string {
+	if owner := l.store.Get(l.name); owner != nil {
+		return fmt.Sprint(owner)
+	}
+
+	return ""
+}
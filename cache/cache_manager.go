@@ -0,0 +1,618 @@
+package cache
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	contracts "github.com/garavel-core/framework/contracts/cache"
+	"github.com/garavel-core/framework/contracts/events"
+	"github.com/garavel-core/framework/memcached"
+	"github.com/garavel-core/framework/redis"
+)
+
+// A callback creating the store of a custom driver from its configuration.
+//
+// It runs without the manager locked, so it may resolve other stores of the
+// manager it builds on.
+type StoreFactory func(config map[string]any) (contracts.Store, error)
+
+type Manager struct {
+	// The cache configuration, holding the "default" store name, the global
+	// "prefix" and the "stores" keyed by name.
+	config map[string]any
+	// The array of resolved cache stores.
+	stores map[string]*Repository
+	// The connections opened by the manager for its stores, by store name.
+	connections map[string]interface{ Close() error }
+	// The registered custom driver creators.
+	customCreators map[string]StoreFactory
+	// The event dispatcher given to new repositories.
+	events events.Dispatcher
+	// The resolutions in progress, by store name.
+	resolving map[string]*resolution
+	// Guards the configuration, the resolved stores, the connections, the
+	// creators, the resolutions and the dispatcher.
+	mutex sync.RWMutex
+}
+
+// The resolution of a store, awaited by concurrent resolutions of the same store.
+type resolution struct {
+	// Closed once the store is resolved.
+	done chan struct{}
+	// The resolved store.
+	repository *Repository
+	// The failure of the resolution.
+	err error
+}
+
+// Create a new Cache manager instance.
+func NewManager(config map[string]any) *Manager {
+	if config == nil {
+		config = map[string]any{}
+	}
+
+	return &Manager{
+		config:         config,
+		stores:         make(map[string]*Repository),
+		connections:    make(map[string]interface{ Close() error }),
+		customCreators: make(map[string]StoreFactory),
+		resolving:      make(map[string]*resolution),
+	}
+}
+
+// Get a cache store instance by name, wrapped in a repository.
+func (m *Manager) Store(name ...string) (*Repository, error) {
+	storeName := m.GetDefaultDriver()
+
+	if name != nil && name[0] != "" {
+		storeName = name[0]
+	}
+
+	return m.store(storeName)
+}
+
+// Get a store by name, resolving it unless it was resolved before.
+//
+// Stores are resolved without holding the mutex, so custom drivers may build
+// on other stores of the manager, while concurrent resolutions of one store
+// wait for the first. A custom driver resolving its own store waits forever.
+func (m *Manager) store(name string) (*Repository, error) {
+	m.mutex.Lock()
+
+	if repository, exists := m.stores[name]; exists {
+		m.mutex.Unlock()
+
+		return repository, nil
+	}
+
+	if pending, exists := m.resolving[name]; exists {
+		m.mutex.Unlock()
+
+		<-pending.done
+
+		return pending.repository, pending.err
+	}
+
+	pending := &resolution{done: make(chan struct{})}
+	m.resolving[name] = pending
+	m.mutex.Unlock()
+
+	defer close(pending.done)
+
+	pending.repository, pending.err = m.resolve(name)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.resolving, name)
+
+	if pending.err != nil {
+		return nil, pending.err
+	}
+
+	m.stores[name] = pending.repository
+
+	return pending.repository, nil
+}
+
+// Get a cache driver instance.
+func (m *Manager) Driver(driver ...string) (*Repository, error) {
+	return m.Store(driver...)
+}
+
+// Resolve the given store.
+//
+// Failover stores listing themselves, directly or through other failover
+// stores, are refused before anything is resolved.
+func (m *Manager) resolve(name string) (*Repository, error) {
+	m.mutex.RLock()
+	config := m.getConfig(name)
+
+	if config == nil {
+		m.mutex.RUnlock()
+
+		return nil, fmt.Errorf("cache store [%s] is not defined", name)
+	}
+
+	config = copyConfig(config)
+	config["store"] = name
+
+	driver := configString(config, "driver", "")
+	creator, custom := m.customCreators[driver]
+	cycle := !custom && driver == "failover" && m.failsOverTo(name, name, map[string]bool{})
+	m.mutex.RUnlock()
+
+	if cycle {
+		return nil, fmt.Errorf("cache store [%s] fails over to itself", name)
+	}
+
+	var store contracts.Store
+	var err error
+
+	if custom {
+		store, err = creator(config)
+	} else {
+		var serializer contracts.Serializer
+
+		if serializer, err = m.getSerializer(config); err != nil {
+			return nil, err
+		}
+
+		switch driver {
+		case "array":
+			store = m.createArrayDriver(config).SetSerializer(serializer)
+		case "memory":
+			store, err = m.createMemoryDriver(config)
+		case "file":
+			store, err = m.createFileDriver(config, serializer)
+		case "redis":
+			store, err = m.createRedisDriver(name, config, serializer)
+		case "memcached":
+			store, err = m.createMemcachedDriver(name, config, serializer)
+		case "database":
+			store, err = m.createDatabaseDriver(config, serializer)
+		case "failover":
+			store, err = m.createFailoverDriver(config)
+		case "null":
+			store = NewNullStore()
+		default:
+			return nil, fmt.Errorf("driver [%s] is not supported", driver)
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return m.Repository(store, config), nil
+}
+
+// Create an instance of the array cache driver.
+func (m *Manager) createArrayDriver(config map[string]any) *ArrayStore {
+	return NewArrayStore(configBool(config, "serialize", false))
+}
+
+// Create an instance of the bounded memory cache driver.
+func (m *Manager) createMemoryDriver(config map[string]any) (contracts.Store, error) {
+	policy, err := ParseEvictionPolicy(configString(config, "policy", "lru"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewMemoryStore(MemoryStoreOptions{
+		MaxEntries: configInt(config, "max_entries", 0),
+		MaxBytes:   int64(configInt(config, "max_bytes", 0)),
+		Policy:     policy,
+		Shards:     configInt(config, "shards", 16),
+	}), nil
+}
+
+// Create an instance of the file cache driver.
+func (m *Manager) createFileDriver(config map[string]any, serializer contracts.Serializer) (contracts.Store, error) {
+	path := configString(config, "path", "")
+
+	if path == "" {
+		return nil, fmt.Errorf("cache store [%s] requires a path", config["store"])
+	}
+
+	var store *FileStore
+
+	if permission := configInt(config, "permission", 0); permission != 0 {
+		store = NewFileStore(path, os.FileMode(permission))
+	} else {
+		store = NewFileStore(path)
+	}
+
+	return store.SetLockDirectory(configString(config, "lock_path", "")).SetSerializer(serializer), nil
+}
+
+// Create an instance of the Redis cache driver.
+//
+// An existing *redis.Connection may be given as "connection", otherwise one is
+// opened from the "host", "port", "password" and "database" options.
+func (m *Manager) createRedisDriver(name string, config map[string]any, serializer contracts.Serializer) (contracts.Store, error) {
+	connection, ok := config["connection"].(*redis.Connection)
+
+	if !ok {
+		connection = redis.NewConnection(redis.Options{
+			Address:  configAddress(config, "127.0.0.1", 6379),
+			Password: configString(config, "password", ""),
+			Database: configInt(config, "database", 0),
+			Timeout:  configDuration(config, "timeout"),
+		})
+
+		m.mutex.Lock()
+		m.connections[name] = connection
+		m.mutex.Unlock()
+	}
+
+	return NewRedisStore(connection, m.getPrefix(config)).SetSerializer(serializer), nil
+}
+
+// Create an instance of the Memcached cache driver.
+//
+// An existing *memcached.Client may be given as "connection", otherwise one is
+// opened from the "host" and "port" options.
+func (m *Manager) createMemcachedDriver(name string, config map[string]any, serializer contracts.Serializer) (contracts.Store, error) {
+	client, ok := config["connection"].(*memcached.Client)
+
+	if !ok {
+		client = memcached.NewClient(memcached.Options{
+			Address: configAddress(config, "127.0.0.1", 11211),
+			Timeout: configDuration(config, "timeout"),
+		})
+
+		m.mutex.Lock()
+		m.connections[name] = client
+		m.mutex.Unlock()
+	}
+
+	return NewMemcachedStore(client, m.getPrefix(config)).SetSerializer(serializer), nil
+}
+
+// Create an instance of the database cache driver.
+func (m *Manager) createDatabaseDriver(config map[string]any, serializer contracts.Serializer) (contracts.Store, error) {
+	connection, ok := config["connection"].(*sql.DB)
+
+	if !ok {
+		return nil, fmt.Errorf("cache store [%s] requires a *sql.DB connection", config["store"])
+	}
+
+	return NewDatabaseStore(connection, configString(config, "table", "cache"), m.getPrefix(config)).SetSerializer(serializer), nil
+}
+
+// Create an instance of the failover cache driver.
+//
+// The "stores" option lists the names of the backing stores in order of
+// preference and the "cooldown" option the time a failed store is skipped for.
+func (m *Manager) createFailoverDriver(config map[string]any) (contracts.Store, error) {
+	names, ok := config["stores"].([]string)
+
+	if !ok || len(names) == 0 {
+		return nil, fmt.Errorf("cache store [%s] requires a list of stores", config["store"])
+	}
+
+	stores := make([]contracts.Store, len(names))
+
+	// The backing stores are shared with the manager, so their connections
+	// are opened once. Purging a backing store closes its connection and
+	// forgets this store too, which is built on the new one when resolved.
+	for i, name := range names {
+		repository, err := m.store(name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		stores[i] = repository.GetStore()
+	}
+
+	store := NewFailoverStore(stores...).SetStoreNames(names...)
+
+	if cooldown := configDuration(config, "cooldown"); cooldown > 0 {
+		store.SetCooldown(cooldown)
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if m.events != nil {
+		store.SetEventDispatcher(m.events)
+	}
+
+	return store, nil
+}
+
+// Determine if the given failover store lists the target store, directly or
+// through other failover stores, the caller holding the mutex.
+func (m *Manager) failsOverTo(name string, target string, visited map[string]bool) bool {
+	config := m.getConfig(name)
+
+	if configString(config, "driver", "") != "failover" {
+		return false
+	}
+
+	names, _ := config["stores"].([]string)
+
+	for _, store := range names {
+		if store == target {
+			return true
+		}
+
+		if !visited[store] {
+			visited[store] = true
+
+			if m.failsOverTo(store, target, visited) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Create a new cache repository with the given implementation.
+func (m *Manager) Repository(store contracts.Store, config ...map[string]any) *Repository {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.repository(store, config...)
+}
+
+// Create a new cache repository, the caller holding the mutex.
+func (m *Manager) repository(store contracts.Store, config ...map[string]any) *Repository {
+	options := map[string]any{}
+
+	if config != nil && config[0] != nil {
+		options = config[0]
+	}
+
+	repository := NewRepository(store, map[string]any{"store": options["store"]})
+
+	repository.CoalesceRemember(configBool(options, "coalesce", true))
+
+	if seconds := configInt(options, "remember_lock", 0); seconds > 0 {
+		repository.LockRemember(seconds, configInt(options, "remember_lock_wait", seconds))
+	}
+
+	if m.events != nil && configBool(options, "events", true) {
+		repository.SetEventDispatcher(m.events)
+	}
+
+	return repository
+}
+
+// Get the serializer of the stored values.
+//
+// The "serializer" option names one of the "gob", "json" and "msgpack"
+// serializers or holds a contracts.Serializer, and defaults to gob.
+func (m *Manager) getSerializer(config map[string]any) (contracts.Serializer, error) {
+	switch serializer := config["serializer"].(type) {
+	case nil:
+		return GobSerializer{}, nil
+	case contracts.Serializer:
+		return serializer, nil
+	case string:
+		switch serializer {
+		case "", "gob":
+			return GobSerializer{}, nil
+		case "json":
+			return NewJSONSerializer(), nil
+		case "msgpack":
+			return NewMsgpackSerializer(), nil
+		}
+	}
+
+	return nil, fmt.Errorf("serializer [%v] is not supported", config["serializer"])
+}
+
+// Get the cache prefix.
+func (m *Manager) getPrefix(config map[string]any) string {
+	if prefix, ok := config["prefix"].(string); ok {
+		return prefix
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return configString(m.config, "prefix", "")
+}
+
+// Get the cache connection configuration.
+//
+// The "null" store is built in, every other store must be configured.
+func (m *Manager) getConfig(name string) map[string]any {
+	if name == "null" {
+		return map[string]any{"driver": "null"}
+	}
+
+	stores, _ := m.config["stores"].(map[string]any)
+	config, _ := stores[name].(map[string]any)
+
+	return config
+}
+
+// Get the default cache driver name.
+func (m *Manager) GetDefaultDriver() string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.getDefaultDriver()
+}
+
+// Get the default cache driver name, the caller holding the mutex.
+func (m *Manager) getDefaultDriver() string {
+	return configString(m.config, "default", "")
+}
+
+// Set the default cache driver name.
+func (m *Manager) SetDefaultDriver(name string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.config["default"] = name
+}
+
+// Unset the given driver instances.
+//
+// Connections the manager opened for the stores are closed, as the next
+// resolution of a store opens a connection of its own.
+func (m *Manager) ForgetDriver(names ...string) *Manager {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if names == nil {
+		names = []string{m.getDefaultDriver()}
+	}
+
+	for _, name := range names {
+		m.forget(name)
+	}
+
+	return m
+}
+
+// Disconnect the given driver and remove it from the local cache.
+//
+// Connections the manager opened for the store are closed, so the next
+// resolution of the store reconnects. Connections handed in through the
+// configuration are left open for their owner to close.
+func (m *Manager) Purge(name ...string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	storeName := m.getDefaultDriver()
+
+	if name != nil {
+		storeName = name[0]
+	}
+
+	m.forget(storeName)
+}
+
+// Remove the given store and close the connection opened for it, the caller holding the mutex.
+//
+// Failover stores backed by the store hold on to it, so they are removed as
+// well and back onto the new store once resolved again.
+func (m *Manager) forget(name string) {
+	if connection, exists := m.connections[name]; exists {
+		connection.Close()
+
+		delete(m.connections, name)
+	}
+
+	repository, exists := m.stores[name]
+
+	if !exists {
+		return
+	}
+
+	delete(m.stores, name)
+
+	for dependent, other := range m.stores {
+		if failover, ok := other.GetStore().(*FailoverStore); ok {
+			for _, store := range failover.GetStores() {
+				if store == repository.GetStore() {
+					m.forget(dependent)
+
+					break
+				}
+			}
+		}
+	}
+}
+
+// Register a custom driver creator.
+func (m *Manager) Extend(driver string, callback StoreFactory) *Manager {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.customCreators[driver] = callback
+
+	return m
+}
+
+// Set the event dispatcher given to the repositories resolved from now on.
+func (m *Manager) SetEventDispatcher(events events.Dispatcher) *Manager {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.events = events
+
+	return m
+}
+
+// Copy the configuration so the resolved store can be annotated freely.
+func copyConfig(config map[string]any) map[string]any {
+	result := make(map[string]any, len(config)+1)
+
+	for key, value := range config {
+		result[key] = value
+	}
+
+	return result
+}
+
+// Get a string option from the configuration.
+func configString(config map[string]any, key string, defaultValue string) string {
+	if value, ok := config[key]; ok && value != nil {
+		return fmt.Sprint(value)
+	}
+
+	return defaultValue
+}
+
+// Get an integer option from the configuration.
+func configInt(config map[string]any, key string, defaultValue int) int {
+	switch value := config[key].(type) {
+	case int:
+		return value
+	case os.FileMode:
+		return int(value)
+	case string:
+		if i, err := strconv.ParseInt(value, 0, 64); err == nil {
+			return int(i)
+		}
+	default:
+		if i, ok := toInt(value); ok {
+			return i
+		}
+	}
+
+	return defaultValue
+}
+
+// Get a boolean option from the configuration.
+func configBool(config map[string]any, key string, defaultValue bool) bool {
+	switch value := config[key].(type) {
+	case bool:
+		return value
+	case string:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+
+	return defaultValue
+}
+
+// Get a duration option from the configuration.
+func configDuration(config map[string]any, key string) time.Duration {
+	duration, _ := new(interactsWithTime).parseDuration(config[key], nil)
+
+	return duration
+}
+
+// Get the "host:port" address from the configuration.
+func configAddress(config map[string]any, host string, port int) string {
+	if address := configString(config, "address", ""); address != "" {
+		return address
+	}
+
+	return fmt.Sprintf("%s:%d", configString(config, "host", host), configInt(config, "port", port))
+}
@@ -0,0 +1,58 @@
+// Package cachetest provides helpers for testing code built on the cache.
+//
+// RunStoreSuite checks that an implementation of contracts/cache.Store
+// behaves like the stores of the framework, and Clock lets tests expire
+// items without waiting for them. Fake stands in for a cache repository,
+// recording its store calls with a SpyStore so tests can assert on them, and
+// Database backs a DatabaseStore without a database server.
+package cachetest
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a clock that only moves when told to.
+//
+// Its Now method can be handed to the SetClock methods of the stores and
+// repositories of the cache package, and of the fake servers of the
+// framework, so items expire deterministically.
+type Clock struct {
+	// The current time of the clock.
+	now time.Time
+	// Guards the current time.
+	mutex sync.Mutex
+}
+
+// Create a new clock stopped at the given time, the current second by default.
+func NewClock(start ...time.Time) *Clock {
+	if start != nil {
+		return &Clock{now: start[0]}
+	}
+
+	return &Clock{now: time.Now().Truncate(time.Second)}
+}
+
+// Get the current time of the clock.
+func (c *Clock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.now
+}
+
+// Move the clock forward by the given duration.
+func (c *Clock) Advance(duration time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.now = c.now.Add(duration)
+}
+
+// Set the current time of the clock.
+func (c *Clock) Set(now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.now = now
+}
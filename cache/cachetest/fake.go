@@ -0,0 +1,205 @@
+package cachetest
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/garavel-core/framework/cache"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Fake is a cache repository recording what the code under test does with it.
+//
+// It stores items in an array store behind a SpyStore, and the store and the
+// repository read the time from a Clock, so items only expire when the clock
+// is advanced. The Fake satisfies contracts/cache.Repository and
+// contracts/cache.ContextRepository; code depending on the concrete repository
+// can be handed the embedded *cache.Repository, whose store calls are recorded
+// as well, though its remember calls are not counted.
+type Fake struct {
+	*cache.Repository
+	// The spy recording the store calls.
+	spy *SpyStore
+	// The clock expiring the items.
+	clock *Clock
+	// The test the assertions report to.
+	t testing.TB
+	// The number of remember calls made for each key.
+	remembered map[string]int
+	// Guards the remember calls.
+	mutex sync.Mutex
+}
+
+// Create a new cache fake reporting to the given test.
+func NewFake(t testing.TB) *Fake {
+	clock := NewClock()
+	spy := NewSpyStore(cache.NewArrayStore().SetClock(clock.Now))
+
+	return &Fake{
+		Repository: cache.NewRepository(spy).SetClock(clock.Now),
+		spy:        spy,
+		clock:      clock,
+		t:          t,
+		remembered: map[string]int{},
+	}
+}
+
+// Get an item from the cache, or execute the given Closure and store the result.
+func (f *Fake) Remember(key string, ttl any, callback func() any) any {
+	f.remember(key)
+
+	return f.Repository.Remember(key, ttl, callback)
+}
+
+// Get an item from the cache, or execute the given Closure and store the result forever.
+func (f *Fake) Sear(key string, callback func() any) any {
+	return f.RememberForever(key, callback)
+}
+
+// Get an item from the cache, or execute the given Closure and store the result forever.
+func (f *Fake) RememberForever(key string, callback func() any) any {
+	f.remember(key)
+
+	return f.Repository.RememberForever(key, callback)
+}
+
+// Retrieve an item from the cache by key, refreshing it in the background if it is stale.
+func (f *Fake) Flexible(key string, ttl [2]any, callback func() any) any {
+	f.remember(key)
+
+	return f.Repository.Flexible(key, ttl, callback)
+}
+
+// Get an item from the cache, or execute the given callback and store the result.
+func (f *Fake) RememberCtx(ctx context.Context, key string, ttl any, callback func(ctx context.Context) (any, error)) (any, error) {
+	f.remember(key)
+
+	return f.Repository.RememberCtx(ctx, key, ttl, callback)
+}
+
+// Get an item from the cache, or execute the given callback and store the result forever.
+func (f *Fake) RememberForeverCtx(ctx context.Context, key string, callback func(ctx context.Context) (any, error)) (any, error) {
+	f.remember(key)
+
+	return f.Repository.RememberForeverCtx(ctx, key, callback)
+}
+
+// Get the spy recording the store calls.
+func (f *Fake) Spy() *SpyStore {
+	return f.spy
+}
+
+// Get the clock expiring the items.
+func (f *Fake) Clock() *Clock {
+	return f.clock
+}
+
+// Assert that an item exists in the cache.
+func (f *Fake) AssertHas(key string) bool {
+	f.t.Helper()
+
+	return assert.NotNil(f.t, f.spy.GetStore().Get(key), "cache item [%s] is missing", key)
+}
+
+// Assert that an item does not exist in the cache.
+func (f *Fake) AssertMissing(key string) bool {
+	f.t.Helper()
+
+	return assert.Nil(f.t, f.spy.GetStore().Get(key), "cache item [%s] exists", key)
+}
+
+// Assert that an item was stored, for the given TTL when one is passed.
+//
+// A TTL of zero expects the item to have been stored forever. TTLs are
+// compared in whole seconds, the precision the store receives them in.
+func (f *Fake) AssertPut(key string, ttl ...time.Duration) bool {
+	f.t.Helper()
+
+	var stored []int
+
+	for _, call := range f.spy.Writes() {
+		if seconds, ok := putSeconds(call, key); ok {
+			stored = append(stored, seconds)
+		}
+	}
+
+	if stored == nil {
+		return assert.Fail(f.t, "cache item [%s] was not stored", key)
+	}
+
+	if ttl == nil {
+		return true
+	}
+
+	expected := 0
+
+	if ttl[0] > 0 {
+		expected = int(math.Ceil(ttl[0].Seconds()))
+	}
+
+	return assert.Contains(f.t, stored, expected, "cache item [%s] was not stored for %d seconds", key, expected)
+}
+
+// Assert that an item was removed from the cache.
+func (f *Fake) AssertForgotten(key string) bool {
+	f.t.Helper()
+
+	for _, call := range f.spy.CallsTo("Forget") {
+		if call.Args[0] == key {
+			return true
+		}
+	}
+
+	return assert.Fail(f.t, "cache item [%s] was not forgotten", key)
+}
+
+// Assert that exactly one remember call was made for the given key.
+func (f *Fake) AssertRememberCalledOnce(key string) bool {
+	f.t.Helper()
+
+	f.mutex.Lock()
+	calls := f.remembered[key]
+	f.mutex.Unlock()
+
+	return assert.Equal(f.t, 1, calls, "remember was called %d times for cache item [%s]", calls, key)
+}
+
+// Assert that nothing was written to the cache.
+func (f *Fake) AssertNothingWritten() bool {
+	f.t.Helper()
+
+	return assert.Empty(f.t, f.spy.Writes(), "the cache was written to")
+}
+
+// Record a remember call for the given key.
+func (f *Fake) remember(key string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.remembered[key]++
+}
+
+// Get the number of seconds a write stored the given key for, zero when forever.
+func putSeconds(call Call, key string) (int, bool) {
+	switch call.Method {
+	case "Put":
+		return call.Args[2].(int), call.Args[0] == key
+	case "Forever":
+		return 0, call.Args[0] == key
+	case "PutMany":
+		_, ok := call.Args[0].(map[string]any)[key]
+		return call.Args[1].(int), ok
+	case "Add":
+		if call.Args[0] != key || call.Result != true {
+			return 0, false
+		}
+
+		return ttlSeconds(call.Args[2:]...), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,216 @@
+package cachetest
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/garavel-core/framework/cache"
+	contracts "github.com/garavel-core/framework/contracts/cache"
+)
+
+// A call made to a store.
+type Call struct {
+	// The name of the store method.
+	Method string
+	// The arguments of the call, in order.
+	Args []any
+	// The result returned by the store, nil for methods without one.
+	Result any
+}
+
+// The store methods that change items.
+var writeMethods = map[string]bool{
+	"Put":       true,
+	"PutMany":   true,
+	"Add":       true,
+	"Increment": true,
+	"Decrement": true,
+	"Forever":   true,
+	"Forget":    true,
+	"Flush":     true,
+}
+
+// Determine if the call changed items of the store.
+func (c Call) IsWrite() bool {
+	return writeMethods[c.Method]
+}
+
+// SpyStore records every call made to the store it wraps.
+//
+// The calls are kept in the order they were made, along with their arguments
+// and results, and are passed on to the wrapped store unchanged.
+type SpyStore struct {
+	// The store receiving the calls.
+	store contracts.Store
+	// The calls made so far.
+	calls []Call
+	// Guards the calls.
+	mutex sync.Mutex
+}
+
+// Create a new spy wrapping the given store, an array store by default.
+func NewSpyStore(store ...contracts.Store) *SpyStore {
+	if store != nil {
+		return &SpyStore{store: store[0]}
+	}
+
+	return &SpyStore{store: cache.NewArrayStore()}
+}
+
+// Retrieve an item from the cache by key.
+func (s *SpyStore) Get(key string) any {
+	return s.record("Get", s.store.Get(key), key)
+}
+
+// Retrieve multiple items from the cache by key.
+//
+// Items not found in the cache will have a null value.
+func (s *SpyStore) Many(keys []string) map[string]any {
+	result := s.store.Many(keys)
+
+	s.record("Many", result, append([]string(nil), keys...))
+
+	return result
+}
+
+// Store an item in the cache for a given number of seconds.
+func (s *SpyStore) Put(key string, value any, seconds int) bool {
+	return s.record("Put", s.store.Put(key, value, seconds), key, value, seconds).(bool)
+}
+
+// Store multiple items in the cache for a given number of seconds.
+func (s *SpyStore) PutMany(values map[string]any, seconds int) bool {
+	copied := make(map[string]any, len(values))
+
+	for key, value := range values {
+		copied[key] = value
+	}
+
+	return s.record("PutMany", s.store.PutMany(values, seconds), copied, seconds).(bool)
+}
+
+// Store an item in the cache if the key does not exist.
+//
+// Wrapped stores that cannot add atomically are checked for the key before
+// the item is written.
+func (s *SpyStore) Add(key string, value any, ttl ...any) bool {
+	var added bool
+
+	if store, ok := s.store.(contracts.AtomicStore); ok {
+		added = store.Add(key, value, ttl...)
+	} else if s.store.Get(key) == nil {
+		added = s.store.Put(key, value, ttlSeconds(ttl...))
+	}
+
+	args := []any{key, value}
+
+	if ttl != nil {
+		args = append(args, ttl[0])
+	}
+
+	return s.record("Add", added, args...).(bool)
+}
+
+// Increment the value of an item in the cache.
+func (s *SpyStore) Increment(key string, value ...any) any {
+	return s.record("Increment", s.store.Increment(key, value...), append([]any{key}, value...)...)
+}
+
+// Decrement the value of an item in the cache.
+func (s *SpyStore) Decrement(key string, value ...any) any {
+	return s.record("Decrement", s.store.Decrement(key, value...), append([]any{key}, value...)...)
+}
+
+// Store an item in the cache indefinitely.
+func (s *SpyStore) Forever(key string, value any) bool {
+	return s.record("Forever", s.store.Forever(key, value), key, value).(bool)
+}
+
+// Remove an item from the cache.
+func (s *SpyStore) Forget(key string) bool {
+	return s.record("Forget", s.store.Forget(key), key).(bool)
+}
+
+// Remove all items from the cache.
+func (s *SpyStore) Flush() bool {
+	return s.record("Flush", s.store.Flush()).(bool)
+}
+
+// Get the cache key prefix.
+func (s *SpyStore) GetPrefix() string {
+	return s.record("GetPrefix", s.store.GetPrefix()).(string)
+}
+
+// Get the calls made so far, in order.
+func (s *SpyStore) Calls() []Call {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]Call(nil), s.calls...)
+}
+
+// Get the calls made so far to the given method, in order.
+func (s *SpyStore) CallsTo(method string) []Call {
+	var calls []Call
+
+	for _, call := range s.Calls() {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+
+	return calls
+}
+
+// Get the calls made so far that changed items, in order.
+func (s *SpyStore) Writes() []Call {
+	var calls []Call
+
+	for _, call := range s.Calls() {
+		if call.IsWrite() {
+			calls = append(calls, call)
+		}
+	}
+
+	return calls
+}
+
+// Forget the calls made so far.
+func (s *SpyStore) Reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.calls = nil
+}
+
+// Get the wrapped store.
+func (s *SpyStore) GetStore() contracts.Store {
+	return s.store
+}
+
+// Record a call and return its result.
+func (s *SpyStore) record(method string, result any, args ...any) any {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.calls = append(s.calls, Call{Method: method, Args: args, Result: result})
+
+	return result
+}
+
+// Get the number of seconds of a TTL handed to Add, zero when there is none.
+func ttlSeconds(ttl ...any) int {
+	if ttl == nil {
+		return 0
+	}
+
+	switch t := ttl[0].(type) {
+	case int:
+		return t
+	case time.Duration:
+		return int(math.Ceil(t.Seconds()))
+	}
+
+	return 0
+}
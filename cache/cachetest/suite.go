@@ -0,0 +1,389 @@
+package cachetest
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/garavel-core/framework/cache"
+	contracts "github.com/garavel-core/framework/contracts/cache"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The prefix the stores of the suite are created with.
+const suitePrefix = "suite"
+
+// The settings a factory creates the store of a test with.
+type Config struct {
+	// The prefix the store should prepend to its keys.
+	Prefix string
+	// The clock the store should read the current time from, nil when the
+	// suite runs with the system clock.
+	Clock *Clock
+}
+
+// Creates a new, empty store for a test of the suite.
+type Factory func(t *testing.T, config Config) contracts.Store
+
+// StoreSuite holds the behavior every contracts/cache.Store implementation is
+// expected to share.
+//
+// By default every test hands a Clock to the factory, which makes the store
+// read the time from it, for example through the SetClock method of the
+// stores of the cache package and of the fake servers. Stores keeping their
+// own time, such as real servers, are run with SystemClock and have their
+// items expired by sleeping.
+type StoreSuite struct {
+	// The factory creating the store of each test.
+	Factory Factory
+	// Indicates if items are expired by sleeping instead of advancing a clock.
+	SystemClock bool
+}
+
+// Run the store conformance tests against the stores created by the factory.
+func RunStoreSuite(t *testing.T, factory Factory) {
+	StoreSuite{Factory: factory}.Run(t)
+}
+
+// The store under test and the clock it reads the time from.
+type subject struct {
+	contracts.Store
+	// The clock of the test, nil when the system clock is used.
+	clock *Clock
+}
+
+// Move the time of the store forward.
+func (s subject) travel(duration time.Duration) {
+	if s.clock != nil {
+		s.clock.Advance(duration)
+	} else {
+		time.Sleep(duration)
+	}
+}
+
+// Run the conformance tests.
+func (s StoreSuite) Run(t *testing.T) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, store subject)
+	}{
+		{"missing items are nil", testMissingItems},
+		{"items are stored and retrieved", testStoredItems},
+		{"items expire", testExpiringItems},
+		{"many returns nil for missing keys", testMany},
+		{"put many stores and expires every item", testPutMany},
+		{"forever items do not expire", testForever},
+		{"forget removes items", testForget},
+		{"flush removes every item", testFlush},
+		{"keys are kept apart from the prefix", testPrefix},
+		{"add only stores missing items", testAdd},
+		{"add is atomic under concurrency", testConcurrentAdd},
+		{"add does not store items with an unusable ttl", testAddInvalidTTL},
+		{"increment changes numeric items", testIncrement},
+		{"increment of missing items", testIncrementMissing},
+		{"concurrent increments are not lost", testConcurrentIncrement},
+		{"concurrent reads and writes", testConcurrentReadsAndWrites},
+		{"counters follow the counter rules", testCounters},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			test.test(t, s.subject(t))
+		})
+	}
+}
+
+// Create the store of a test.
+func (s StoreSuite) subject(t *testing.T) subject {
+	var clock *Clock
+
+	if !s.SystemClock {
+		clock = NewClock()
+	}
+
+	store := s.Factory(t, Config{Prefix: suitePrefix, Clock: clock})
+
+	return subject{Store: store, clock: clock}
+}
+
+func testMissingItems(t *testing.T, store subject) {
+	assert.Nil(t, store.Get("missing"))
+}
+
+func testStoredItems(t *testing.T, store subject) {
+	assert.True(t, store.Put("string", "value", 60))
+	assert.True(t, store.Put("integer", 42, 60))
+	assert.True(t, store.Put("map", map[string]any{"name": "Taylor"}, 60))
+
+	assert.Equal(t, "value", store.Get("string"))
+	assert.EqualValues(t, 42, store.Get("integer"))
+	assert.Equal(t, map[string]any{"name": "Taylor"}, store.Get("map"))
+
+	assert.True(t, store.Put("string", "changed", 60))
+	assert.Equal(t, "changed", store.Get("string"))
+}
+
+func testExpiringItems(t *testing.T, store subject) {
+	assert.True(t, store.Put("item", "value", 1))
+	assert.Equal(t, "value", store.Get("item"))
+
+	if store.clock != nil {
+		store.travel(500 * time.Millisecond)
+		assert.Equal(t, "value", store.Get("item"))
+	}
+
+	store.travel(2100 * time.Millisecond)
+	assert.Nil(t, store.Get("item"))
+}
+
+func testMany(t *testing.T, store subject) {
+	store.Put("first", "one", 60)
+	store.Put("second", "two", 60)
+
+	assert.Equal(t, map[string]any{"first": "one", "second": "two", "missing": nil}, store.Many([]string{"first", "second", "missing"}))
+	assert.Empty(t, store.Many([]string{}))
+}
+
+func testPutMany(t *testing.T, store subject) {
+	assert.True(t, store.PutMany(map[string]any{"first": "one", "second": "two"}, 1))
+	assert.Equal(t, map[string]any{"first": "one", "second": "two"}, store.Many([]string{"first", "second"}))
+
+	store.travel(2100 * time.Millisecond)
+	assert.Equal(t, map[string]any{"first": nil, "second": nil}, store.Many([]string{"first", "second"}))
+}
+
+func testForever(t *testing.T, store subject) {
+	assert.True(t, store.Forever("item", "value"))
+
+	if store.clock != nil {
+		store.travel(365 * 24 * time.Hour)
+	}
+
+	assert.Equal(t, "value", store.Get("item"))
+}
+
+func testForget(t *testing.T, store subject) {
+	store.Put("item", "value", 60)
+
+	assert.True(t, store.Forget("item"))
+	assert.Nil(t, store.Get("item"))
+	assert.False(t, store.Forget("item"))
+}
+
+func testFlush(t *testing.T, store subject) {
+	store.Put("first", "one", 60)
+	store.Forever("second", "two")
+
+	assert.True(t, store.Flush())
+	assert.Nil(t, store.Get("first"))
+	assert.Nil(t, store.Get("second"))
+}
+
+func testPrefix(t *testing.T, store subject) {
+	if prefix := store.GetPrefix(); prefix != "" {
+		assert.True(t, strings.HasPrefix(prefix, suitePrefix), "prefix [%s] does not start with [%s]", prefix, suitePrefix)
+	}
+
+	store.Put("item", "unprefixed", 60)
+	store.Put(suitePrefix+":item", "prefixed", 60)
+
+	assert.Equal(t, "unprefixed", store.Get("item"))
+	assert.Equal(t, "prefixed", store.Get(suitePrefix+":item"))
+}
+
+func testAdd(t *testing.T, store subject) {
+	adder, ok := store.Store.(contracts.AtomicStore)
+
+	if !ok {
+		t.Skip("the store does not implement contracts.AtomicStore")
+	}
+
+	assert.True(t, adder.Add("item", "first", 1))
+	assert.False(t, adder.Add("item", "second", 1))
+	assert.Equal(t, "first", store.Get("item"))
+
+	store.travel(2100 * time.Millisecond)
+
+	assert.True(t, adder.Add("item", "third"))
+	assert.Equal(t, "third", store.Get("item"))
+}
+
+func testAddInvalidTTL(t *testing.T, store subject) {
+	adder, ok := store.Store.(contracts.AtomicStore)
+
+	if !ok {
+		t.Skip("the store does not implement contracts.AtomicStore")
+	}
+
+	for _, ttl := range []any{-1, 0, time.Duration(0), "soon"} {
+		assert.False(t, adder.Add("item", "value", ttl), "ttl %#v", ttl)
+		assert.Nil(t, store.Get("item"), "ttl %#v", ttl)
+	}
+}
+
+func testConcurrentAdd(t *testing.T, store subject) {
+	adder, ok := store.Store.(contracts.AtomicStore)
+
+	if !ok {
+		t.Skip("the store does not implement contracts.AtomicStore")
+	}
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	var winners []int
+
+	start := make(chan struct{})
+
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			<-start
+
+			if adder.Add("item", i, 60) {
+				mutex.Lock()
+				winners = append(winners, i)
+				mutex.Unlock()
+			}
+		}(i)
+	}
+
+	close(start)
+	wg.Wait()
+
+	if assert.Len(t, winners, 1) {
+		assert.EqualValues(t, winners[0], store.Get("item"))
+	}
+}
+
+func testIncrement(t *testing.T, store subject) {
+	store.Put("counter", 5, 60)
+
+	assert.EqualValues(t, 6, store.Increment("counter"))
+	assert.EqualValues(t, 10, store.Increment("counter", 4))
+	assert.EqualValues(t, 7, store.Decrement("counter", 3))
+	assert.EqualValues(t, 7, store.Get("counter"))
+}
+
+func testIncrementMissing(t *testing.T, store subject) {
+	// Stores either create the item with the step, or report the failure and
+	// leave the item missing, but never store anything else.
+	if result := store.Increment("missing", 3); result == false {
+		assert.Nil(t, store.Get("missing"))
+	} else {
+		assert.EqualValues(t, 3, result)
+		assert.EqualValues(t, 3, store.Get("missing"))
+	}
+}
+
+func testConcurrentIncrement(t *testing.T, store subject) {
+	store.Put("counter", 0, 60)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 25; j++ {
+				store.Increment("counter")
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	assert.EqualValues(t, 200, store.Get("counter"))
+}
+
+func testConcurrentReadsAndWrites(t *testing.T, store subject) {
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			own := fmt.Sprintf("writer:%d", i)
+			other := fmt.Sprintf("writer:%d", (i+1)%8)
+
+			for j := 0; j < 25; j++ {
+				store.Put(own, j, 60)
+				store.PutMany(map[string]any{own + ":many": j}, 60)
+
+				// Readers see either nothing or a whole value, never a torn write.
+				if value := store.Get(other); value != nil {
+					assert.Regexp(t, "^[0-9]+$", fmt.Sprint(value))
+				}
+
+				store.Many([]string{own, other})
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	for i := 0; i < 8; i++ {
+		key := fmt.Sprintf("writer:%d", i)
+
+		assert.EqualValues(t, 24, store.Get(key))
+		assert.EqualValues(t, 24, store.Get(key+":many"))
+	}
+}
+
+func testCounters(t *testing.T, store subject) {
+	counters, ok := store.Store.(contracts.CounterStore)
+
+	if !ok {
+		t.Skip("the store does not implement contracts.CounterStore")
+	}
+
+	result, err := counters.IncrementBy("counter", 5)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), result)
+
+	result, err = counters.DecrementBy("counter", 8, false)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-3), result)
+
+	store.Put("floored", 3, 60)
+
+	result, err = counters.DecrementBy("floored", 5, true)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), result)
+
+	store.Put("string", "abc", 60)
+
+	_, err = counters.IncrementBy("string", 1)
+	assert.ErrorIs(t, err, cache.ErrNotNumeric)
+	assert.Equal(t, "abc", store.Get("string"))
+
+	store.Put("max", math.MaxInt64, 60)
+
+	_, err = counters.IncrementBy("max", 1)
+	assert.ErrorIs(t, err, cache.ErrOverflow)
+
+	floating, err := counters.IncrementFloat("float", 1.5)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.5, floating)
+
+	// Counters keep the expiration of the items they change.
+	store.Put("expiring", 1, 1)
+
+	_, err = counters.IncrementBy("expiring", 1)
+	assert.NoError(t, err)
+
+	store.travel(2100 * time.Millisecond)
+	assert.Nil(t, store.Get("expiring"))
+}
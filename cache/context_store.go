@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"context"
+	"errors"
+
+	contracts "github.com/garavel-core/framework/contracts/cache"
+)
+
+var (
+	// The error returned when a counter is changed on an item that does not exist.
+	ErrMissing = errors.New("cache: the item does not exist")
+	// The error returned when a counter is changed on an item that is not numeric.
+	ErrNotNumeric = errors.New("cache: the item is not numeric")
+)
+
+// Get the context-first API of the given store.
+//
+// Stores implementing contracts.ContextStore are returned as they are, so
+// their errors reach the caller. Other stores are adapted: the context is
+// checked before every call and failed writes are reported as ErrStoreFailed.
+// Such stores cannot tell a missing item from a failing backend, so their
+// reads never fail and a nil value counts as a miss. Their counters check
+// that the item exists before changing it, which is not atomic.
+func AsContextStore(store contracts.Store) contracts.ContextStore {
+	if store, ok := store.(contracts.ContextStore); ok {
+		return store
+	}
+
+	return &contextStore{store: store}
+}
+
+// Adapts a bool-style store to the context-first API.
+type contextStore struct {
+	// The adapted store.
+	store contracts.Store
+}
+
+// Retrieve an item from the cache by key, reporting whether it was found.
+func (s *contextStore) GetCtx(ctx context.Context, key string) (any, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+
+	value := s.store.Get(key)
+
+	return value, value != nil, nil
+}
+
+// Retrieve multiple items from the cache by key, missing items have a nil value.
+func (s *contextStore) ManyCtx(ctx context.Context, keys []string) (map[string]any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return s.store.Many(keys), nil
+}
+
+// Store an item in the cache for a given number of seconds.
+func (s *contextStore) PutCtx(ctx context.Context, key string, value any, seconds int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return failure(s.store.Put(key, value, seconds))
+}
+
+// Store multiple items in the cache for a given number of seconds.
+func (s *contextStore) PutManyCtx(ctx context.Context, values map[string]any, seconds int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return failure(s.store.PutMany(values, seconds))
+}
+
+// Store an item in the cache if the key does not exist, reporting whether it was stored.
+//
+// Stores that cannot add atomically are checked for the key before the item
+// is written, which leaves a window for concurrent writers.
+func (s *contextStore) AddCtx(ctx context.Context, key string, value any, seconds int) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	if store, ok := s.store.(contracts.AtomicStore); ok {
+		if seconds <= 0 {
+			return store.Add(key, value), nil
+		}
+
+		return store.Add(key, value, seconds), nil
+	}
+
+	if s.store.Get(key) != nil {
+		return false, nil
+	}
+
+	return true, failure(s.store.Put(key, value, seconds))
+}
+
+// Increment the value of an item in the cache and return the new value.
+func (s *contextStore) IncrementCtx(ctx context.Context, key string, value int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	if s.store.Get(key) == nil {
+		return 0, ErrMissing
+	}
+
+	return counterResult(s.store.Increment(key, int(value)))
+}
+
+// Decrement the value of an item in the cache and return the new value.
+func (s *contextStore) DecrementCtx(ctx context.Context, key string, value int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	if s.store.Get(key) == nil {
+		return 0, ErrMissing
+	}
+
+	return counterResult(s.store.Decrement(key, int(value)))
+}
+
+// Store an item in the cache indefinitely.
+func (s *contextStore) ForeverCtx(ctx context.Context, key string, value any) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return failure(s.store.Forever(key, value))
+}
+
+// Remove an item from the cache, reporting whether it existed.
+func (s *contextStore) ForgetCtx(ctx context.Context, key string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	return s.store.Forget(key), nil
+}
+
+// Remove all items from the cache.
+func (s *contextStore) FlushCtx(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return failure(s.store.Flush())
+}
+
+// Turn the result of a bool-style write into an error.
+func failure(ok bool) error {
+	if !ok {
+		return ErrStoreFailed
+	}
+
+	return nil
+}
+
+// Turn the result of a bool-style counter change into a number.
+func counterResult(result any) (int64, error) {
+	if result == false {
+		return 0, ErrStoreFailed
+	}
+
+	number, ok := toInt(result)
+
+	if !ok {
+		return 0, ErrNotNumeric
+	}
+
+	return int64(number), nil
+}
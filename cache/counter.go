@@ -0,0 +1,203 @@
+package cache
+
+import (
+	"errors"
+	"math"
+)
+
+// The error returned when the result of a counter change does not fit the counter.
+var ErrOverflow = errors.New("cache: the counter would overflow")
+
+// The change a counter operation makes to the current value of an item.
+//
+// Every store implementing contracts.CounterStore applies the same rules:
+//
+//   - a missing item counts as zero and is created without expiration,
+//   - an existing item keeps its expiration,
+//   - integer counters accept integers and floats with an integral value,
+//     float counters accept any integer or float,
+//   - any other item is left untouched and ErrNotNumeric is returned,
+//   - a result beyond the range of int64, or an infinite or NaN float, is
+//     refused with ErrOverflow and the item is left untouched,
+//   - a negative step performs the opposite operation,
+//   - a floored decrement stops at zero, and leaves a counter that already
+//     is below zero as it is.
+type counterChange func(current any, exists bool) (any, error)
+
+// Get the change adding the given step to an integer counter.
+func incrementBy(step int64) counterChange {
+	return func(current any, exists bool) (any, error) {
+		value, err := counterInt(current, exists)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if (step > 0 && value > math.MaxInt64-step) || (step < 0 && value < math.MinInt64-step) {
+			return nil, ErrOverflow
+		}
+
+		return int(value + step), nil
+	}
+}
+
+// Get the change subtracting the given step from an integer counter.
+func decrementBy(step int64, floor bool) counterChange {
+	return func(current any, exists bool) (any, error) {
+		if step == math.MinInt64 {
+			return nil, ErrOverflow
+		}
+
+		result, err := incrementBy(-step)(current, exists)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if value := result.(int); floor && value < 0 && step > 0 {
+			return minInt(value+int(step), 0), nil
+		}
+
+		return result, nil
+	}
+}
+
+// Get the change adding the given step to a floating point counter.
+func incrementFloatBy(step float64) counterChange {
+	return func(current any, exists bool) (any, error) {
+		value := float64(0)
+
+		if exists {
+			var ok bool
+
+			if value, ok = toFloat(current); !ok {
+				return nil, ErrNotNumeric
+			}
+		}
+
+		result := value + step
+
+		if math.IsNaN(result) || math.IsInf(result, 0) {
+			return nil, ErrOverflow
+		}
+
+		return result, nil
+	}
+}
+
+// Get the current value of an integer counter.
+func counterInt(current any, exists bool) (int64, error) {
+	if !exists {
+		return 0, nil
+	}
+
+	switch v := current.(type) {
+	case int:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint:
+		return uintCounter(uint64(v))
+	case uint8:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint64:
+		return uintCounter(v)
+	case float32:
+		return floatCounter(float64(v))
+	case float64:
+		return floatCounter(v)
+	}
+
+	return 0, ErrNotNumeric
+}
+
+// Get the counter value of an unsigned integer.
+func uintCounter(value uint64) (int64, error) {
+	if value > math.MaxInt64 {
+		return 0, ErrOverflow
+	}
+
+	return int64(value), nil
+}
+
+// Get the counter value of a float, which must hold an integral value.
+func floatCounter(value float64) (int64, error) {
+	if value != math.Trunc(value) {
+		return 0, ErrNotNumeric
+	}
+
+	if value < math.MinInt64 || value >= math.MaxInt64 {
+		return 0, ErrOverflow
+	}
+
+	return int64(value), nil
+}
+
+// Cast the given number to a float, rejecting anything that is not a number.
+func toFloat(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+
+	return 0, false
+}
+
+// Get the integer result of a counter change.
+func integerResult(result any, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(result.(int)), nil
+}
+
+// Get the floating point result of a counter change.
+func floatResult(result any, err error) (float64, error) {
+	if err != nil {
+		return 0, err
+	}
+
+	return result.(float64), nil
+}
+
+// Get the smaller of two integers.
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,615 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"time"
+
+	contracts "github.com/garavel-core/framework/contracts/cache"
+)
+
+// The number of seconds items stored "forever" are kept in the database.
+const foreverSeconds = 315360000
+
+// The SQL dialect spoken by the database of a DatabaseStore.
+type DatabaseGrammar int
+
+const (
+	MySqlGrammar DatabaseGrammar = iota
+	PostgresGrammar
+	SQLiteGrammar
+)
+
+// Wrap an identifier in keyword identifiers.
+func (g DatabaseGrammar) wrap(identifier string) string {
+	if g == MySqlGrammar {
+		return "`" + identifier + "`"
+	}
+
+	return `"` + identifier + `"`
+}
+
+// Get the placeholder of the parameter at the given one based position.
+func (g DatabaseGrammar) parameter(position int) string {
+	if g == PostgresGrammar {
+		return fmt.Sprintf("$%d", position)
+	}
+
+	return "?"
+}
+
+// Get the clause locking the selected rows for the transaction.
+func (g DatabaseGrammar) lockForUpdate() string {
+	if g == SQLiteGrammar {
+		return ""
+	}
+
+	return " for update"
+}
+
+// Guess the grammar from the driver behind the given database handle.
+func detectDatabaseGrammar(db *sql.DB) DatabaseGrammar {
+	driver := strings.ToLower(reflect.TypeOf(db.Driver()).String())
+
+	switch {
+	case strings.Contains(driver, "sqlite"):
+		return SQLiteGrammar
+	case strings.Contains(driver, "pq."), strings.Contains(driver, "pgx"), strings.Contains(driver, "postgres"), strings.Contains(driver, "stdlib."):
+		return PostgresGrammar
+	}
+
+	return MySqlGrammar
+}
+
+// Create the cache table on the given database if it does not exist yet.
+func CreateCacheTable(db *sql.DB, table string, grammar ...DatabaseGrammar) error {
+	g := detectDatabaseGrammar(db)
+
+	if grammar != nil {
+		g = grammar[0]
+	}
+
+	_, err := db.Exec(fmt.Sprintf(
+		"create table if not exists %s (%s varchar(255) not null primary key, %s text not null, %s bigint not null)",
+		g.wrap(table), g.wrap("key"), g.wrap("value"), g.wrap("expiration"),
+	))
+
+	return err
+}
+
+type DatabaseStore struct {
+	interactsWithTime
+	// The database connection instance.
+	connection *sql.DB
+	// The name of the cache table.
+	table string
+	// A string that should be prepended to keys.
+	prefix string
+	// The SQL dialect of the connection.
+	grammar DatabaseGrammar
+	// An array representation of the expired row garbage collection lottery odds.
+	gcLottery [2]int
+	// The serializer of the stored values.
+	serializer contracts.Serializer
+}
+
+// Create a new database store.
+func NewDatabaseStore(connection *sql.DB, table string, prefix ...string) *DatabaseStore {
+	store := &DatabaseStore{
+		connection: connection,
+		table:      table,
+		grammar:    detectDatabaseGrammar(connection),
+		gcLottery:  [2]int{2, 100},
+		serializer: GobSerializer{},
+	}
+
+	if prefix != nil {
+		store.prefix = prefix[0]
+	}
+
+	return store
+}
+
+// Set the clock the store reads the current time from, the system clock when nil.
+func (s *DatabaseStore) SetClock(clock func() time.Time) *DatabaseStore {
+	s.clock = clock
+
+	return s
+}
+
+// Retrieve an item from the cache by key.
+func (s *DatabaseStore) Get(key string) any {
+	return s.Many([]string{key})[key]
+}
+
+// Retrieve an item from the cache by key, reporting whether it was found.
+func (s *DatabaseStore) GetCtx(ctx context.Context, key string) (any, bool, error) {
+	results, err := s.ManyCtx(ctx, []string{key})
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	return results[key], results[key] != nil, nil
+}
+
+// Retrieve multiple items from the cache by key.
+//
+// Items not found in the cache will have a null value.
+func (s *DatabaseStore) Many(keys []string) map[string]any {
+	results, err := s.ManyCtx(context.Background(), keys)
+
+	if err != nil {
+		return nullValues(keys)
+	}
+
+	return results
+}
+
+// Retrieve multiple items from the cache by key, missing items have a nil value.
+func (s *DatabaseStore) ManyCtx(ctx context.Context, keys []string) (map[string]any, error) {
+	results := nullValues(keys)
+
+	if len(keys) == 0 {
+		return results, nil
+	}
+
+	placeholders := make([]string, len(keys))
+	args := make([]any, len(keys))
+
+	for i, key := range keys {
+		placeholders[i] = s.grammar.parameter(i + 1)
+		args[i] = s.prefix + key
+	}
+
+	rows, err := s.connection.QueryContext(ctx, fmt.Sprintf(
+		"select %s, %s, %s from %s where %s in (%s)",
+		s.column("key"), s.column("value"), s.column("expiration"), s.wrappedTable(), s.column("key"), strings.Join(placeholders, ", "),
+	), args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	now := s.currentTime().Unix()
+
+	for rows.Next() {
+		var key, value string
+		var expiration int64
+
+		if err := rows.Scan(&key, &value, &expiration); err != nil {
+			return nil, err
+		}
+
+		// If this cache expiration date is past the current time, we will return a
+		// null value since the cache is expired. The row itself is removed later
+		// by the garbage collection lottery instead of on this read path.
+		if now >= expiration {
+			continue
+		}
+
+		data, err := s.unserialize(value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		results[strings.TrimPrefix(key, s.prefix)] = data
+	}
+
+	return results, rows.Err()
+}
+
+// Store an item in the cache for a given number of seconds.
+func (s *DatabaseStore) Put(key string, value any, seconds int) bool {
+	return s.PutMany(map[string]any{key: value}, seconds)
+}
+
+// Store an item in the cache for a given number of seconds.
+func (s *DatabaseStore) PutCtx(ctx context.Context, key string, value any, seconds int) error {
+	return s.PutManyCtx(ctx, map[string]any{key: value}, seconds)
+}
+
+// Store multiple items in the cache for a given number of seconds.
+func (s *DatabaseStore) PutMany(values map[string]any, seconds int) bool {
+	return s.PutManyCtx(context.Background(), values, seconds) == nil
+}
+
+// Store multiple items in the cache for a given number of seconds.
+func (s *DatabaseStore) PutManyCtx(ctx context.Context, values map[string]any, seconds int) error {
+	if seconds <= 0 {
+		seconds = foreverSeconds
+	}
+
+	expiration := s.expirationTimestamp(time.Duration(seconds) * time.Second)
+
+	tx, err := s.connection.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	for key, value := range values {
+		data, err := s.serialize(value)
+
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, s.upsertStatement(), s.prefix+key, data, expiration); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.collectGarbage()
+
+	return nil
+}
+
+// Store an item in the cache if the key does not exist.
+func (s *DatabaseStore) Add(key string, value any, ttl ...any) bool {
+	duration, ok := s.ttlDuration(ttl...)
+
+	if !ok {
+		return false
+	}
+
+	added, err := s.add(context.Background(), key, value, duration)
+
+	return err == nil && added
+}
+
+// Store an item in the cache if the key does not exist, reporting whether it was stored.
+func (s *DatabaseStore) AddCtx(ctx context.Context, key string, value any, seconds int) (bool, error) {
+	return s.add(ctx, key, value, time.Duration(seconds)*time.Second)
+}
+
+// Store an item if the key does not exist, keeping it forever when the TTL is not positive.
+func (s *DatabaseStore) add(ctx context.Context, key string, value any, ttl time.Duration) (bool, error) {
+	data, err := s.serialize(value)
+
+	if err != nil {
+		return false, err
+	}
+
+	if ttl <= 0 {
+		ttl = foreverSeconds * time.Second
+	}
+
+	expiration := s.expirationTimestamp(ttl)
+
+	// The insert fails when the key is already present. An expired row still
+	// owns the key though, so it is taken over as long as it is expired.
+	_, err = s.connection.ExecContext(ctx, fmt.Sprintf(
+		"insert into %s (%s, %s, %s) values (%s, %s, %s)",
+		s.wrappedTable(), s.column("key"), s.column("value"), s.column("expiration"),
+		s.grammar.parameter(1), s.grammar.parameter(2), s.grammar.parameter(3),
+	), s.prefix+key, data, expiration)
+
+	if err == nil {
+		return true, nil
+	}
+
+	result, err := s.connection.ExecContext(ctx, fmt.Sprintf(
+		"update %s set %s = %s, %s = %s where %s = %s and %s <= %s",
+		s.wrappedTable(), s.column("value"), s.grammar.parameter(1), s.column("expiration"), s.grammar.parameter(2),
+		s.column("key"), s.grammar.parameter(3), s.column("expiration"), s.grammar.parameter(4),
+	), data, expiration, s.prefix+key, s.currentTime().Unix())
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	return err == nil && affected > 0, err
+}
+
+// Increment the value of an item in the cache.
+func (s *DatabaseStore) Increment(key string, value ...any) any {
+	result, err := s.incrementOrDecrement(context.Background(), key, int64(incrementStep(value...)))
+
+	if err != nil {
+		return false
+	}
+
+	return int(result)
+}
+
+// Increment the value of an item in the cache and return the new value.
+func (s *DatabaseStore) IncrementCtx(ctx context.Context, key string, value int64) (int64, error) {
+	return s.incrementOrDecrement(ctx, key, value)
+}
+
+// Decrement the value of an item in the cache.
+func (s *DatabaseStore) Decrement(key string, value ...any) any {
+	result, err := s.incrementOrDecrement(context.Background(), key, int64(incrementStep(value...))*-1)
+
+	if err != nil {
+		return false
+	}
+
+	return int(result)
+}
+
+// Decrement the value of an item in the cache and return the new value.
+func (s *DatabaseStore) DecrementCtx(ctx context.Context, key string, value int64) (int64, error) {
+	return s.incrementOrDecrement(ctx, key, value*-1)
+}
+
+// Increment or decrement an existing item in the cache.
+func (s *DatabaseStore) incrementOrDecrement(ctx context.Context, key string, step int64) (int64, error) {
+	result, exists, err := s.countExisting(ctx, key, incrementBy(step))
+
+	if err == nil && !exists {
+		return 0, ErrMissing
+	}
+
+	return integerResult(result, err)
+}
+
+// Add the given step to an integer counter and return the new value.
+func (s *DatabaseStore) IncrementBy(key string, value int64) (int64, error) {
+	return integerResult(s.count(context.Background(), key, incrementBy(value)))
+}
+
+// Subtract the given step from an integer counter, stopping at zero when floor is set.
+func (s *DatabaseStore) DecrementBy(key string, value int64, floor bool) (int64, error) {
+	return integerResult(s.count(context.Background(), key, decrementBy(value, floor)))
+}
+
+// Add the given step to a floating point counter and return the new value.
+func (s *DatabaseStore) IncrementFloat(key string, value float64) (float64, error) {
+	return floatResult(s.count(context.Background(), key, incrementFloatBy(value)))
+}
+
+// Apply a counter change to an item.
+//
+// Existing rows are changed inside a transaction with the row locked. Missing
+// counters are created with an insert that fails when another process created
+// the row first, in which case the change is applied to that row instead.
+func (s *DatabaseStore) count(ctx context.Context, key string, change counterChange) (any, error) {
+	for {
+		result, exists, err := s.countExisting(ctx, key, change)
+
+		if err != nil || exists {
+			return result, err
+		}
+
+		if result, err = change(nil, false); err != nil {
+			return nil, err
+		}
+
+		added, err := s.add(ctx, key, result, 0)
+
+		if err != nil || added {
+			return result, err
+		}
+	}
+}
+
+// Apply a counter change to an existing item, reporting whether it existed.
+//
+// The row is read and written inside a single transaction with the row
+// locked, so concurrent updates from other processes are never lost.
+func (s *DatabaseStore) countExisting(ctx context.Context, key string, change counterChange) (any, bool, error) {
+	tx, err := s.connection.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	defer tx.Rollback()
+
+	var raw string
+	var expiration int64
+
+	err = tx.QueryRowContext(ctx, fmt.Sprintf(
+		"select %s, %s from %s where %s = %s%s",
+		s.column("value"), s.column("expiration"), s.wrappedTable(), s.column("key"), s.grammar.parameter(1), s.grammar.lockForUpdate(),
+	), s.prefix+key).Scan(&raw, &expiration)
+
+	// If there is no value in the cache, we will report the missing item here.
+	// Otherwise the value will be unserialized and the change applied to it.
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && s.currentTime().Unix() >= expiration) {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	current, err := s.unserialize(raw)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	result, err := change(current, true)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	data, err := s.serialize(result)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(
+		"update %s set %s = %s where %s = %s",
+		s.wrappedTable(), s.column("value"), s.grammar.parameter(1), s.column("key"), s.grammar.parameter(2),
+	), data, s.prefix+key)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	return result, true, tx.Commit()
+}
+
+// Store an item in the cache indefinitely.
+func (s *DatabaseStore) Forever(key string, value any) bool {
+	return s.Put(key, value, foreverSeconds)
+}
+
+// Store an item in the cache indefinitely.
+func (s *DatabaseStore) ForeverCtx(ctx context.Context, key string, value any) error {
+	return s.PutCtx(ctx, key, value, foreverSeconds)
+}
+
+// Remove an item from the cache.
+func (s *DatabaseStore) Forget(key string) bool {
+	forgotten, err := s.ForgetCtx(context.Background(), key)
+
+	return err == nil && forgotten
+}
+
+// Remove an item from the cache, reporting whether it existed.
+func (s *DatabaseStore) ForgetCtx(ctx context.Context, key string) (bool, error) {
+	result, err := s.connection.ExecContext(ctx, fmt.Sprintf(
+		"delete from %s where %s = %s",
+		s.wrappedTable(), s.column("key"), s.grammar.parameter(1),
+	), s.prefix+key)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	return affected > 0, err
+}
+
+// Remove all items from the cache.
+func (s *DatabaseStore) Flush() bool {
+	return s.FlushCtx(context.Background()) == nil
+}
+
+// Remove all items from the cache.
+func (s *DatabaseStore) FlushCtx(ctx context.Context) error {
+	_, err := s.connection.ExecContext(ctx, fmt.Sprintf("delete from %s", s.wrappedTable()))
+
+	return err
+}
+
+// Remove every expired row from the cache table.
+func (s *DatabaseStore) Prune() bool {
+	_, err := s.connection.Exec(fmt.Sprintf(
+		"delete from %s where %s <= %s",
+		s.wrappedTable(), s.column("expiration"), s.grammar.parameter(1),
+	), s.currentTime().Unix())
+
+	return err == nil
+}
+
+// Get the underlying database connection.
+func (s *DatabaseStore) GetConnection() *sql.DB {
+	return s.connection
+}
+
+// Set the SQL dialect used to build queries.
+func (s *DatabaseStore) SetGrammar(grammar DatabaseGrammar) *DatabaseStore {
+	s.grammar = grammar
+
+	return s
+}
+
+// Set the odds of pruning expired rows after a write.
+func (s *DatabaseStore) SetGarbageCollectionLottery(chances int, outOf int) *DatabaseStore {
+	s.gcLottery = [2]int{chances, outOf}
+
+	return s
+}
+
+// Get the serializer of the stored values.
+func (s *DatabaseStore) GetSerializer() contracts.Serializer {
+	return s.serializer
+}
+
+// Set the serializer of the stored values.
+func (s *DatabaseStore) SetSerializer(serializer contracts.Serializer) *DatabaseStore {
+	s.serializer = serializer
+
+	return s
+}
+
+// Get the cache key prefix.
+func (s *DatabaseStore) GetPrefix() string {
+	return s.prefix
+}
+
+// Prune expired rows if the lottery is won.
+func (s *DatabaseStore) collectGarbage() {
+	if s.gcLottery[1] > 0 && rand.Intn(s.gcLottery[1]) < s.gcLottery[0] {
+		s.Prune()
+	}
+}
+
+// Get the upsert statement for a single row.
+func (s *DatabaseStore) upsertStatement() string {
+	insert := fmt.Sprintf(
+		"insert into %s (%s, %s, %s) values (%s, %s, %s)",
+		s.wrappedTable(), s.column("key"), s.column("value"), s.column("expiration"),
+		s.grammar.parameter(1), s.grammar.parameter(2), s.grammar.parameter(3),
+	)
+
+	if s.grammar == MySqlGrammar {
+		return insert + fmt.Sprintf(
+			" on duplicate key update %[1]s = values(%[1]s), %[2]s = values(%[2]s)",
+			s.column("value"), s.column("expiration"),
+		)
+	}
+
+	return insert + fmt.Sprintf(
+		" on conflict (%[1]s) do update set %[2]s = excluded.%[2]s, %[3]s = excluded.%[3]s",
+		s.column("key"), s.column("value"), s.column("expiration"),
+	)
+}
+
+// Get the wrapped name of the cache table.
+func (s *DatabaseStore) wrappedTable() string {
+	return s.grammar.wrap(s.table)
+}
+
+// Get the wrapped name of the given column.
+func (s *DatabaseStore) column(name string) string {
+	return s.grammar.wrap(name)
+}
+
+// Serialize the given value into a text column safe string.
+func (s *DatabaseStore) serialize(value any) (string, error) {
+	data, err := s.serializer.Serialize(value)
+
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// Unserialize the given text column value.
+func (s *DatabaseStore) unserialize(value string) (any, error) {
+	data, err := base64.StdEncoding.DecodeString(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.serializer.Unserialize(data)
+}
@@ -0,0 +1,107 @@
+package cache
+
+// The data shared by every cache event.
+type CacheEvent struct {
+	// The name of the cache store.
+	StoreName string
+	// The key of the event.
+	Key string
+	// The tags that were assigned to the key.
+	Tags []string
+}
+
+// Create a new cache event instance.
+func NewCacheEvent(storeName string, key string, tags ...string) CacheEvent {
+	return CacheEvent{StoreName: storeName, Key: key, Tags: tags}
+}
+
+// Set the tags for the cache event.
+func (e CacheEvent) SetTags(tags []string) CacheEvent {
+	e.Tags = tags
+
+	return e
+}
+
+// Dispatched when an item was found in the cache.
+type CacheHit struct {
+	CacheEvent
+	// The value that was retrieved.
+	Value any
+}
+
+// Create a new cache hit event instance.
+func NewCacheHit(storeName string, key string, value any, tags ...string) CacheHit {
+	return CacheHit{CacheEvent: NewCacheEvent(storeName, key, tags...), Value: value}
+}
+
+// Dispatched when an item was not found in the cache.
+type CacheMissed struct {
+	CacheEvent
+}
+
+// Create a new cache missed event instance.
+func NewCacheMissed(storeName string, key string, tags ...string) CacheMissed {
+	return CacheMissed{CacheEvent: NewCacheEvent(storeName, key, tags...)}
+}
+
+// Dispatched when an item was written to the cache.
+type KeyWritten struct {
+	CacheEvent
+	// The value that was written.
+	Value any
+	// The number of seconds the key should be valid, zero when stored indefinitely.
+	Seconds int
+}
+
+// Create a new key written event instance.
+func NewKeyWritten(storeName string, key string, value any, seconds int, tags ...string) KeyWritten {
+	return KeyWritten{CacheEvent: NewCacheEvent(storeName, key, tags...), Value: value, Seconds: seconds}
+}
+
+// Dispatched when an item was removed from the cache.
+type KeyForgotten struct {
+	CacheEvent
+}
+
+// Create a new key forgotten event instance.
+func NewKeyForgotten(storeName string, key string, tags ...string) KeyForgotten {
+	return KeyForgotten{CacheEvent: NewCacheEvent(storeName, key, tags...)}
+}
+
+// Dispatched when a backend of a failover store failed and calls moved on to the next backend.
+type CacheFailedOver struct {
+	CacheEvent
+	// The position of the failed backend within the failover store.
+	Backend int
+	// The failure of the backend.
+	Err error
+}
+
+// Create a new cache failed over event instance.
+func NewCacheFailedOver(storeName string, key string, backend int, err error) CacheFailedOver {
+	return CacheFailedOver{CacheEvent: NewCacheEvent(storeName, key), Backend: backend, Err: err}
+}
+
+// Dispatched when a failed backend of a failover store served a call again.
+type CacheRecovered struct {
+	CacheEvent
+	// The position of the recovered backend within the failover store.
+	Backend int
+}
+
+// Create a new cache recovered event instance.
+func NewCacheRecovered(storeName string, key string, backend int) CacheRecovered {
+	return CacheRecovered{CacheEvent: NewCacheEvent(storeName, key), Backend: backend}
+}
+
+// Dispatched when the background refresh of a flexible item panicked.
+type CacheRefreshFailed struct {
+	CacheEvent
+	// The panic of the refresh, as an error.
+	Err error
+}
+
+// Create a new cache refresh failed event instance.
+func NewCacheRefreshFailed(storeName string, key string, err error, tags ...string) CacheRefreshFailed {
+	return CacheRefreshFailed{CacheEvent: NewCacheEvent(storeName, key, tags...), Err: err}
+}
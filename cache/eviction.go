@@ -0,0 +1,266 @@
+package cache
+
+import (
+	"container/heap"
+	"container/list"
+	"fmt"
+)
+
+// The policy choosing which items a bounded store evicts when it is full.
+type EvictionPolicy int
+
+const (
+	// Evict the least recently used item.
+	LRU EvictionPolicy = iota
+	// Evict the least frequently used item, the least recently used first among equals.
+	LFU
+	// Evict the least recently used item, but only admit a new item when it
+	// is estimated to be used more often than the item it would evict.
+	TinyLFU
+)
+
+// Get the name of the eviction policy.
+func (p EvictionPolicy) String() string {
+	switch p {
+	case LRU:
+		return "lru"
+	case LFU:
+		return "lfu"
+	case TinyLFU:
+		return "tinylfu"
+	}
+
+	return fmt.Sprintf("EvictionPolicy(%d)", int(p))
+}
+
+// Parse the name of an eviction policy.
+func ParseEvictionPolicy(name string) (EvictionPolicy, error) {
+	for _, policy := range []EvictionPolicy{LRU, LFU, TinyLFU} {
+		if policy.String() == name {
+			return policy, nil
+		}
+	}
+
+	return LRU, fmt.Errorf("eviction policy [%s] is not supported", name)
+}
+
+// The reason an item left a bounded store without being forgotten.
+type EvictionReason int
+
+const (
+	// The item was evicted to make room for another item.
+	EvictedForCapacity EvictionReason = iota
+	// The item was removed because it expired.
+	EvictedExpired
+)
+
+// Get the name of the eviction reason.
+func (r EvictionReason) String() string {
+	switch r {
+	case EvictedForCapacity:
+		return "capacity"
+	case EvictedExpired:
+		return "expired"
+	}
+
+	return fmt.Sprintf("EvictionReason(%d)", int(r))
+}
+
+// The order in which the items of a memory shard are evicted.
+type evictionOrder interface {
+	// Track a new item.
+	add(entry *memoryEntry)
+	// Record an access to a tracked item.
+	touch(entry *memoryEntry)
+	// Stop tracking an item.
+	remove(entry *memoryEntry)
+	// Get the item to evict next, nil when there is none.
+	victim() *memoryEntry
+}
+
+// Create the eviction order of the given policy.
+func newEvictionOrder(policy EvictionPolicy) evictionOrder {
+	if policy == LFU {
+		return &lfuOrder{}
+	}
+
+	return &lruOrder{items: list.New()}
+}
+
+// Evicts the least recently used item first.
+type lruOrder struct {
+	// The items, most recently used at the front.
+	items *list.List
+}
+
+func (o *lruOrder) add(entry *memoryEntry) {
+	entry.element = o.items.PushFront(entry)
+}
+
+func (o *lruOrder) touch(entry *memoryEntry) {
+	o.items.MoveToFront(entry.element)
+}
+
+func (o *lruOrder) remove(entry *memoryEntry) {
+	o.items.Remove(entry.element)
+	entry.element = nil
+}
+
+func (o *lruOrder) victim() *memoryEntry {
+	if back := o.items.Back(); back != nil {
+		return back.Value.(*memoryEntry)
+	}
+
+	return nil
+}
+
+// Evicts the least frequently used item first.
+//
+// The items are kept in a min-heap ordered by access count, then by the time
+// of the last access, so ties go to the least recently used item.
+type lfuOrder struct {
+	// The heap of items.
+	entries []*memoryEntry
+	// The logical clock stamping accesses.
+	clock uint64
+}
+
+func (o *lfuOrder) add(entry *memoryEntry) {
+	o.clock++
+	entry.frequency = 1
+	entry.accessed = o.clock
+
+	heap.Push(o, entry)
+}
+
+func (o *lfuOrder) touch(entry *memoryEntry) {
+	o.clock++
+	entry.frequency++
+	entry.accessed = o.clock
+
+	heap.Fix(o, entry.index)
+}
+
+func (o *lfuOrder) remove(entry *memoryEntry) {
+	heap.Remove(o, entry.index)
+}
+
+func (o *lfuOrder) victim() *memoryEntry {
+	if len(o.entries) == 0 {
+		return nil
+	}
+
+	return o.entries[0]
+}
+
+func (o *lfuOrder) Len() int {
+	return len(o.entries)
+}
+
+func (o *lfuOrder) Less(i, j int) bool {
+	if o.entries[i].frequency != o.entries[j].frequency {
+		return o.entries[i].frequency < o.entries[j].frequency
+	}
+
+	return o.entries[i].accessed < o.entries[j].accessed
+}
+
+func (o *lfuOrder) Swap(i, j int) {
+	o.entries[i], o.entries[j] = o.entries[j], o.entries[i]
+	o.entries[i].index = i
+	o.entries[j].index = j
+}
+
+func (o *lfuOrder) Push(value any) {
+	entry := value.(*memoryEntry)
+	entry.index = len(o.entries)
+	o.entries = append(o.entries, entry)
+}
+
+func (o *lfuOrder) Pop() any {
+	last := len(o.entries) - 1
+	entry := o.entries[last]
+	o.entries[last] = nil
+	o.entries = o.entries[:last]
+
+	return entry
+}
+
+// A count-min sketch estimating how often keys were accessed recently.
+//
+// Counters saturate at fifteen and are all halved once the sketch has seen
+// ten times as many accesses as it has counters per row, so the estimates
+// follow the recent popularity of keys rather than their whole history.
+type frequencySketch struct {
+	// The counters, four rows of the same width laid out one after another.
+	counters []uint8
+	// The width of a row minus one, the width being a power of two.
+	mask uint64
+	// The number of accesses recorded since the last halving.
+	additions int
+	// The number of accesses after which the counters are halved.
+	sampleSize int
+}
+
+// The number of rows of a frequency sketch.
+const sketchDepth = 4
+
+// Create a frequency sketch sized for the given number of items.
+func newFrequencySketch(capacity int) *frequencySketch {
+	width := 64
+
+	for width < capacity {
+		width <<= 1
+	}
+
+	return &frequencySketch{
+		counters:   make([]uint8, width*sketchDepth),
+		mask:       uint64(width - 1),
+		sampleSize: width * 10,
+	}
+}
+
+// Record an access to the key with the given hash.
+func (s *frequencySketch) increment(hash uint64) {
+	for i := 0; i < sketchDepth; i++ {
+		if index := s.index(hash, i); s.counters[index] < 15 {
+			s.counters[index]++
+		}
+	}
+
+	if s.additions++; s.additions >= s.sampleSize {
+		s.reset()
+	}
+}
+
+// Estimate how often the key with the given hash was accessed.
+func (s *frequencySketch) estimate(hash uint64) uint8 {
+	result := uint8(15)
+
+	for i := 0; i < sketchDepth; i++ {
+		if count := s.counters[s.index(hash, i)]; count < result {
+			result = count
+		}
+	}
+
+	return result
+}
+
+// Halve every counter.
+func (s *frequencySketch) reset() {
+	for i := range s.counters {
+		s.counters[i] >>= 1
+	}
+
+	s.additions /= 2
+}
+
+// Get the position of the counter of the key in the given row.
+func (s *frequencySketch) index(hash uint64, row int) uint64 {
+	// The hash also picks the shard, so it is mixed again to spread the keys
+	// of one shard over the whole row.
+	mixed := hash * 0x9e3779b97f4a7c15
+	h1, h2 := mixed>>32, mixed|1
+
+	return uint64(row)*(s.mask+1) + (h1+uint64(row)*h2)&s.mask
+}
@@ -0,0 +1,496 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	contracts "github.com/garavel-core/framework/contracts/cache"
+	"github.com/garavel-core/framework/contracts/events"
+	"github.com/garavel-core/framework/support/arr"
+)
+
+// The error recorded when a backend reports a failed write without a cause.
+var ErrStoreFailed = errors.New("cache: the store reported a failure")
+
+// FailoverStore serves calls from the first healthy backend of an ordered list.
+//
+// A backend fails a call when it panics or reports an error. Backends that
+// implement contracts.ContextStore report errors through that API, so misses
+// and refused additions are told apart from failures without extra round
+// trips. Other backends only fail when they report a failed write, or when a
+// counter change fails and a Ping confirms the backend is unreachable. A
+// failed backend is skipped for the cool-down period and the call moves on
+// to the next backend. Values written while a backend was skipped are not
+// copied back to it once it recovers.
+type FailoverStore struct {
+	interactsWithTime
+	// The ordered backends.
+	stores []contracts.Store
+	// The names of the backends reported in events.
+	names []string
+	// The time until which each backend is skipped.
+	unhealthyUntil []time.Time
+	// The time a failed backend is skipped for.
+	cooldown time.Duration
+	// The event dispatcher implementation.
+	events events.Dispatcher
+	// Guards the health of the backends.
+	mutex sync.Mutex
+}
+
+// Create a new failover store over the given backends, in order of preference.
+func NewFailoverStore(stores ...contracts.Store) *FailoverStore {
+	names := make([]string, len(stores))
+
+	for i := range stores {
+		names[i] = strconv.Itoa(i)
+	}
+
+	return &FailoverStore{
+		stores:         stores,
+		names:          names,
+		unhealthyUntil: make([]time.Time, len(stores)),
+		cooldown:       30 * time.Second,
+	}
+}
+
+// Set the clock the store reads the current time from, the system clock when nil.
+func (s *FailoverStore) SetClock(clock func() time.Time) *FailoverStore {
+	s.clock = clock
+
+	return s
+}
+
+// Retrieve an item from the cache by key.
+func (s *FailoverStore) Get(key string) any {
+	return s.attempt(key, func(store contracts.Store) (any, error) {
+		if store, ok := store.(contracts.ContextStore); ok {
+			value, _, err := store.GetCtx(context.Background(), key)
+
+			return value, err
+		}
+
+		return store.Get(key), nil
+	})
+}
+
+// Retrieve multiple items from the cache by key.
+//
+// Items not found in the cache will have a null value.
+func (s *FailoverStore) Many(keys []string) map[string]any {
+	result := s.attempt(firstKey(keys), func(store contracts.Store) (any, error) {
+		if store, ok := store.(contracts.ContextStore); ok {
+			return store.ManyCtx(context.Background(), keys)
+		}
+
+		return store.Many(keys), nil
+	})
+
+	if values, ok := result.(map[string]any); ok {
+		return values
+	}
+
+	return nullValues(keys)
+}
+
+// Store an item in the cache for a given number of seconds.
+func (s *FailoverStore) Put(key string, value any, seconds int) bool {
+	return s.attemptBool(key, func(store contracts.Store) error {
+		if store, ok := store.(contracts.ContextStore); ok {
+			return store.PutCtx(context.Background(), key, value, seconds)
+		}
+
+		return failure(store.Put(key, value, seconds))
+	})
+}
+
+// Store multiple items in the cache for a given number of seconds.
+func (s *FailoverStore) PutMany(values map[string]any, seconds int) bool {
+	return s.attemptBool(firstKey(arr.Keys(values)), func(store contracts.Store) error {
+		if store, ok := store.(contracts.ContextStore); ok {
+			return store.PutManyCtx(context.Background(), values, seconds)
+		}
+
+		return failure(store.PutMany(values, seconds))
+	})
+}
+
+// Store an item in the cache for a given duration, keeping sub-second precision.
+func (s *FailoverStore) PutFor(key string, value any, ttl time.Duration) bool {
+	return s.attemptBool(key, func(store contracts.Store) error {
+		if store, ok := store.(contracts.PreciseStore); ok {
+			return failure(store.PutFor(key, value, ttl))
+		}
+
+		return failure(store.Put(key, value, durationSeconds(ttl)))
+	})
+}
+
+// Store multiple items in the cache for a given duration, keeping sub-second precision.
+func (s *FailoverStore) PutManyFor(values map[string]any, ttl time.Duration) bool {
+	return s.attemptBool(firstKey(arr.Keys(values)), func(store contracts.Store) error {
+		if store, ok := store.(contracts.PreciseStore); ok {
+			return failure(store.PutManyFor(values, ttl))
+		}
+
+		return failure(store.PutMany(values, durationSeconds(ttl)))
+	})
+}
+
+// Store an item in the cache if the key does not exist.
+//
+// Backends implementing contracts.ContextStore receive the TTL rounded up to
+// whole seconds, so that a refused addition is not taken for a failure.
+func (s *FailoverStore) Add(key string, value any, ttl ...any) bool {
+	duration, ok := s.ttlDuration(ttl...)
+
+	if !ok {
+		return false
+	}
+
+	seconds := durationSeconds(duration)
+
+	result := s.attempt(key, func(store contracts.Store) (any, error) {
+		if store, ok := store.(contracts.ContextStore); ok {
+			return store.AddCtx(context.Background(), key, value, seconds)
+		}
+
+		if store, ok := store.(contracts.AtomicStore); ok {
+			return store.Add(key, value, ttl...), nil
+		}
+
+		if store.Get(key) != nil {
+			return false, nil
+		}
+
+		if seconds > 0 {
+			return true, failure(store.Put(key, value, seconds))
+		}
+
+		return true, failure(store.Forever(key, value))
+	})
+
+	return result == true
+}
+
+// Increment the value of an item in the cache.
+func (s *FailoverStore) Increment(key string, value ...any) any {
+	return s.attempt(key, func(store contracts.Store) (any, error) {
+		return s.counted(store, store.Increment(key, value...))
+	})
+}
+
+// Decrement the value of an item in the cache.
+func (s *FailoverStore) Decrement(key string, value ...any) any {
+	return s.attempt(key, func(store contracts.Store) (any, error) {
+		return s.counted(store, store.Decrement(key, value...))
+	})
+}
+
+// Add the given step to an integer counter and return the new value.
+func (s *FailoverStore) IncrementBy(key string, value int64) (int64, error) {
+	result, err := s.count(key, func(store contracts.Store) (any, error) {
+		return incrementStore(store, key, value)
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.(int64), nil
+}
+
+// Subtract the given step from an integer counter and return the new value.
+func (s *FailoverStore) DecrementBy(key string, value int64, floor bool) (int64, error) {
+	result, err := s.count(key, func(store contracts.Store) (any, error) {
+		return decrementStore(store, key, value, floor)
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.(int64), nil
+}
+
+// Add the given step to a floating point counter and return the new value.
+func (s *FailoverStore) IncrementFloat(key string, value float64) (float64, error) {
+	result, err := s.count(key, func(store contracts.Store) (any, error) {
+		return incrementFloatStore(store, key, value)
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.(float64), nil
+}
+
+// Store an item in the cache indefinitely.
+func (s *FailoverStore) Forever(key string, value any) bool {
+	return s.attemptBool(key, func(store contracts.Store) error {
+		if store, ok := store.(contracts.ContextStore); ok {
+			return store.ForeverCtx(context.Background(), key, value)
+		}
+
+		return failure(store.Forever(key, value))
+	})
+}
+
+// Remove an item from the cache.
+//
+// The item is removed from every available backend, so a stale copy does
+// not resurface once calls move back to a recovered backend.
+func (s *FailoverStore) Forget(key string) bool {
+	return s.everyAvailable(func(store contracts.Store) (bool, error) {
+		if store, ok := store.(contracts.ContextStore); ok {
+			return store.ForgetCtx(context.Background(), key)
+		}
+
+		return store.Forget(key), nil
+	})
+}
+
+// Remove all items from the cache.
+//
+// Every available backend is flushed.
+func (s *FailoverStore) Flush() bool {
+	return s.everyAvailable(func(store contracts.Store) (bool, error) {
+		if store, ok := store.(contracts.ContextStore); ok {
+			err := store.FlushCtx(context.Background())
+
+			return err == nil, err
+		}
+
+		return store.Flush(), nil
+	})
+}
+
+// Begin executing a new tags operation.
+func (s *FailoverStore) Tags(names ...string) contracts.TaggedCache {
+	return NewTaggedCache(s, NewTagSet(s, names))
+}
+
+// Get a lock instance.
+//
+// Locks are always held by the first backend, even while calls fail over,
+// since a lock split across backends would no longer exclude anyone. Locks
+// cannot be acquired while the first backend is down.
+func (s *FailoverStore) Lock(name string, seconds int, owner ...string) contracts.Lock {
+	if len(s.stores) == 0 {
+		return NewCacheLock(s, name, seconds, owner...)
+	}
+
+	if store, ok := s.stores[0].(contracts.LockProvider); ok {
+		return store.Lock(name, seconds, owner...)
+	}
+
+	return NewCacheLock(s.stores[0], name, seconds, owner...)
+}
+
+// Restore a lock instance using the owner identifier.
+func (s *FailoverStore) RestoreLock(name string, owner string) contracts.Lock {
+	return s.Lock(name, 0, owner)
+}
+
+// Get the cache key prefix.
+func (s *FailoverStore) GetPrefix() string {
+	if len(s.stores) == 0 {
+		return ""
+	}
+
+	return s.stores[0].GetPrefix()
+}
+
+// Get the backends of the store.
+func (s *FailoverStore) GetStores() []contracts.Store {
+	return s.stores
+}
+
+// Set the names of the backends reported in events, in the order of the backends.
+func (s *FailoverStore) SetStoreNames(names ...string) *FailoverStore {
+	copy(s.names, names)
+
+	return s
+}
+
+// Set the time a failed backend is skipped for.
+func (s *FailoverStore) SetCooldown(cooldown time.Duration) *FailoverStore {
+	s.cooldown = cooldown
+
+	return s
+}
+
+// Determine if the backend at the given position may be used.
+func (s *FailoverStore) Available(backend int) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return !s.currentTime().Before(s.unhealthyUntil[backend])
+}
+
+// Get the event dispatcher instance.
+func (s *FailoverStore) GetEventDispatcher() events.Dispatcher {
+	return s.events
+}
+
+// Set the event dispatcher instance.
+func (s *FailoverStore) SetEventDispatcher(events events.Dispatcher) *FailoverStore {
+	s.events = events
+
+	return s
+}
+
+// Run the operation against the first backend that does not fail it.
+func (s *FailoverStore) attempt(key string, operation func(store contracts.Store) (any, error)) any {
+	var result any
+
+	for i, store := range s.stores {
+		if !s.Available(i) {
+			continue
+		}
+
+		value, err := s.run(store, operation)
+
+		if err != nil {
+			s.markFailed(key, i, err)
+
+			if result == nil {
+				result = value
+			}
+
+			continue
+		}
+
+		s.markHealthy(key, i)
+
+		return value
+	}
+
+	return result
+}
+
+// Run the write against the first backend that does not fail it.
+func (s *FailoverStore) attemptBool(key string, operation func(store contracts.Store) error) bool {
+	result := s.attempt(key, func(store contracts.Store) (any, error) {
+		err := operation(store)
+
+		return err == nil, err
+	})
+
+	return result == true
+}
+
+// Run the operation against every available backend.
+func (s *FailoverStore) everyAvailable(operation func(store contracts.Store) (bool, error)) bool {
+	result := false
+
+	for i, store := range s.stores {
+		if !s.Available(i) {
+			continue
+		}
+
+		value, err := s.run(store, func(store contracts.Store) (any, error) {
+			return operation(store)
+		})
+
+		if err != nil {
+			s.markFailed("", i, err)
+
+			continue
+		}
+
+		if value == true {
+			result = true
+		}
+	}
+
+	return result
+}
+
+// Run the operation against the given backend, turning panics into errors.
+func (s *FailoverStore) run(store contracts.Store, operation func(store contracts.Store) (any, error)) (result any, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			result, err = nil, fmt.Errorf("cache: the store panicked: %v", recovered)
+		}
+	}()
+
+	return operation(store)
+}
+
+// Run a counter change against the first backend that does not fail it.
+//
+// Items that cannot be counted are an answer of the backend rather than a
+// failure, so ErrNotNumeric and ErrOverflow are returned without failing over.
+func (s *FailoverStore) count(key string, change func(store contracts.Store) (any, error)) (any, error) {
+	err := error(ErrStoreFailed)
+
+	result := s.attempt(key, func(store contracts.Store) (any, error) {
+		var value any
+
+		if value, err = change(store); errors.Is(err, ErrNotNumeric) || errors.Is(err, ErrOverflow) {
+			return nil, nil
+		}
+
+		return value, err
+	})
+
+	if result == nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Determine if the result of a counter change is a failure of the backend.
+//
+// Stores answer false both for items they cannot count and when they cannot
+// be reached, so only the failed changes are followed by a Ping.
+func (s *FailoverStore) counted(store contracts.Store, result any) (any, error) {
+	if result != false {
+		return result, nil
+	}
+
+	if store, ok := store.(contracts.PingableStore); ok {
+		return result, store.Ping()
+	}
+
+	return result, nil
+}
+
+// Skip the backend at the given position for the cool-down period.
+func (s *FailoverStore) markFailed(key string, backend int, err error) {
+	s.mutex.Lock()
+	s.unhealthyUntil[backend] = s.currentTime().Add(s.cooldown)
+	s.mutex.Unlock()
+
+	if s.events != nil {
+		s.events.Dispatch(NewCacheFailedOver(s.names[backend], key, backend, err))
+	}
+}
+
+// Record that the backend at the given position served a call.
+func (s *FailoverStore) markHealthy(key string, backend int) {
+	s.mutex.Lock()
+	recovered := !s.unhealthyUntil[backend].IsZero()
+	s.unhealthyUntil[backend] = time.Time{}
+	s.mutex.Unlock()
+
+	if recovered && s.events != nil {
+		s.events.Dispatch(NewCacheRecovered(s.names[backend], key, backend))
+	}
+}
+
+// Get the first of the given keys, if any.
+func firstKey(keys []string) string {
+	if len(keys) == 0 {
+		return ""
+	}
+
+	return keys[0]
+}
@@ -0,0 +1,41 @@
+package cache
+
+import "fmt"
+
+type FileLock struct {
+	*CacheLock
+	// The file store holding the lock.
+	store *FileStore
+}
+
+// Create a new lock instance.
+//
+// Acquiring goes through the locked add of the file store, so the lock is
+// exclusive across every process sharing the cache directory.
+func NewFileLock(store *FileStore, name string, seconds int, owner ...string) *FileLock {
+	lock := &FileLock{CacheLock: NewCacheLock(store, name, seconds, owner...), store: store}
+
+	lock.driver = lock
+
+	return lock
+}
+
+// Release the lock.
+//
+// The ownership check and the removal happen under the file lock of the key,
+// so a lock that expired and was acquired by another owner in between is
+// never removed by its previous owner. The owner is read straight from the
+// payload, as Get takes the file lock of expired items itself.
+func (l *FileLock) release() bool {
+	released := false
+
+	l.store.withLock(l.name, func() error {
+		if payload, ok := l.store.getPayload(l.name); ok && fmt.Sprint(payload.data) == l.owner {
+			released = l.store.Forget(l.name)
+		}
+
+		return nil
+	})
+
+	return released
+}
@@ -0,0 +1,462 @@
+package cache
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	contracts "github.com/garavel-core/framework/contracts/cache"
+)
+
+// The expiration timestamp written for items stored indefinitely.
+const foreverTimestamp int64 = 9999999999
+
+// The length of the expiration header at the start of each cache file.
+const expirationHeaderLength = 10
+
+// The contents of a cache file.
+type filePayload struct {
+	// The cached value.
+	data any
+	// The expiration timestamp of the item.
+	expiration int64
+}
+
+type FileStore struct {
+	interactsWithTime
+	// The file cache directory.
+	directory string
+	// The file cache lock directory.
+	lockDirectory string
+	// The permission that should be applied to cache files.
+	filePermission os.FileMode
+	// The serializer of the stored values.
+	serializer contracts.Serializer
+}
+
+// Create a new file cache store instance.
+func NewFileStore(directory string, filePermission ...os.FileMode) *FileStore {
+	permission := os.FileMode(0o644)
+
+	if filePermission != nil {
+		permission = filePermission[0]
+	}
+
+	return &FileStore{directory: directory, filePermission: permission, serializer: GobSerializer{}}
+}
+
+// Set the clock the store reads the current time from, the system clock when nil.
+func (s *FileStore) SetClock(clock func() time.Time) *FileStore {
+	s.clock = clock
+
+	return s
+}
+
+// Retrieve an item from the cache by key.
+func (s *FileStore) Get(key string) any {
+	payload, ok, expired := s.readPayload(key)
+
+	// Expired files are only removed under the lock of the key, after reading
+	// them again, so a value written in the meantime is never thrown away.
+	if expired {
+		s.withLock(key, func() error {
+			payload, ok = s.getPayload(key)
+
+			return nil
+		})
+	}
+
+	if !ok {
+		return nil
+	}
+
+	return payload.data
+}
+
+// Retrieve multiple items from the cache by key.
+//
+// Items not found in the cache will have a null value.
+func (s *FileStore) Many(keys []string) map[string]any {
+	results := make(map[string]any, len(keys))
+
+	for _, key := range keys {
+		results[key] = s.Get(key)
+	}
+
+	return results
+}
+
+// Store an item in the cache for a given number of seconds.
+//
+// The write holds the lock of the key, so a reader removing the expired file
+// it found cannot remove the value written here instead.
+func (s *FileStore) Put(key string, value any, seconds int) bool {
+	return s.withLock(key, func() error {
+		return s.write(key, value, s.expiration(seconds))
+	}) == nil
+}
+
+// Store multiple items in the cache for a given number of seconds.
+func (s *FileStore) PutMany(values map[string]any, seconds int) bool {
+	result := true
+
+	for key, value := range values {
+		if !s.Put(key, value, seconds) {
+			result = false
+		}
+	}
+
+	return result
+}
+
+// Store an item in the cache if the key does not exist.
+func (s *FileStore) Add(key string, value any, ttl ...any) bool {
+	duration, ok := s.ttlDuration(ttl...)
+
+	if !ok {
+		return false
+	}
+
+	expiration := foreverTimestamp
+
+	// Partial seconds are rounded up, as the file only keeps whole seconds.
+	if duration > 0 {
+		if at := s.expirationTimestamp(duration); at < foreverTimestamp {
+			expiration = at
+		}
+	}
+
+	added := false
+
+	err := s.withLock(key, func() error {
+		if _, exists := s.getPayload(key); exists {
+			return nil
+		}
+
+		if err := s.write(key, value, expiration); err != nil {
+			return err
+		}
+
+		added = true
+
+		return nil
+	})
+
+	return err == nil && added
+}
+
+// Increment the value of an item in the cache.
+func (s *FileStore) Increment(key string, value ...any) any {
+	step := incrementStep(value...)
+	result := 0
+
+	err := s.withLock(key, func() error {
+		payload, exists := s.getPayload(key)
+
+		if !exists {
+			payload.expiration = foreverTimestamp
+		}
+
+		current, _ := toInt(payload.data)
+		result = current + step
+
+		return s.write(key, result, payload.expiration)
+	})
+
+	if err != nil {
+		return false
+	}
+
+	return result
+}
+
+// Decrement the value of an item in the cache.
+func (s *FileStore) Decrement(key string, value ...any) any {
+	return s.Increment(key, incrementStep(value...)*-1)
+}
+
+// Add the given step to an integer counter and return the new value.
+func (s *FileStore) IncrementBy(key string, value int64) (int64, error) {
+	return integerResult(s.count(key, incrementBy(value)))
+}
+
+// Subtract the given step from an integer counter, stopping at zero when floor is set.
+func (s *FileStore) DecrementBy(key string, value int64, floor bool) (int64, error) {
+	return integerResult(s.count(key, decrementBy(value, floor)))
+}
+
+// Add the given step to a floating point counter and return the new value.
+func (s *FileStore) IncrementFloat(key string, value float64) (float64, error) {
+	return floatResult(s.count(key, incrementFloatBy(value)))
+}
+
+// Apply a counter change to an item while holding its cross-process lock.
+func (s *FileStore) count(key string, change counterChange) (any, error) {
+	var result any
+
+	err := s.withLock(key, func() error {
+		payload, exists := s.getPayload(key)
+
+		if !exists {
+			payload.expiration = foreverTimestamp
+		}
+
+		var err error
+
+		if result, err = change(payload.data, exists); err != nil {
+			return err
+		}
+
+		return s.write(key, result, payload.expiration)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Store an item in the cache indefinitely.
+func (s *FileStore) Forever(key string, value any) bool {
+	return s.Put(key, value, 0)
+}
+
+// Remove an item from the cache.
+func (s *FileStore) Forget(key string) bool {
+	return os.Remove(s.path(key)) == nil
+}
+
+// Remove all items from the cache.
+//
+// The cache directory is first moved aside and then deleted, so concurrent
+// writers either land in the old tree or start a fresh one, never in a tree
+// that is half removed.
+func (s *FileStore) Flush() bool {
+	directory := filepath.Clean(s.directory)
+
+	if directory == "." || directory == string(filepath.Separator) || directory == filepath.VolumeName(directory)+string(filepath.Separator) {
+		return false
+	}
+
+	if info, err := os.Stat(directory); err != nil || !info.IsDir() {
+		return false
+	}
+
+	trash, err := os.MkdirTemp(filepath.Dir(directory), filepath.Base(directory)+".flush-*")
+
+	if err != nil {
+		return false
+	}
+
+	if err := os.Rename(directory, filepath.Join(trash, filepath.Base(directory))); err != nil {
+		os.Remove(trash)
+
+		return false
+	}
+
+	return os.RemoveAll(trash) == nil
+}
+
+// Get a lock instance.
+func (s *FileStore) Lock(name string, seconds int, owner ...string) contracts.Lock {
+	store := s
+
+	if s.lockDirectory != "" {
+		store = NewFileStore(s.lockDirectory, s.filePermission).SetSerializer(s.serializer)
+		store.clock = s.clock
+	}
+
+	return NewFileLock(store, name, seconds, owner...)
+}
+
+// Restore a lock instance using the owner identifier.
+func (s *FileStore) RestoreLock(name string, owner string) contracts.Lock {
+	return s.Lock(name, 0, owner)
+}
+
+// Get the working directory of the cache.
+func (s *FileStore) GetDirectory() string {
+	return s.directory
+}
+
+// Set the cache directory where locks should be stored.
+func (s *FileStore) SetLockDirectory(lockDirectory string) *FileStore {
+	s.lockDirectory = lockDirectory
+
+	return s
+}
+
+// Get the serializer of the stored values.
+func (s *FileStore) GetSerializer() contracts.Serializer {
+	return s.serializer
+}
+
+// Set the serializer of the stored values.
+func (s *FileStore) SetSerializer(serializer contracts.Serializer) *FileStore {
+	s.serializer = serializer
+
+	return s
+}
+
+// Get the cache key prefix.
+func (s *FileStore) GetPrefix() string {
+	return ""
+}
+
+// Retrieve an item and expiry time from the cache by key, removing it when expired.
+//
+// The removal is only safe while holding the lock of the key.
+func (s *FileStore) getPayload(key string) (filePayload, bool) {
+	payload, ok, expired := s.readPayload(key)
+
+	if expired {
+		os.Remove(s.path(key))
+	}
+
+	return payload, ok
+}
+
+// Retrieve an item and expiry time from the cache by key, reporting whether it expired.
+func (s *FileStore) readPayload(key string) (payload filePayload, ok bool, expired bool) {
+	// If the file doesn't exist, we obviously cannot return the cache so we will
+	// just return an empty payload. Otherwise, we will get the contents of the
+	// file and get the expiration UNIX timestamps from the start of the file.
+	contents, err := os.ReadFile(s.path(key))
+
+	if err != nil || len(contents) < expirationHeaderLength {
+		return filePayload{}, false, false
+	}
+
+	expiration, err := strconv.ParseInt(string(contents[:expirationHeaderLength]), 10, 64)
+
+	// If the current time is greater than expiration timestamps the file has to
+	// be deleted and an empty payload returned. This makes sure we don't return
+	// a stale value from the cache. Unreadable files are treated the same.
+	if err != nil || s.currentTime().Unix() >= expiration {
+		return filePayload{}, false, true
+	}
+
+	data, err := s.serializer.Unserialize(contents[expirationHeaderLength:])
+
+	if err != nil {
+		return filePayload{}, false, true
+	}
+
+	return filePayload{data: data, expiration: expiration}, true, false
+}
+
+// Atomically write the value with the given expiration timestamp.
+//
+// The contents are written to a temporary file next to the cache file and
+// then renamed over it, so readers never observe a partially written file.
+func (s *FileStore) write(key string, value any, expiration int64) error {
+	data, err := s.serializer.Serialize(value)
+
+	if err != nil {
+		return err
+	}
+
+	path := s.path(key)
+
+	if err := s.ensureCacheDirectoryExists(path); err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(temp.Name())
+
+	if _, err := fmt.Fprintf(temp, "%010d", expiration); err != nil {
+		temp.Close()
+
+		return err
+	}
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+
+		return err
+	}
+
+	if err := temp.Chmod(s.filePermission); err != nil {
+		temp.Close()
+
+		return err
+	}
+
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), path)
+}
+
+// Run the callback while holding the cross-process lock of the given key.
+func (s *FileStore) withLock(key string, callback func() error) error {
+	path := s.lockPath(key)
+
+	if err := s.ensureCacheDirectoryExists(path); err != nil {
+		return err
+	}
+
+	file, err := openLockedFile(path, s.filePermission)
+
+	if err != nil {
+		return err
+	}
+
+	// The lock file is removed when we are done with it, which waiting callers
+	// notice before starting over with a lock file of their own.
+	defer file.Remove()
+
+	return callback()
+}
+
+// Create the file cache directory if necessary.
+func (s *FileStore) ensureCacheDirectoryExists(path string) error {
+	return os.MkdirAll(filepath.Dir(path), 0o755)
+}
+
+// Get the full path for the given cache key.
+func (s *FileStore) path(key string) string {
+	return s.pathIn(s.directory, key)
+}
+
+// Get the full path of the lock file for the given cache key.
+func (s *FileStore) lockPath(key string) string {
+	if s.lockDirectory != "" {
+		return s.pathIn(s.lockDirectory, key) + ".lock"
+	}
+
+	return s.path(key) + ".lock"
+}
+
+// Get the sharded path for the given cache key within a directory.
+func (s *FileStore) pathIn(directory string, key string) string {
+	sum := sha1.Sum([]byte(key))
+	hash := hex.EncodeToString(sum[:])
+
+	return filepath.Join(directory, hash[0:2], hash[2:4], hash)
+}
+
+// Get the expiration timestamp based on the given seconds.
+func (s *FileStore) expiration(seconds int) int64 {
+	if seconds <= 0 {
+		return foreverTimestamp
+	}
+
+	if at := s.expirationTimestamp(time.Duration(seconds) * time.Second); at < foreverTimestamp {
+		return at
+	}
+
+	return foreverTimestamp
+}
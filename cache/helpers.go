@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Get the step of an increment or decrement operation, defaulting to one.
+func incrementStep(value ...any) int {
+	if value == nil || value[0] == nil {
+		return 1
+	}
+
+	step, _ := toInt(value[0])
+
+	return step
+}
+
+// Cast the given value to an integer the way a cache driver would.
+func toInt(value any) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case int8:
+		return int(v), true
+	case int16:
+		return int(v), true
+	case int32:
+		return int(v), true
+	case int64:
+		return int(v), true
+	case uint:
+		return int(v), true
+	case uint8:
+		return int(v), true
+	case uint16:
+		return int(v), true
+	case uint32:
+		return int(v), true
+	case uint64:
+		return int(v), true
+	case float32:
+		return int(v), true
+	case float64:
+		return int(v), true
+	case bool:
+		if v {
+			return 1, true
+		}
+
+		return 0, true
+	case string:
+		return stringToInt(v)
+	case []byte:
+		return stringToInt(string(v))
+	}
+
+	return 0, false
+}
+
+// Parse the leading integer of the given string.
+func stringToInt(value string) (int, bool) {
+	value = strings.TrimSpace(value)
+
+	if i, err := strconv.Atoi(value); err == nil {
+		return i, true
+	}
+
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return int(f), true
+	}
+
+	return 0, false
+}
+
+// Get a result map where every key has a null value.
+func nullValues(keys []string) map[string]any {
+	results := make(map[string]any, len(keys))
+
+	for _, key := range keys {
+		results[key] = nil
+	}
+
+	return results
+}
@@ -0,0 +1,393 @@
+package cache
+
+import (
+	"strings"
+	"time"
+
+	contracts "github.com/garavel-core/framework/contracts/cache"
+	"github.com/garavel-core/framework/support/helpers"
+)
+
+// InstrumentedRepository records the hits, misses, writes, deletes, errors
+// and latencies of the calls made through a cache repository.
+//
+// Counters are kept per store and per key prefix. The prefix of a key is the
+// part before its first colon, so "users:1" and "users:2" are counted
+// together. Keys without a colon are counted under an empty prefix, which
+// keeps the number of series bounded. Operations on several keys are timed
+// under the prefix the keys share, or under "*" when they have none in common.
+type InstrumentedRepository struct {
+	// The decorated repository.
+	repository contracts.Repository
+	// The collector receiving the metrics.
+	metrics *Metrics
+	// The name of the store reported in the metrics.
+	store string
+	// The callback that resolves the prefix of a key.
+	prefixResolver func(key string) string
+}
+
+// Create a new instrumented repository reporting to the given metrics.
+func NewInstrumentedRepository(repository contracts.Repository, metrics *Metrics) *InstrumentedRepository {
+	instrumented := &InstrumentedRepository{repository: repository, metrics: metrics, prefixResolver: keyPrefix}
+
+	if named, ok := repository.(interface{ GetName() string }); ok {
+		instrumented.store = named.GetName()
+	}
+
+	return instrumented
+}
+
+// Fetches a value from the cache.
+func (r *InstrumentedRepository) Get(key string, defaultValue ...any) any {
+	var value any
+
+	r.measure("get", []string{key}, func() {
+		value = r.repository.Get(key)
+		r.read(key, value != nil)
+	})
+
+	if value == nil && defaultValue != nil && defaultValue[0] != nil {
+		return helpers.Value(defaultValue[0])
+	}
+
+	return value
+}
+
+// Persists data in the cache, uniquely referenced by a key with an optional expiration TTL time.
+func (r *InstrumentedRepository) Set(key string, value any, ttl ...any) bool {
+	return r.Put(key, value, ttl...)
+}
+
+// Delete an item from the cache by its unique key.
+func (r *InstrumentedRepository) Delete(key string) bool {
+	return r.Forget(key)
+}
+
+// Wipes clean the entire cache's keys.
+func (r *InstrumentedRepository) Clear() bool {
+	var result bool
+
+	r.measure("clear", nil, func() {
+		result = r.repository.Clear()
+
+		if !result {
+			r.record("", Counters{Errors: 1})
+		}
+	})
+
+	return result
+}
+
+// Obtains multiple cache items by their unique keys.
+func (r *InstrumentedRepository) GetMultiple(keys []string, defaultValue ...any) map[string]any {
+	var values map[string]any
+
+	r.measure("many", keys, func() {
+		values = r.repository.GetMultiple(keys)
+
+		for _, key := range keys {
+			r.read(key, values[key] != nil)
+		}
+	})
+
+	if defaultValue != nil && defaultValue[0] != nil {
+		for key, value := range values {
+			if value == nil {
+				values[key] = helpers.Value(defaultValue[0])
+			}
+		}
+	}
+
+	return values
+}
+
+// Persists a set of key => value pairs in the cache, with an optional TTL.
+func (r *InstrumentedRepository) SetMultiple(values map[string]any, ttl ...any) bool {
+	keys := make([]string, 0, len(values))
+
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	var result bool
+
+	r.measure("put_many", keys, func() {
+		result = r.repository.SetMultiple(values, ttl...)
+
+		for _, key := range keys {
+			r.written(key, result)
+		}
+	})
+
+	return result
+}
+
+// Deletes multiple cache items in a single operation.
+func (r *InstrumentedRepository) DeleteMultiple(keys []string) bool {
+	var result bool
+
+	r.measure("forget_many", keys, func() {
+		result = r.repository.DeleteMultiple(keys)
+
+		if result {
+			for _, key := range keys {
+				r.record(key, Counters{Deletes: 1})
+			}
+		}
+	})
+
+	return result
+}
+
+// Determines whether an item is present in the cache.
+func (r *InstrumentedRepository) Has(key string) bool {
+	var result bool
+
+	r.measure("has", []string{key}, func() {
+		result = r.repository.Has(key)
+		r.read(key, result)
+	})
+
+	return result
+}
+
+// Retrieve an item from the cache and delete it.
+func (r *InstrumentedRepository) Pull(key string, defaultValue ...any) any {
+	var value any
+
+	r.measure("pull", []string{key}, func() {
+		value = r.repository.Pull(key)
+		r.read(key, value != nil)
+
+		if value != nil {
+			r.record(key, Counters{Deletes: 1})
+		}
+	})
+
+	if value == nil && defaultValue != nil && defaultValue[0] != nil {
+		return helpers.Value(defaultValue[0])
+	}
+
+	return value
+}
+
+// Store an item in the cache.
+func (r *InstrumentedRepository) Put(key string, value any, ttl ...any) bool {
+	var result bool
+
+	r.measure("put", []string{key}, func() {
+		result = r.repository.Put(key, value, ttl...)
+		r.written(key, result)
+	})
+
+	return result
+}
+
+// Store an item in the cache if the key does not exist.
+func (r *InstrumentedRepository) Add(key string, value any, ttl ...any) bool {
+	var result bool
+
+	// A refused add means the key already exists, which is not an error.
+	r.measure("add", []string{key}, func() {
+		if result = r.repository.Add(key, value, ttl...); result {
+			r.record(key, Counters{Writes: 1})
+		}
+	})
+
+	return result
+}
+
+// Increment the value of an item in the cache.
+func (r *InstrumentedRepository) Increment(key string, value ...any) any {
+	var result any
+
+	r.measure("increment", []string{key}, func() {
+		result = r.repository.Increment(key, value...)
+		r.written(key, result != false)
+	})
+
+	return result
+}
+
+// Decrement the value of an item in the cache.
+func (r *InstrumentedRepository) Decrement(key string, value ...any) any {
+	var result any
+
+	r.measure("decrement", []string{key}, func() {
+		result = r.repository.Decrement(key, value...)
+		r.written(key, result != false)
+	})
+
+	return result
+}
+
+// Store an item in the cache indefinitely.
+func (r *InstrumentedRepository) Forever(key string, value any) bool {
+	var result bool
+
+	r.measure("put", []string{key}, func() {
+		result = r.repository.Forever(key, value)
+		r.written(key, result)
+	})
+
+	return result
+}
+
+// Get an item from the cache, or execute the given Closure and store the result.
+func (r *InstrumentedRepository) Remember(key string, ttl any, callback func() any) any {
+	return r.remember(key, callback, func(callback func() any) any {
+		return r.repository.Remember(key, ttl, callback)
+	})
+}
+
+// Get an item from the cache, or execute the given Closure and store the result forever.
+func (r *InstrumentedRepository) Sear(key string, callback func() any) any {
+	return r.RememberForever(key, callback)
+}
+
+// Get an item from the cache, or execute the given Closure and store the result forever.
+func (r *InstrumentedRepository) RememberForever(key string, callback func() any) any {
+	return r.remember(key, callback, func(callback func() any) any {
+		return r.repository.RememberForever(key, callback)
+	})
+}
+
+// Record a remember call, counting a miss and a write when the callback ran.
+func (r *InstrumentedRepository) remember(key string, callback func() any, remember func(callback func() any) any) any {
+	var value any
+
+	r.measure("remember", []string{key}, func() {
+		computed := false
+
+		value = remember(func() any {
+			computed = true
+
+			return callback()
+		})
+
+		if computed {
+			r.record(key, Counters{Misses: 1, Writes: 1})
+		} else {
+			r.record(key, Counters{Hits: 1})
+		}
+	})
+
+	return value
+}
+
+// Remove an item from the cache.
+func (r *InstrumentedRepository) Forget(key string) bool {
+	var result bool
+
+	r.measure("forget", []string{key}, func() {
+		if result = r.repository.Forget(key); result {
+			r.record(key, Counters{Deletes: 1})
+		}
+	})
+
+	return result
+}
+
+// Get the cache store implementation.
+func (r *InstrumentedRepository) GetStore() contracts.Store {
+	return r.repository.GetStore()
+}
+
+// Get the decorated repository.
+func (r *InstrumentedRepository) GetRepository() contracts.Repository {
+	return r.repository
+}
+
+// Get the collector receiving the metrics.
+func (r *InstrumentedRepository) GetMetrics() *Metrics {
+	return r.metrics
+}
+
+// Get a snapshot of the collected metrics.
+func (r *InstrumentedRepository) Stats() Stats {
+	return r.metrics.Stats()
+}
+
+// Set the name of the store reported in the metrics.
+func (r *InstrumentedRepository) SetStoreName(name string) *InstrumentedRepository {
+	r.store = name
+
+	return r
+}
+
+// Set the callback that resolves the prefix a key is counted under.
+func (r *InstrumentedRepository) SetPrefixResolver(resolver func(key string) string) *InstrumentedRepository {
+	r.prefixResolver = resolver
+
+	return r
+}
+
+// Time an operation on the given keys, counting an error when it panics.
+func (r *InstrumentedRepository) measure(operation string, keys []string, callback func()) {
+	prefix := r.commonPrefix(keys)
+	start := time.Now()
+
+	defer func() {
+		r.metrics.observe(LatencyKey{StatsKey{r.store, prefix}, operation}, time.Since(start))
+
+		if recovered := recover(); recovered != nil {
+			r.metrics.record(StatsKey{r.store, prefix}, Counters{Errors: 1})
+
+			panic(recovered)
+		}
+	}()
+
+	callback()
+}
+
+// Count a read of the given key.
+func (r *InstrumentedRepository) read(key string, hit bool) {
+	if hit {
+		r.record(key, Counters{Hits: 1})
+	} else {
+		r.record(key, Counters{Misses: 1})
+	}
+}
+
+// Count a write of the given key, or an error when it failed.
+func (r *InstrumentedRepository) written(key string, ok bool) {
+	if ok {
+		r.record(key, Counters{Writes: 1})
+	} else {
+		r.record(key, Counters{Errors: 1})
+	}
+}
+
+// Record counters under the prefix of the given key.
+func (r *InstrumentedRepository) record(key string, counters Counters) {
+	r.metrics.record(StatsKey{r.store, r.prefixResolver(key)}, counters)
+}
+
+// Get the prefix shared by all the given keys, "*" when they differ.
+func (r *InstrumentedRepository) commonPrefix(keys []string) string {
+	if len(keys) == 0 {
+		return ""
+	}
+
+	prefix := r.prefixResolver(keys[0])
+
+	for _, key := range keys[1:] {
+		if r.prefixResolver(key) != prefix {
+			return "*"
+		}
+	}
+
+	return prefix
+}
+
+// Get the part of a key before its first colon, empty when it has none.
+func keyPrefix(key string) string {
+	prefix, _, found := strings.Cut(key, ":")
+
+	if !found {
+		return ""
+	}
+
+	return prefix
+}
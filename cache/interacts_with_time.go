@@ -0,0 +1,153 @@
+package cache
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// The error returned for TTLs that cannot be resolved into a duration.
+var ErrInvalidTTL = errors.New("cache: the TTL is not supported")
+
+// Tells the time for the stores and repositories embedding it.
+//
+// The system clock is read unless another clock was set, which lets tests
+// expire items without waiting. The clock is set before the store is used.
+type interactsWithTime struct {
+	// The clock replacing the system clock, nil when the system clock is used.
+	clock func() time.Time
+}
+
+// Get the current system time.
+func (i *interactsWithTime) currentTime() time.Time {
+	if i.clock != nil {
+		return i.clock()
+	}
+
+	return time.Now()
+}
+
+// Get the "available at" time for the given number of seconds.
+func (i *interactsWithTime) availableAt(seconds int) time.Time {
+	return i.currentTime().Add(time.Duration(seconds) * time.Second)
+}
+
+// Get the Unix timestamp at which an item stored for the given duration expires.
+//
+// Partial seconds are rounded up, so stores keeping whole seconds never
+// expire an item before its TTL has passed.
+func (i *interactsWithTime) expirationTimestamp(ttl time.Duration) int64 {
+	at := i.currentTime().Add(ttl)
+
+	if at.Nanosecond() > 0 {
+		return at.Unix() + 1
+	}
+
+	return at.Unix()
+}
+
+// Get the number of seconds until the given time.
+func (i *interactsWithTime) secondsUntil(at time.Time) int {
+	seconds := int(at.Sub(i.currentTime()).Seconds())
+
+	if seconds < 0 {
+		return 0
+	}
+
+	return seconds
+}
+
+// Resolve the optional TTL handed to an atomic store into a duration.
+//
+// A zero duration means no TTL was given and the item should be stored
+// indefinitely. A TTL that is given but does not resolve to a positive
+// duration is reported as not ok, so the item is not stored at all.
+func (i *interactsWithTime) ttlDuration(ttl ...any) (time.Duration, bool) {
+	if ttl == nil || ttl[0] == nil {
+		return 0, true
+	}
+
+	duration, ok := i.parseDuration(ttl[0], nil)
+
+	return duration, ok && duration > 0
+}
+
+// Parse the given TTL into a duration, reporting whether it is supported.
+//
+// The TTL may be a number of seconds, a time.Duration, a time.Time at which
+// the item expires, a duration string such as "1h30m" or a number of seconds
+// as a string. Callbacks are called with the value being cached and their
+// result is parsed the same way. Other TTLs, such as strings that are not
+// durations, are not supported.
+func (i *interactsWithTime) parseDuration(ttl any, value any) (time.Duration, bool) {
+	switch t := ttl.(type) {
+	case nil:
+		return 0, true
+	case time.Duration:
+		return t, true
+	case time.Time:
+		return t.Sub(i.currentTime()), true
+	case *time.Time:
+		if t == nil {
+			return 0, true
+		}
+
+		return t.Sub(i.currentTime()), true
+	case string:
+		if duration, err := time.ParseDuration(t); err == nil {
+			return duration, true
+		}
+
+		if seconds, err := strconv.ParseFloat(t, 64); err == nil {
+			return floatSeconds(seconds), true
+		}
+
+		return 0, false
+	case float64:
+		return floatSeconds(t), true
+	case float32:
+		return floatSeconds(float64(t)), true
+	case func() any:
+		return i.parseDuration(t(), value)
+	case func(any) any:
+		return i.parseDuration(t(value), value)
+	case func(...any) any:
+		return i.parseDuration(t(value), value)
+	case func() time.Duration:
+		return t(), true
+	case func(any) time.Duration:
+		return t(value), true
+	case func() time.Time:
+		return t().Sub(i.currentTime()), true
+	case func(any) time.Time:
+		return t(value).Sub(i.currentTime()), true
+	}
+
+	switch v := reflect.ValueOf(ttl); v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return time.Duration(v.Int()) * time.Second, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return time.Duration(v.Uint()) * time.Second, true
+	}
+
+	return 0, false
+}
+
+// Convert a fractional number of seconds into a duration.
+func floatSeconds(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// Get the number of whole seconds covering the given duration.
+//
+// Partial seconds are rounded up, so a positive duration never turns into a
+// zero TTL that would remove the item instead of storing it.
+func durationSeconds(duration time.Duration) int {
+	if duration <= 0 {
+		return 0
+	}
+
+	return int(math.Ceil(duration.Seconds()))
+}
@@ -0,0 +1,79 @@
+package cache
+
+// Limit describes how many attempts are allowed within a window of time.
+type Limit struct {
+	// The rate limit signature key.
+	Key string
+	// The maximum number of attempts allowed within the given number of seconds.
+	MaxAttempts int
+	// The number of seconds until the rate limit is reset.
+	DecaySeconds int
+	// Indicates if the limit uses a sliding window instead of a fixed one.
+	Sliding bool
+}
+
+// Create a new limit instance.
+func NewLimit(key string, maxAttempts int, decaySeconds int) Limit {
+	return Limit{Key: key, MaxAttempts: maxAttempts, DecaySeconds: decaySeconds}
+}
+
+// Create a new rate limit using seconds as decay time.
+func PerSecond(maxAttempts int, decaySeconds ...int) Limit {
+	return NewLimit("", maxAttempts, decay(1, decaySeconds))
+}
+
+// Create a new rate limit.
+func PerMinute(maxAttempts int, decayMinutes ...int) Limit {
+	return NewLimit("", maxAttempts, 60*decay(1, decayMinutes))
+}
+
+// Create a new rate limit using minutes as decay time.
+func PerMinutes(decayMinutes int, maxAttempts int) Limit {
+	return NewLimit("", maxAttempts, 60*decayMinutes)
+}
+
+// Create a new rate limit using hours as decay time.
+func PerHour(maxAttempts int, decayHours ...int) Limit {
+	return NewLimit("", maxAttempts, 60*60*decay(1, decayHours))
+}
+
+// Create a new rate limit using days as decay time.
+func PerDay(maxAttempts int, decayDays ...int) Limit {
+	return NewLimit("", maxAttempts, 60*60*24*decay(1, decayDays))
+}
+
+// Create a new unlimited rate limit.
+func Unlimited() Limit {
+	return Limit{}
+}
+
+// Set the key of the rate limit.
+func (l Limit) By(key string) Limit {
+	l.Key = key
+
+	return l
+}
+
+// Count the attempts of the rate limit within a sliding window.
+//
+// A fixed window allows a burst of twice the limit around the moment the
+// window resets, a sliding window smooths such bursts out.
+func (l Limit) SlidingWindow() Limit {
+	l.Sliding = true
+
+	return l
+}
+
+// Determine if the limit is unlimited.
+func (l Limit) IsUnlimited() bool {
+	return l.MaxAttempts <= 0
+}
+
+// Get the given decay or the default one.
+func decay(defaultValue int, value []int) int {
+	if value != nil && value[0] > 0 {
+		return value[0]
+	}
+
+	return defaultValue
+}
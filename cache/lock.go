@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"errors"
+	"time"
+
+	"github.com/garavel-core/framework/support/str"
+)
+
+// The error returned when a lock could not be acquired in time.
+var ErrLockTimeout = errors.New("cache: timed out waiting for the lock")
+
+// The operations every lock driver implements.
+type lockDriver interface {
+	// Attempt to acquire the lock.
+	acquire() bool
+	// Release the lock if it is owned by the current owner.
+	release() bool
+	// Releases this lock in disregard of ownership.
+	forceRelease()
+	// Returns the owner value written into the driver for this lock.
+	getCurrentOwner() string
+}
+
+type Lock struct {
+	// The name of the lock.
+	name string
+	// The number of seconds the lock should be maintained.
+	seconds int
+	// The scope identifier of this lock.
+	owner string
+	// The number of milliseconds to wait before re-attempting to acquire a lock while blocking.
+	sleepMilliseconds int
+	// The driver implementing the lock operations.
+	driver lockDriver
+}
+
+// Create a new lock instance.
+func newLock(name string, seconds int, owner ...string) *Lock {
+	lock := &Lock{name: name, seconds: seconds, sleepMilliseconds: 250}
+
+	if owner != nil && owner[0] != "" {
+		lock.owner = owner[0]
+	} else {
+		lock.owner = str.Random()
+	}
+
+	return lock
+}
+
+// Attempt to acquire the lock.
+func (l *Lock) Get(callback ...func() any) any {
+	result := l.driver.acquire()
+
+	if result && callback != nil && callback[0] != nil {
+		defer l.Release()
+
+		return callback[0]()
+	}
+
+	return result
+}
+
+// Attempt to acquire the lock for the given number of seconds.
+//
+// The wait is measured with the system clock, so a clock set on the store
+// never keeps a caller waiting forever.
+func (l *Lock) Block(seconds int, callback ...func() any) (any, error) {
+	deadline := time.Now().Add(time.Duration(seconds) * time.Second)
+
+	for !l.driver.acquire() {
+		time.Sleep(time.Duration(l.sleepMilliseconds) * time.Millisecond)
+
+		if !time.Now().Before(deadline) {
+			return false, ErrLockTimeout
+		}
+	}
+
+	if callback != nil && callback[0] != nil {
+		defer l.Release()
+
+		return callback[0](), nil
+	}
+
+	return true, nil
+}
+
+// Release the lock.
+func (l *Lock) Release() bool {
+	return l.driver.release()
+}
+
+// Releases this lock in disregard of ownership.
+func (l *Lock) ForceRelease() {
+	l.driver.forceRelease()
+}
+
+// Returns the current owner of the lock.
+func (l *Lock) Owner() string {
+	return l.owner
+}
+
+// Determines whether this lock is allowed to release the lock in the driver.
+func (l *Lock) isOwnedByCurrentProcess() bool {
+	return l.driver.getCurrentOwner() == l.owner
+}
+
+// Specify the number of milliseconds to sleep in between blocked lock acquisition attempts.
+func (l *Lock) BetweenBlockedAttemptsSleepFor(milliseconds int) *Lock {
+	l.sleepMilliseconds = milliseconds
+
+	return l
+}
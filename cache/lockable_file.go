@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"os"
+)
+
+// A file that can be exclusively locked across processes.
+type lockableFile struct {
+	// The file resource.
+	handle *os.File
+}
+
+// Open the file at the given path, creating it if needed, and acquire an exclusive lock on it.
+//
+// The file may be removed by its previous holder while we wait for the lock,
+// in which case the lock we got guards nothing and we start over with the
+// file now found at the path.
+func openLockedFile(path string, permission os.FileMode) (*lockableFile, error) {
+	for {
+		handle, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, permission)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err := lockFile(handle); err != nil {
+			handle.Close()
+
+			return nil, err
+		}
+
+		if isCurrentFile(handle, path) {
+			return &lockableFile{handle: handle}, nil
+		}
+
+		unlockFile(handle)
+		handle.Close()
+	}
+}
+
+// Determine if the opened file is still the one found at the given path.
+func isCurrentFile(handle *os.File, path string) bool {
+	opened, err := handle.Stat()
+
+	if err != nil {
+		return false
+	}
+
+	current, err := os.Stat(path)
+
+	return err == nil && os.SameFile(opened, current)
+}
+
+// Release the lock and close the file.
+func (f *lockableFile) Close() error {
+	unlockFile(f.handle)
+
+	return f.handle.Close()
+}
+
+// Remove the file, then release the lock and close it.
+func (f *lockableFile) Remove() error {
+	return removeLockedFile(f.handle)
+}
@@ -0,0 +1,48 @@
+//go:build !unix
+
+package cache
+
+import (
+	"os"
+	"sync"
+)
+
+// The in-process locks used where advisory file locks are unavailable.
+//
+// These only serialize goroutines of the current process, so sharing a file
+// cache between several processes is not safe on these platforms.
+var fileLocks sync.Map
+
+// Acquire an exclusive lock on the file, blocking until it is available.
+func lockFile(file *os.File) error {
+	mutex, _ := fileLocks.LoadOrStore(file.Name(), &sync.Mutex{})
+
+	mutex.(*sync.Mutex).Lock()
+
+	return nil
+}
+
+// Release the lock held on the file.
+func unlockFile(file *os.File) error {
+	if mutex, ok := fileLocks.Load(file.Name()); ok {
+		mutex.(*sync.Mutex).Unlock()
+	}
+
+	return nil
+}
+
+// Close and remove the locked file, then release the lock.
+//
+// Some of these platforms cannot remove open files, so the file is closed
+// first, while the in-process lock still keeps other goroutines away from it.
+func removeLockedFile(file *os.File) error {
+	err := file.Close()
+
+	if removed := os.Remove(file.Name()); err == nil {
+		err = removed
+	}
+
+	unlockFile(file)
+
+	return err
+}
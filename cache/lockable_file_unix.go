@@ -0,0 +1,40 @@
+//go:build unix
+
+package cache
+
+import (
+	"os"
+	"syscall"
+)
+
+// Acquire an exclusive advisory lock on the file, blocking until it is available.
+func lockFile(file *os.File) error {
+	for {
+		err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
+
+// Release the advisory lock held on the file.
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
+
+// Remove the locked file, then release the lock and close it.
+//
+// The file is removed while the lock is held, so nobody can hold the lock of
+// a file that is no longer at its path without noticing.
+func removeLockedFile(file *os.File) error {
+	removed := os.Remove(file.Name())
+
+	unlockFile(file)
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return removed
+}
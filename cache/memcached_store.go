@@ -0,0 +1,523 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	contracts "github.com/garavel-core/framework/contracts/cache"
+	"github.com/garavel-core/framework/memcached"
+)
+
+const (
+	// The item flag marking a value produced by the cache serializer.
+	memcachedSerializedFlag uint32 = 0
+	// The item flag marking a plain decimal integer usable with incr and decr.
+	memcachedIntegerFlag uint32 = 1
+)
+
+type MemcachedStore struct {
+	interactsWithTime
+	// The Memcached instance.
+	memcached *memcached.Client
+	// A string that should be prepended to keys.
+	prefix string
+	// The serializer of the stored values.
+	serializer contracts.Serializer
+}
+
+// Create a new Memcached store.
+func NewMemcachedStore(client *memcached.Client, prefix ...string) *MemcachedStore {
+	store := &MemcachedStore{memcached: client, serializer: GobSerializer{}}
+
+	if prefix != nil {
+		store.SetPrefix(prefix[0])
+	}
+
+	return store
+}
+
+// Set the clock the store reads the current time from, the system clock when nil.
+func (s *MemcachedStore) SetClock(clock func() time.Time) *MemcachedStore {
+	s.clock = clock
+
+	return s
+}
+
+// Retrieve an item from the cache by key.
+func (s *MemcachedStore) Get(key string) any {
+	value, _, _ := s.GetCtx(context.Background(), key)
+
+	return value
+}
+
+// Retrieve an item from the cache by key, reporting whether it was found.
+func (s *MemcachedStore) GetCtx(ctx context.Context, key string) (any, bool, error) {
+	item, err := s.memcached.GetContext(ctx, s.prefix+key)
+
+	if errors.Is(err, memcached.ErrCacheMiss) {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	value, err := s.unserialize(item)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	return value, true, nil
+}
+
+// Retrieve multiple items from the cache by key.
+//
+// Items not found in the cache will have a null value.
+func (s *MemcachedStore) Many(keys []string) map[string]any {
+	results, err := s.ManyCtx(context.Background(), keys)
+
+	if err != nil {
+		return nullValues(keys)
+	}
+
+	return results
+}
+
+// Retrieve multiple items from the cache by key, missing items have a nil value.
+func (s *MemcachedStore) ManyCtx(ctx context.Context, keys []string) (map[string]any, error) {
+	prefixedKeys := make([]string, len(keys))
+
+	for i, key := range keys {
+		prefixedKeys[i] = s.prefix + key
+	}
+
+	items, err := s.memcached.GetMultiContext(ctx, prefixedKeys)
+
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]any, len(keys))
+
+	for i, key := range keys {
+		results[key] = nil
+
+		if item, exists := items[prefixedKeys[i]]; exists {
+			if results[key], err = s.unserialize(item); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// Store an item in the cache for a given number of seconds.
+func (s *MemcachedStore) Put(key string, value any, seconds int) bool {
+	return s.PutCtx(context.Background(), key, value, seconds) == nil
+}
+
+// Store an item in the cache for a given number of seconds.
+func (s *MemcachedStore) PutCtx(ctx context.Context, key string, value any, seconds int) error {
+	item, err := s.item(key, value, seconds)
+
+	if err != nil {
+		return err
+	}
+
+	return s.memcached.SetContext(ctx, item)
+}
+
+// Store multiple items in the cache for a given number of seconds.
+func (s *MemcachedStore) PutMany(values map[string]any, seconds int) bool {
+	result := true
+
+	for key, value := range values {
+		if !s.Put(key, value, seconds) {
+			result = false
+		}
+	}
+
+	return result
+}
+
+// Store multiple items in the cache for a given number of seconds.
+//
+// The items are stored one by one and the first failure is returned once
+// every item was attempted.
+func (s *MemcachedStore) PutManyCtx(ctx context.Context, values map[string]any, seconds int) error {
+	var result error
+
+	for key, value := range values {
+		if err := s.PutCtx(ctx, key, value, seconds); err != nil && result == nil {
+			result = err
+		}
+	}
+
+	return result
+}
+
+// Store an item in the cache if the key does not exist.
+func (s *MemcachedStore) Add(key string, value any, ttl ...any) bool {
+	duration, ok := s.ttlDuration(ttl...)
+
+	if !ok {
+		return false
+	}
+
+	seconds := 0
+
+	if duration > 0 {
+		seconds = int(math.Ceil(duration.Seconds()))
+	}
+
+	added, err := s.AddCtx(context.Background(), key, value, seconds)
+
+	return err == nil && added
+}
+
+// Store an item in the cache if the key does not exist, reporting whether it was stored.
+func (s *MemcachedStore) AddCtx(ctx context.Context, key string, value any, seconds int) (bool, error) {
+	item, err := s.item(key, value, seconds)
+
+	if err != nil {
+		return false, err
+	}
+
+	err = s.memcached.AddContext(ctx, item)
+
+	if errors.Is(err, memcached.ErrNotStored) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// Increment the value of an item in the cache.
+func (s *MemcachedStore) Increment(key string, value ...any) any {
+	result, err := s.IncrementCtx(context.Background(), key, int64(incrementStep(value...)))
+
+	if err != nil {
+		return false
+	}
+
+	return int(result)
+}
+
+// Increment the value of an item in the cache and return the new value.
+func (s *MemcachedStore) IncrementCtx(ctx context.Context, key string, value int64) (int64, error) {
+	if value == math.MinInt64 {
+		return 0, ErrOverflow
+	}
+
+	if value < 0 {
+		return s.DecrementCtx(ctx, key, -value)
+	}
+
+	result, err := s.memcached.IncrementContext(ctx, s.prefix+key, uint64(value))
+
+	if errors.Is(err, memcached.ErrCacheMiss) {
+		return 0, ErrMissing
+	}
+
+	return int64(result), err
+}
+
+// Decrement the value of an item in the cache.
+func (s *MemcachedStore) Decrement(key string, value ...any) any {
+	result, err := s.DecrementCtx(context.Background(), key, int64(incrementStep(value...)))
+
+	if err != nil {
+		return false
+	}
+
+	return int(result)
+}
+
+// Decrement the value of an item in the cache and return the new value.
+func (s *MemcachedStore) DecrementCtx(ctx context.Context, key string, value int64) (int64, error) {
+	if value == math.MinInt64 {
+		return 0, ErrOverflow
+	}
+
+	if value < 0 {
+		return s.IncrementCtx(ctx, key, -value)
+	}
+
+	result, err := s.memcached.DecrementContext(ctx, s.prefix+key, uint64(value))
+
+	if errors.Is(err, memcached.ErrCacheMiss) {
+		return 0, ErrMissing
+	}
+
+	return int64(result), err
+}
+
+// Add the given step to an integer counter and return the new value.
+//
+// Counters holding a non-negative integer are changed with the server side
+// incr command, which keeps the expiration of the item. Any other change is
+// written back with a compare-and-swap, and since memcached cannot report how
+// long an item has left to live, an item written that way loses its expiration.
+func (s *MemcachedStore) IncrementBy(key string, value int64) (int64, error) {
+	if value >= 0 {
+		if result, done, err := s.countNatively(key, value, false); done {
+			return result, err
+		}
+	}
+
+	return integerResult(s.count(key, incrementBy(value)))
+}
+
+// Subtract the given step from an integer counter, stopping at zero when floor is set.
+//
+// Floored decrements of non-negative counters are left to the server side
+// decr command, which stops at zero by itself. Other decrements are written
+// back with a compare-and-swap, losing the expiration of the item.
+func (s *MemcachedStore) DecrementBy(key string, value int64, floor bool) (int64, error) {
+	if floor && value >= 0 {
+		if result, done, err := s.countNatively(key, value, true); done {
+			return result, err
+		}
+	}
+
+	return integerResult(s.count(key, decrementBy(value, floor)))
+}
+
+// Add the given step to a floating point counter and return the new value.
+//
+// Memcached has no floating point counters, so the item is always written
+// back with a compare-and-swap, losing its expiration.
+func (s *MemcachedStore) IncrementFloat(key string, value float64) (float64, error) {
+	return floatResult(s.count(key, incrementFloatBy(value)))
+}
+
+// Change a counter with the server side incr or decr command.
+//
+// The commands only apply to existing items holding a non-negative integer,
+// so the change is reported as not done for any other item.
+func (s *MemcachedStore) countNatively(key string, value int64, decrement bool) (int64, bool, error) {
+	var result uint64
+	var err error
+
+	if decrement {
+		result, err = s.memcached.Decrement(s.prefix+key, uint64(value))
+	} else {
+		result, err = s.memcached.Increment(s.prefix+key, uint64(value))
+	}
+
+	var serverError memcached.ServerError
+
+	switch {
+	case errors.Is(err, memcached.ErrCacheMiss), errors.As(err, &serverError):
+		return 0, false, nil
+	case err != nil:
+		return 0, true, err
+	case result > math.MaxInt64:
+		// The server counts with unsigned integers, so an increment past the
+		// range of the counter is taken back before the overflow is reported.
+		s.memcached.Decrement(s.prefix+key, uint64(value))
+
+		return 0, true, ErrOverflow
+	}
+
+	return int64(result), true, nil
+}
+
+// Apply a counter change to an item with a compare-and-swap.
+//
+// The change is applied again whenever the item was changed by another
+// client between reading and writing it.
+func (s *MemcachedStore) count(key string, change counterChange) (any, error) {
+	for {
+		item, err := s.memcached.Get(s.prefix + key)
+		exists := !errors.Is(err, memcached.ErrCacheMiss)
+
+		if exists && err != nil {
+			return nil, err
+		}
+
+		var current any
+
+		if exists {
+			if current, err = s.unserialize(item); err != nil {
+				return nil, err
+			}
+		}
+
+		result, err := change(current, exists)
+
+		if err != nil {
+			return nil, err
+		}
+
+		next, err := s.item(key, result, 0)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if exists {
+			next.CasID = item.CasID
+			err = s.memcached.CompareAndSwap(next)
+		} else {
+			err = s.memcached.Add(next)
+		}
+
+		switch {
+		case err == nil:
+			return result, nil
+		case errors.Is(err, memcached.ErrCASConflict), errors.Is(err, memcached.ErrCacheMiss), errors.Is(err, memcached.ErrNotStored):
+			continue
+		}
+
+		return nil, err
+	}
+}
+
+// Store an item in the cache indefinitely.
+func (s *MemcachedStore) Forever(key string, value any) bool {
+	return s.Put(key, value, 0)
+}
+
+// Store an item in the cache indefinitely.
+func (s *MemcachedStore) ForeverCtx(ctx context.Context, key string, value any) error {
+	return s.PutCtx(ctx, key, value, 0)
+}
+
+// Remove an item from the cache.
+func (s *MemcachedStore) Forget(key string) bool {
+	forgotten, err := s.ForgetCtx(context.Background(), key)
+
+	return err == nil && forgotten
+}
+
+// Remove an item from the cache, reporting whether it existed.
+func (s *MemcachedStore) ForgetCtx(ctx context.Context, key string) (bool, error) {
+	err := s.memcached.DeleteContext(ctx, s.prefix+key)
+
+	if errors.Is(err, memcached.ErrCacheMiss) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// Remove all items from the cache.
+func (s *MemcachedStore) Flush() bool {
+	return s.FlushCtx(context.Background()) == nil
+}
+
+// Remove all items from the cache.
+func (s *MemcachedStore) FlushCtx(ctx context.Context) error {
+	return s.memcached.FlushAllContext(ctx)
+}
+
+// Check that the Memcached server is reachable.
+func (s *MemcachedStore) Ping() error {
+	return s.memcached.Ping()
+}
+
+// Get the underlying Memcached connection.
+func (s *MemcachedStore) GetMemcached() *memcached.Client {
+	return s.memcached
+}
+
+// Begin executing a new tags operation.
+func (s *MemcachedStore) Tags(names ...string) contracts.TaggedCache {
+	return NewTaggedCache(s, NewTagSet(s, names))
+}
+
+// Get the serializer of the stored values.
+func (s *MemcachedStore) GetSerializer() contracts.Serializer {
+	return s.serializer
+}
+
+// Set the serializer of the stored values.
+func (s *MemcachedStore) SetSerializer(serializer contracts.Serializer) *MemcachedStore {
+	s.serializer = serializer
+
+	return s
+}
+
+// Get the cache key prefix.
+func (s *MemcachedStore) GetPrefix() string {
+	return s.prefix
+}
+
+// Set the cache key prefix.
+func (s *MemcachedStore) SetPrefix(prefix string) *MemcachedStore {
+	if prefix != "" {
+		prefix += ":"
+	}
+
+	s.prefix = prefix
+
+	return s
+}
+
+// Get the maximum length of the keys the store accepts, leaving room for the prefix.
+func (s *MemcachedStore) MaxKeyLength() int {
+	return memcached.MaxKeyLength - len(s.prefix)
+}
+
+// Build the protocol item for the given value.
+func (s *MemcachedStore) item(key string, value any, seconds int) (*memcached.Item, error) {
+	item := &memcached.Item{Key: s.prefix + key, Expiration: s.calculateExpiration(seconds)}
+
+	// Non-negative integers are stored as plain decimal strings so the server
+	// side incr and decr commands keep working on them.
+	if i, ok := value.(int); ok && i >= 0 {
+		item.Value = strconv.AppendInt(nil, int64(i), 10)
+		item.Flags = memcachedIntegerFlag
+
+		return item, nil
+	}
+
+	data, err := s.serializer.Serialize(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	item.Value = data
+	item.Flags = memcachedSerializedFlag
+
+	return item, nil
+}
+
+// Decode the value of the given protocol item.
+func (s *MemcachedStore) unserialize(item *memcached.Item) (any, error) {
+	// Values touched by incr and decr keep their flags but are always decimal.
+	if i, err := strconv.Atoi(string(item.Value)); err == nil {
+		return i, nil
+	}
+
+	if item.Flags != memcachedSerializedFlag {
+		return nil, fmt.Errorf("cache: unexpected memcached item flags %d", item.Flags)
+	}
+
+	return s.serializer.Unserialize(item.Value)
+}
+
+// Get the expiration time of the key.
+//
+// Memcached reads expirations of up to thirty days as relative seconds and
+// anything larger as a UNIX timestamp, so long lived items are sent with the
+// absolute time they expire at.
+func (s *MemcachedStore) calculateExpiration(seconds int) int64 {
+	if seconds <= 0 {
+		return 0
+	}
+
+	if seconds > memcached.MaxRelativeExpiration {
+		return s.availableAt(seconds).Unix()
+	}
+
+	return int64(seconds)
+}
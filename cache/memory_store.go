@@ -0,0 +1,713 @@
+package cache
+
+import (
+	"container/list"
+	"hash/fnv"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The approximate number of bytes a memory store spends on each item besides
+// its key and value.
+const memoryEntryOverhead = 96
+
+type MemoryStoreOptions struct {
+	// The maximum number of items kept, zero for no limit.
+	MaxEntries int
+	// The approximate maximum number of bytes kept, zero for no limit.
+	MaxBytes int64
+	// The policy choosing the items evicted when the store is full.
+	Policy EvictionPolicy
+	// The number of shards, rounded up to a power of two. Defaults to 16.
+	Shards int
+	// The callback receiving the items evicted or expired, called without any lock held.
+	OnEvict func(key string, value any, reason EvictionReason)
+	// The callback measuring values, defaults to an estimate based on reflection.
+	Sizer func(value any) int64
+}
+
+// A snapshot of the counters of a memory store.
+type MemoryStoreStats struct {
+	// The number of reads that found an item.
+	Hits uint64
+	// The number of reads that found nothing.
+	Misses uint64
+	// The number of items evicted to make room for others.
+	Evictions uint64
+	// The number of items that were not stored, either because the admission
+	// policy preferred the items already held or because they exceed the budget.
+	Rejections uint64
+	// The number of expired items removed.
+	Expirations uint64
+	// The number of items held.
+	Entries int
+	// The approximate number of bytes held.
+	Bytes int64
+}
+
+// An item held by a memory store.
+type memoryEntry struct {
+	// The key of the item.
+	key string
+	// The stored value.
+	value any
+	// The time the item expires, the zero time never expires.
+	expiresAt time.Time
+	// The approximate number of bytes of the item.
+	size int64
+	// The position of the item in an LRU order.
+	element *list.Element
+	// The number of accesses to the item, used by the LFU order.
+	frequency uint64
+	// The logical time of the last access, used by the LFU order.
+	accessed uint64
+	// The position of the item in the LFU heap.
+	index int
+}
+
+// Determine if the item has expired at the given time.
+func (e *memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// An item that left a memory store, reported once the shard lock is released.
+type memoryEviction struct {
+	key    string
+	value  any
+	reason EvictionReason
+}
+
+// A partition of a memory store guarded by its own lock.
+type memoryShard struct {
+	// The items of the shard.
+	entries map[string]*memoryEntry
+	// The order the items are evicted in.
+	order evictionOrder
+	// The access frequencies used for admission, nil unless the policy is TinyLFU.
+	sketch *frequencySketch
+	// The maximum number of items, zero for no limit.
+	maxEntries int
+	// The approximate maximum number of bytes, zero for no limit.
+	maxBytes int64
+	// The approximate number of bytes held.
+	bytes int64
+	// Guards the shard.
+	mutex sync.Mutex
+}
+
+// MemoryStore is an in-process store bounded by a number of items or an
+// approximate byte budget.
+//
+// Items are spread over shards by the hash of their key, each with its own
+// lock, so concurrent callers rarely wait on each other. The limits are
+// divided between the shards and enforced per shard, so a store may evict
+// items slightly before its global limit is reached when keys are not spread
+// evenly.
+type MemoryStore struct {
+	interactsWithTime
+	// The shards of the store.
+	shards []*memoryShard
+	// The number of shards minus one.
+	mask uint64
+	// The policy choosing the items evicted when the store is full.
+	policy EvictionPolicy
+	// The callback receiving the items evicted or expired.
+	onEvict func(key string, value any, reason EvictionReason)
+	// The callback measuring values.
+	sizer func(value any) int64
+
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	evictions   atomic.Uint64
+	rejections  atomic.Uint64
+	expirations atomic.Uint64
+}
+
+// Create a new bounded memory store.
+func NewMemoryStore(options ...MemoryStoreOptions) *MemoryStore {
+	var config MemoryStoreOptions
+
+	if options != nil {
+		config = options[0]
+	}
+
+	if config.Shards <= 0 {
+		config.Shards = 16
+	}
+
+	count := 1
+
+	for count < config.Shards {
+		count <<= 1
+	}
+
+	// Every shard must be able to hold at least one item.
+	for config.MaxEntries > 0 && count > config.MaxEntries {
+		count >>= 1
+	}
+
+	store := &MemoryStore{
+		shards:  make([]*memoryShard, count),
+		mask:    uint64(count - 1),
+		policy:  config.Policy,
+		onEvict: config.OnEvict,
+		sizer:   config.Sizer,
+	}
+
+	if store.sizer == nil {
+		store.sizer = approximateSize
+	}
+
+	for i := range store.shards {
+		shard := &memoryShard{
+			entries:    make(map[string]*memoryEntry),
+			order:      newEvictionOrder(config.Policy),
+			maxEntries: shareOf(config.MaxEntries, count, i),
+			maxBytes:   int64(shareOf(int(config.MaxBytes), count, i)),
+		}
+
+		if config.Policy == TinyLFU {
+			capacity := shard.maxEntries
+
+			if capacity == 0 {
+				capacity = 1024
+			}
+
+			shard.sketch = newFrequencySketch(capacity)
+		}
+
+		store.shards[i] = shard
+	}
+
+	return store
+}
+
+// Set the clock the store reads the current time from, the system clock when nil.
+func (s *MemoryStore) SetClock(clock func() time.Time) *MemoryStore {
+	s.clock = clock
+
+	return s
+}
+
+// Retrieve an item from the cache by key.
+func (s *MemoryStore) Get(key string) any {
+	hash := hashKey(key)
+	shard := s.shard(hash)
+
+	shard.mutex.Lock()
+
+	if shard.sketch != nil {
+		shard.sketch.increment(hash)
+	}
+
+	entry, exists := shard.entries[key]
+
+	if !exists {
+		shard.mutex.Unlock()
+		s.misses.Add(1)
+
+		return nil
+	}
+
+	if entry.expired(s.currentTime()) {
+		shard.remove(entry)
+		shard.mutex.Unlock()
+
+		s.misses.Add(1)
+		s.expirations.Add(1)
+		s.evicted([]memoryEviction{{entry.key, entry.value, EvictedExpired}})
+
+		return nil
+	}
+
+	shard.order.touch(entry)
+	value := entry.value
+	shard.mutex.Unlock()
+
+	s.hits.Add(1)
+
+	return value
+}
+
+// Retrieve multiple items from the cache by key.
+//
+// Items not found in the cache will have a null value.
+func (s *MemoryStore) Many(keys []string) map[string]any {
+	results := make(map[string]any, len(keys))
+
+	for _, key := range keys {
+		results[key] = s.Get(key)
+	}
+
+	return results
+}
+
+// Store an item in the cache for a given number of seconds.
+func (s *MemoryStore) Put(key string, value any, seconds int) bool {
+	return s.put(key, value, s.calculateExpiration(seconds), false)
+}
+
+// Store multiple items in the cache for a given number of seconds.
+func (s *MemoryStore) PutMany(values map[string]any, seconds int) bool {
+	expiresAt := s.calculateExpiration(seconds)
+	result := true
+
+	for key, value := range values {
+		if !s.put(key, value, expiresAt, false) {
+			result = false
+		}
+	}
+
+	return result
+}
+
+// Store an item in the cache for a given duration, keeping sub-second precision.
+func (s *MemoryStore) PutFor(key string, value any, ttl time.Duration) bool {
+	return s.put(key, value, s.currentTime().Add(ttl), false)
+}
+
+// Store multiple items in the cache for a given duration, keeping sub-second precision.
+func (s *MemoryStore) PutManyFor(values map[string]any, ttl time.Duration) bool {
+	expiresAt := s.currentTime().Add(ttl)
+	result := true
+
+	for key, value := range values {
+		if !s.put(key, value, expiresAt, false) {
+			result = false
+		}
+	}
+
+	return result
+}
+
+// Store an item in the cache if the key does not exist.
+func (s *MemoryStore) Add(key string, value any, ttl ...any) bool {
+	duration, ok := s.ttlDuration(ttl...)
+
+	if !ok {
+		return false
+	}
+
+	var expiresAt time.Time
+
+	if duration > 0 {
+		expiresAt = s.currentTime().Add(duration)
+	}
+
+	return s.put(key, value, expiresAt, true)
+}
+
+// Increment the value of an item in the cache.
+func (s *MemoryStore) Increment(key string, value ...any) any {
+	step := incrementStep(value...)
+	hash := hashKey(key)
+	shard := s.shard(hash)
+
+	shard.mutex.Lock()
+
+	result := step
+	expiresAt := time.Time{}
+
+	if entry, exists := shard.entries[key]; exists && !entry.expired(s.currentTime()) {
+		current, _ := toInt(entry.value)
+		result = current + step
+		expiresAt = entry.expiresAt
+	}
+
+	stored, evictions := s.set(shard, hash, key, result, expiresAt, false)
+	shard.mutex.Unlock()
+
+	s.evicted(evictions)
+
+	if !stored {
+		return false
+	}
+
+	return result
+}
+
+// Decrement the value of an item in the cache.
+func (s *MemoryStore) Decrement(key string, value ...any) any {
+	return s.Increment(key, incrementStep(value...)*-1)
+}
+
+// Add the given step to an integer counter and return the new value.
+func (s *MemoryStore) IncrementBy(key string, value int64) (int64, error) {
+	return integerResult(s.count(key, incrementBy(value)))
+}
+
+// Subtract the given step from an integer counter, stopping at zero when floor is set.
+func (s *MemoryStore) DecrementBy(key string, value int64, floor bool) (int64, error) {
+	return integerResult(s.count(key, decrementBy(value, floor)))
+}
+
+// Add the given step to a floating point counter and return the new value.
+func (s *MemoryStore) IncrementFloat(key string, value float64) (float64, error) {
+	return floatResult(s.count(key, incrementFloatBy(value)))
+}
+
+// Apply a counter change to an item while holding the lock of its shard.
+func (s *MemoryStore) count(key string, change counterChange) (any, error) {
+	hash := hashKey(key)
+	shard := s.shard(hash)
+
+	shard.mutex.Lock()
+
+	var current any
+	var expiresAt time.Time
+
+	entry, exists := shard.entries[key]
+
+	if exists && !entry.expired(s.currentTime()) {
+		current, expiresAt = entry.value, entry.expiresAt
+	} else {
+		exists = false
+	}
+
+	result, err := change(current, exists)
+
+	if err != nil {
+		shard.mutex.Unlock()
+
+		return nil, err
+	}
+
+	stored, evictions := s.set(shard, hash, key, result, expiresAt, false)
+	shard.mutex.Unlock()
+
+	s.evicted(evictions)
+
+	if !stored {
+		return nil, ErrStoreFailed
+	}
+
+	return result, nil
+}
+
+// Store an item in the cache indefinitely.
+func (s *MemoryStore) Forever(key string, value any) bool {
+	return s.Put(key, value, 0)
+}
+
+// Remove an item from the cache.
+func (s *MemoryStore) Forget(key string) bool {
+	shard := s.shard(hashKey(key))
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	if entry, exists := shard.entries[key]; exists {
+		shard.remove(entry)
+
+		return true
+	}
+
+	return false
+}
+
+// Remove all items from the cache.
+func (s *MemoryStore) Flush() bool {
+	for _, shard := range s.shards {
+		shard.mutex.Lock()
+		shard.entries = make(map[string]*memoryEntry)
+		shard.order = newEvictionOrder(s.policy)
+		shard.bytes = 0
+		shard.mutex.Unlock()
+	}
+
+	return true
+}
+
+// Get the cache key prefix.
+func (s *MemoryStore) GetPrefix() string {
+	return ""
+}
+
+// Remove every expired item from the cache and return how many were removed.
+func (s *MemoryStore) Prune() int {
+	now := s.currentTime()
+	removed := 0
+
+	for _, shard := range s.shards {
+		var evictions []memoryEviction
+
+		shard.mutex.Lock()
+
+		for _, entry := range shard.entries {
+			if entry.expired(now) {
+				shard.remove(entry)
+				evictions = append(evictions, memoryEviction{entry.key, entry.value, EvictedExpired})
+			}
+		}
+
+		shard.mutex.Unlock()
+
+		s.expirations.Add(uint64(len(evictions)))
+		s.evicted(evictions)
+		removed += len(evictions)
+	}
+
+	return removed
+}
+
+// Get a snapshot of the counters of the store.
+func (s *MemoryStore) Stats() MemoryStoreStats {
+	stats := MemoryStoreStats{
+		Hits:        s.hits.Load(),
+		Misses:      s.misses.Load(),
+		Evictions:   s.evictions.Load(),
+		Rejections:  s.rejections.Load(),
+		Expirations: s.expirations.Load(),
+	}
+
+	for _, shard := range s.shards {
+		shard.mutex.Lock()
+		stats.Entries += len(shard.entries)
+		stats.Bytes += shard.bytes
+		shard.mutex.Unlock()
+	}
+
+	return stats
+}
+
+// Get the policy choosing the items evicted when the store is full.
+func (s *MemoryStore) GetPolicy() EvictionPolicy {
+	return s.policy
+}
+
+// Store the value under the given key.
+func (s *MemoryStore) put(key string, value any, expiresAt time.Time, onlyIfMissing bool) bool {
+	hash := hashKey(key)
+	shard := s.shard(hash)
+
+	shard.mutex.Lock()
+	stored, evictions := s.set(shard, hash, key, value, expiresAt, onlyIfMissing)
+	shard.mutex.Unlock()
+
+	s.evicted(evictions)
+
+	return stored
+}
+
+// Store the value in the locked shard and return the items that had to leave.
+func (s *MemoryStore) set(shard *memoryShard, hash uint64, key string, value any, expiresAt time.Time, onlyIfMissing bool) (bool, []memoryEviction) {
+	var evictions []memoryEviction
+
+	entry, exists := shard.entries[key]
+
+	if exists && entry.expired(s.currentTime()) {
+		shard.remove(entry)
+		s.expirations.Add(1)
+		evictions = append(evictions, memoryEviction{entry.key, entry.value, EvictedExpired})
+		exists = false
+	}
+
+	if exists && onlyIfMissing {
+		return false, evictions
+	}
+
+	if shard.sketch != nil {
+		shard.sketch.increment(hash)
+	}
+
+	size := int64(len(key)) + s.sizer(value) + memoryEntryOverhead
+
+	// An item larger than the whole budget of its shard can never be held, and
+	// an outdated value must not be served in its place.
+	if shard.maxBytes > 0 && size > shard.maxBytes {
+		if exists {
+			shard.remove(entry)
+		}
+
+		s.rejections.Add(1)
+
+		return false, evictions
+	}
+
+	if exists {
+		shard.bytes += size - entry.size
+		entry.value, entry.expiresAt, entry.size = value, expiresAt, size
+		shard.order.touch(entry)
+
+		return true, append(evictions, s.evict(shard, entry)...)
+	}
+
+	// With TinyLFU the item only takes the place of the next victim when it
+	// has been asked for more often than that victim.
+	if shard.sketch != nil && shard.full(1, size) {
+		if victim := shard.order.victim(); victim != nil && shard.sketch.estimate(hash) <= shard.sketch.estimate(hashKey(victim.key)) {
+			s.rejections.Add(1)
+
+			return false, evictions
+		}
+	}
+
+	for shard.full(1, size) {
+		victim := shard.order.victim()
+
+		if victim == nil {
+			break
+		}
+
+		shard.remove(victim)
+		s.evictions.Add(1)
+		evictions = append(evictions, memoryEviction{victim.key, victim.value, EvictedForCapacity})
+	}
+
+	entry = &memoryEntry{key: key, value: value, expiresAt: expiresAt, size: size}
+	shard.entries[key] = entry
+	shard.bytes += size
+	shard.order.add(entry)
+
+	return true, evictions
+}
+
+// Evict items of the locked shard until it fits its limits, keeping the given item.
+func (s *MemoryStore) evict(shard *memoryShard, keep *memoryEntry) []memoryEviction {
+	var evictions []memoryEviction
+
+	for shard.full(0, 0) {
+		victim := shard.order.victim()
+
+		if victim == nil || victim == keep {
+			break
+		}
+
+		shard.remove(victim)
+		s.evictions.Add(1)
+		evictions = append(evictions, memoryEviction{victim.key, victim.value, EvictedForCapacity})
+	}
+
+	return evictions
+}
+
+// Report the items that left the store to the eviction callback.
+func (s *MemoryStore) evicted(evictions []memoryEviction) {
+	if s.onEvict == nil {
+		return
+	}
+
+	for _, eviction := range evictions {
+		s.onEvict(eviction.key, eviction.value, eviction.reason)
+	}
+}
+
+// Get the shard holding the key with the given hash.
+func (s *MemoryStore) shard(hash uint64) *memoryShard {
+	return s.shards[hash&s.mask]
+}
+
+// Get the expiration time of the key.
+func (s *MemoryStore) calculateExpiration(seconds int) time.Time {
+	if seconds > 0 {
+		return s.availableAt(seconds)
+	}
+
+	return time.Time{}
+}
+
+// Determine if the shard would exceed its limits with the given additions.
+func (s *memoryShard) full(entries int, bytes int64) bool {
+	if s.maxEntries > 0 && len(s.entries)+entries > s.maxEntries {
+		return true
+	}
+
+	return s.maxBytes > 0 && s.bytes+bytes > s.maxBytes
+}
+
+// Remove the item from the shard.
+func (s *memoryShard) remove(entry *memoryEntry) {
+	delete(s.entries, entry.key)
+	s.order.remove(entry)
+	s.bytes -= entry.size
+}
+
+// Hash the key to pick its shard and its frequency counters.
+func hashKey(key string) uint64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(key))
+
+	return hash.Sum64()
+}
+
+// Get the share of the given total assigned to one of several parts.
+func shareOf(total int, parts int, part int) int {
+	share := total / parts
+
+	if part < total%parts {
+		share++
+	}
+
+	return share
+}
+
+// Estimate the number of bytes the value occupies.
+//
+// Strings and byte slices count their length, other values are walked to a
+// limited depth and counted by the size of their types. The estimate ignores
+// sharing, so values pointing to the same data are counted once per pointer.
+func approximateSize(value any) int64 {
+	if value == nil {
+		return 0
+	}
+
+	return sizeOf(reflect.ValueOf(value), 0)
+}
+
+// Estimate the number of bytes of the given value.
+func sizeOf(value reflect.Value, depth int) int64 {
+	if depth > 8 {
+		return 8
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return int64(value.Type().Size()) + int64(value.Len())
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return 8
+		}
+
+		return 8 + sizeOf(value.Elem(), depth+1)
+	case reflect.Slice:
+		size := int64(value.Type().Size())
+
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			return size + int64(value.Len())
+		}
+
+		for i := 0; i < value.Len(); i++ {
+			size += sizeOf(value.Index(i), depth+1)
+		}
+
+		return size
+	case reflect.Array:
+		size := int64(0)
+
+		for i := 0; i < value.Len(); i++ {
+			size += sizeOf(value.Index(i), depth+1)
+		}
+
+		return size
+	case reflect.Map:
+		size := int64(48)
+
+		for iterator := value.MapRange(); iterator.Next(); {
+			size += sizeOf(iterator.Key(), depth+1) + sizeOf(iterator.Value(), depth+1)
+		}
+
+		return size
+	case reflect.Struct:
+		size := int64(0)
+
+		for i := 0; i < value.NumField(); i++ {
+			size += sizeOf(value.Field(i), depth+1)
+		}
+
+		return size
+	}
+
+	return int64(value.Type().Size())
+}
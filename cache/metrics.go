@@ -0,0 +1,328 @@
+package cache
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The latency buckets used when none are given, in seconds.
+var DefaultLatencyBuckets = []float64{0.0001, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+
+// The counters of a cache store or key prefix.
+type Counters struct {
+	// The number of reads that found an item.
+	Hits uint64
+	// The number of reads that found nothing.
+	Misses uint64
+	// The number of items written.
+	Writes uint64
+	// The number of items deleted.
+	Deletes uint64
+	// The number of failed operations.
+	Errors uint64
+}
+
+// Get the share of reads that found an item.
+func (c Counters) HitRatio() float64 {
+	if c.Hits+c.Misses == 0 {
+		return 0
+	}
+
+	return float64(c.Hits) / float64(c.Hits+c.Misses)
+}
+
+// Add the given counters to these ones.
+func (c *Counters) add(counters Counters) {
+	c.Hits += counters.Hits
+	c.Misses += counters.Misses
+	c.Writes += counters.Writes
+	c.Deletes += counters.Deletes
+	c.Errors += counters.Errors
+}
+
+// The store and key prefix the counters were recorded for.
+type StatsKey struct {
+	// The name of the cache store.
+	Store string
+	// The prefix of the keys.
+	Prefix string
+}
+
+// The store, key prefix and operation a latency was recorded for.
+type LatencyKey struct {
+	StatsKey
+	// The name of the repository operation.
+	Operation string
+}
+
+// The distribution of the latencies of an operation.
+type Histogram struct {
+	// The upper bounds of the buckets, in seconds.
+	Buckets []float64
+	// The number of observations in each bucket, cumulative.
+	Counts []uint64
+	// The total of the observed latencies, in seconds.
+	Sum float64
+	// The number of observations.
+	Count uint64
+}
+
+// Observe a latency.
+func (h *Histogram) observe(seconds float64) {
+	for i, bound := range h.Buckets {
+		if seconds <= bound {
+			h.Counts[i]++
+		}
+	}
+
+	h.Sum += seconds
+	h.Count++
+}
+
+// Copy the histogram.
+func (h *Histogram) clone() Histogram {
+	return Histogram{
+		Buckets: h.Buckets,
+		Counts:  append([]uint64(nil), h.Counts...),
+		Sum:     h.Sum,
+		Count:   h.Count,
+	}
+}
+
+// A point-in-time snapshot of the cache metrics.
+type Stats struct {
+	// The counters across all stores.
+	Total Counters
+	// The counters of each store.
+	Stores map[string]Counters
+	// The counters of each store and key prefix.
+	Prefixes map[StatsKey]Counters
+	// The latencies of each store, key prefix and operation.
+	Latencies map[LatencyKey]Histogram
+}
+
+// Metrics collects the counters and latencies of instrumented repositories.
+//
+// A single Metrics instance may be shared by the repositories of several
+// stores, so that one handler exports all of them.
+type Metrics struct {
+	// The upper bounds of the latency buckets, in seconds.
+	buckets []float64
+	// The counters of each store and key prefix.
+	counters map[StatsKey]*Counters
+	// The latencies of each store, key prefix and operation.
+	latencies map[LatencyKey]*Histogram
+	// Guards the counters and latencies.
+	mutex sync.Mutex
+}
+
+// Create a new metrics collector with the given latency buckets, in seconds.
+func NewMetrics(buckets ...float64) *Metrics {
+	if buckets == nil {
+		buckets = DefaultLatencyBuckets
+	}
+
+	// The +Inf bucket is always rendered, so it is not kept as a bound.
+	bounds := make([]float64, 0, len(buckets))
+
+	for _, bound := range buckets {
+		if !math.IsInf(bound, 1) {
+			bounds = append(bounds, bound)
+		}
+	}
+
+	sort.Float64s(bounds)
+
+	return &Metrics{
+		buckets:   bounds,
+		counters:  map[StatsKey]*Counters{},
+		latencies: map[LatencyKey]*Histogram{},
+	}
+}
+
+// Record the given counters for a store and key prefix.
+func (m *Metrics) record(key StatsKey, counters Counters) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	current, ok := m.counters[key]
+
+	if !ok {
+		current = &Counters{}
+		m.counters[key] = current
+	}
+
+	current.add(counters)
+}
+
+// Record the latency of an operation.
+func (m *Metrics) observe(key LatencyKey, duration time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	histogram, ok := m.latencies[key]
+
+	if !ok {
+		histogram = &Histogram{Buckets: m.buckets, Counts: make([]uint64, len(m.buckets))}
+		m.latencies[key] = histogram
+	}
+
+	histogram.observe(duration.Seconds())
+}
+
+// Get a snapshot of the collected metrics.
+func (m *Metrics) Stats() Stats {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	stats := Stats{
+		Stores:    map[string]Counters{},
+		Prefixes:  make(map[StatsKey]Counters, len(m.counters)),
+		Latencies: make(map[LatencyKey]Histogram, len(m.latencies)),
+	}
+
+	for key, counters := range m.counters {
+		store := stats.Stores[key.Store]
+		store.add(*counters)
+		stats.Stores[key.Store] = store
+
+		stats.Total.add(*counters)
+		stats.Prefixes[key] = *counters
+	}
+
+	for key, histogram := range m.latencies {
+		stats.Latencies[key] = histogram.clone()
+	}
+
+	return stats
+}
+
+// Clear the collected metrics.
+func (m *Metrics) Reset() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.counters = map[StatsKey]*Counters{}
+	m.latencies = map[LatencyKey]*Histogram{}
+}
+
+// Get an HTTP handler rendering the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+		m.WriteTo(w)
+	})
+}
+
+// Write the metrics in the Prometheus text exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	stats := m.Stats()
+	builder := &strings.Builder{}
+
+	prefixes := make([]StatsKey, 0, len(stats.Prefixes))
+
+	for key := range stats.Prefixes {
+		prefixes = append(prefixes, key)
+	}
+
+	sort.Slice(prefixes, func(i, j int) bool {
+		if prefixes[i].Store != prefixes[j].Store {
+			return prefixes[i].Store < prefixes[j].Store
+		}
+
+		return prefixes[i].Prefix < prefixes[j].Prefix
+	})
+
+	counters := []struct {
+		name  string
+		help  string
+		value func(Counters) uint64
+	}{
+		{"cache_hits_total", "The number of cache reads that found an item.", func(c Counters) uint64 { return c.Hits }},
+		{"cache_misses_total", "The number of cache reads that found nothing.", func(c Counters) uint64 { return c.Misses }},
+		{"cache_writes_total", "The number of cache items written.", func(c Counters) uint64 { return c.Writes }},
+		{"cache_deletes_total", "The number of cache items deleted.", func(c Counters) uint64 { return c.Deletes }},
+		{"cache_errors_total", "The number of failed cache operations.", func(c Counters) uint64 { return c.Errors }},
+	}
+
+	for _, counter := range counters {
+		fmt.Fprintf(builder, "# HELP %s %s\n# TYPE %s counter\n", counter.name, counter.help, counter.name)
+
+		for _, key := range prefixes {
+			fmt.Fprintf(builder, "%s{%s} %d\n", counter.name, labels(key.Store, key.Prefix), counter.value(stats.Prefixes[key]))
+		}
+	}
+
+	latencies := make([]LatencyKey, 0, len(stats.Latencies))
+
+	for key := range stats.Latencies {
+		latencies = append(latencies, key)
+	}
+
+	sort.Slice(latencies, func(i, j int) bool {
+		a, b := latencies[i], latencies[j]
+
+		if a.Store != b.Store {
+			return a.Store < b.Store
+		}
+
+		if a.Prefix != b.Prefix {
+			return a.Prefix < b.Prefix
+		}
+
+		return a.Operation < b.Operation
+	})
+
+	name := "cache_operation_duration_seconds"
+	fmt.Fprintf(builder, "# HELP %s The latency of cache operations.\n# TYPE %s histogram\n", name, name)
+
+	for _, key := range latencies {
+		histogram := stats.Latencies[key]
+		series := labels(key.Store, key.Prefix, key.Operation)
+
+		for i, bound := range histogram.Buckets {
+			fmt.Fprintf(builder, "%s_bucket{%s,le=\"%s\"} %d\n", name, series, formatFloat(bound), histogram.Counts[i])
+		}
+
+		fmt.Fprintf(builder, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, series, histogram.Count)
+		fmt.Fprintf(builder, "%s_sum{%s} %s\n", name, series, formatFloat(histogram.Sum))
+		fmt.Fprintf(builder, "%s_count{%s} %d\n", name, series, histogram.Count)
+	}
+
+	written, err := io.WriteString(w, builder.String())
+
+	return int64(written), err
+}
+
+// Render the store, prefix and optional operation labels of a series.
+func labels(store string, prefix string, operation ...string) string {
+	result := `store="` + escapeLabel(store) + `",prefix="` + escapeLabel(prefix) + `"`
+
+	if operation != nil {
+		result += `,operation="` + escapeLabel(operation[0]) + `"`
+	}
+
+	return result
+}
+
+// Escape a label value for the Prometheus text format.
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// Format a sample value for the Prometheus text format.
+func formatFloat(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
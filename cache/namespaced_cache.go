@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+
+	contracts "github.com/garavel-core/framework/contracts/cache"
+	"github.com/garavel-core/framework/support/str"
+)
+
+type NamespacedCache struct {
+	*Repository
+	// The name of the namespace.
+	name string
+	// The callback that resolves keys of the enclosing repository.
+	parent func(key string) string
+}
+
+// Create a new namespaced cache instance.
+func NewNamespacedCache(store contracts.Store, name string) *NamespacedCache {
+	cache := &NamespacedCache{Repository: NewRepository(store), name: name}
+
+	cache.keyResolver = cache.namespacedItemKey
+
+	return cache
+}
+
+// Remove all items of the namespace from the cache.
+//
+// The items are not deleted from the backend: the namespace moves to a new
+// version, so its old keys are no longer reachable and expire on their own.
+func (c *NamespacedCache) Flush() bool {
+	c.ResetVersion()
+
+	return true
+}
+
+// Wipes clean the entire cache's keys.
+func (c *NamespacedCache) Clear() bool {
+	return c.Flush()
+}
+
+// Wipes clean the entire cache's keys.
+func (c *NamespacedCache) ClearCtx(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.Flush()
+
+	return nil
+}
+
+// Get the current version of the namespace.
+func (c *NamespacedCache) Version() string {
+	if version := c.store.Get(c.VersionKey()); version != nil {
+		return fmt.Sprint(version)
+	}
+
+	// Concurrent first uses of the namespace agree on a single version when
+	// the store can add atomically.
+	if store, ok := c.store.(contracts.AtomicStore); ok {
+		version := str.Random(20)
+
+		if store.Add(c.VersionKey(), version) {
+			return version
+		}
+
+		if version := c.store.Get(c.VersionKey()); version != nil {
+			return fmt.Sprint(version)
+		}
+	}
+
+	return c.ResetVersion()
+}
+
+// Move the namespace to a new version and return it.
+func (c *NamespacedCache) ResetVersion() string {
+	version := str.Random(20)
+
+	c.store.Forever(c.VersionKey(), version)
+
+	return version
+}
+
+// Get the key the version of the namespace is stored under.
+func (c *NamespacedCache) VersionKey() string {
+	key := "namespace:" + c.name + ":version"
+
+	if c.parent != nil {
+		return c.parent(key)
+	}
+
+	return key
+}
+
+// Get the name of the namespace.
+func (c *NamespacedCache) GetNamespace() string {
+	return c.name
+}
+
+// Get a fully qualified key for an item of the namespace.
+func (c *NamespacedCache) namespacedItemKey(key string) string {
+	key = c.name + ":" + c.Version() + ":" + key
+
+	if c.parent != nil {
+		return c.parent(key)
+	}
+
+	return key
+}
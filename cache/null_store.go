@@ -0,0 +1,66 @@
+package cache
+
+type NullStore struct{}
+
+// Create a new null store.
+func NewNullStore() *NullStore {
+	return &NullStore{}
+}
+
+// Retrieve an item from the cache by key.
+func (s *NullStore) Get(key string) any {
+	return nil
+}
+
+// Retrieve multiple items from the cache by key.
+//
+// Items not found in the cache will have a null value.
+func (s *NullStore) Many(keys []string) map[string]any {
+	results := make(map[string]any, len(keys))
+
+	for _, key := range keys {
+		results[key] = nil
+	}
+
+	return results
+}
+
+// Store an item in the cache for a given number of seconds.
+func (s *NullStore) Put(key string, value any, seconds int) bool {
+	return false
+}
+
+// Store multiple items in the cache for a given number of seconds.
+func (s *NullStore) PutMany(values map[string]any, seconds int) bool {
+	return false
+}
+
+// Increment the value of an item in the cache.
+func (s *NullStore) Increment(key string, value ...any) any {
+	return false
+}
+
+// Decrement the value of an item in the cache.
+func (s *NullStore) Decrement(key string, value ...any) any {
+	return false
+}
+
+// Store an item in the cache indefinitely.
+func (s *NullStore) Forever(key string, value any) bool {
+	return false
+}
+
+// Remove an item from the cache.
+func (s *NullStore) Forget(key string) bool {
+	return true
+}
+
+// Remove all items from the cache.
+func (s *NullStore) Flush() bool {
+	return true
+}
+
+// Get the cache key prefix.
+func (s *NullStore) GetPrefix() string {
+	return ""
+}
@@ -0,0 +1,438 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// A callback resolving the limits of a named limiter from its arguments.
+type LimiterCallback func(args ...any) []Limit
+
+type RateLimiter struct {
+	// The cache store implementation.
+	cache *Repository
+	// The configured limit object resolvers.
+	limiters map[string]LimiterCallback
+	// Indicates if attempts are counted within sliding windows.
+	sliding bool
+	// Guards the configured limiters.
+	mutex *sync.RWMutex
+}
+
+// Create a new rate limiter instance.
+func NewRateLimiter(cache *Repository) *RateLimiter {
+	return &RateLimiter{cache: cache, limiters: make(map[string]LimiterCallback), mutex: &sync.RWMutex{}}
+}
+
+// Get a rate limiter sharing the configured limiters that counts attempts within sliding windows.
+//
+// The sliding window is estimated from the hits of the current fixed window
+// and those of the previous one, weighted by how much of the previous window
+// still overlaps the sliding window.
+func (l *RateLimiter) SlidingWindow() *RateLimiter {
+	sliding := *l
+	sliding.sliding = true
+
+	return &sliding
+}
+
+// Register a named limiter configuration.
+func (l *RateLimiter) For(name string, callback LimiterCallback) *RateLimiter {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.limiters[name] = callback
+
+	return l
+}
+
+// Get the given named rate limiter.
+func (l *RateLimiter) Limiter(name string) LimiterCallback {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	return l.limiters[name]
+}
+
+// Attempts to execute a callback if it's not limited.
+//
+// The result of the callback is returned, or false when the key has been
+// accessed too many times. The hit is recorded before the limit is checked,
+// so concurrent attempts see distinct counts and cannot pass the limit
+// together, and a hit over the limit is taken back.
+func (l *RateLimiter) Attempt(key string, maxAttempts int, callback func() any, decaySeconds ...int) any {
+	if _, ok := l.attempt(key, maxAttempts, decay(60, decaySeconds)); !ok {
+		return false
+	}
+
+	if result := callback(); result != nil {
+		return result
+	}
+
+	return true
+}
+
+// Attempts to execute a callback within the limits of the named limiter.
+//
+// The arguments are handed to the limiter to resolve its limits. Each limit
+// is counted under the name of the limiter, the key of the limit and its
+// window, so limits sharing a key are counted separately. The result of the
+// callback is returned, or false when any of the limits has been exceeded,
+// in which case the hits of the other limits are taken back.
+func (l *RateLimiter) AttemptFor(name string, callback func() any, args ...any) (any, error) {
+	resolve := l.Limiter(name)
+
+	if resolve == nil {
+		return false, fmt.Errorf("cache: rate limiter [%s] is not defined", name)
+	}
+
+	var counters []string
+
+	for _, limit := range resolve(args...) {
+		if limit.IsUnlimited() {
+			continue
+		}
+
+		counter, ok := l.forLimit(limit).attempt(limitKey(name, limit), limit.MaxAttempts, decay(60, []int{limit.DecaySeconds}))
+
+		if !ok {
+			for _, counter := range counters {
+				l.takeBack(counter)
+			}
+
+			return false, nil
+		}
+
+		counters = append(counters, counter)
+	}
+
+	if result := callback(); result != nil {
+		return result, nil
+	}
+
+	return true, nil
+}
+
+// Determine if the given key has been "accessed" too many times.
+func (l *RateLimiter) TooManyAttempts(key string, maxAttempts int) bool {
+	if l.sliding {
+		return l.Attempts(key) >= maxAttempts
+	}
+
+	if l.Attempts(key) >= maxAttempts {
+		if l.cache.Has(key + ":timer") {
+			return true
+		}
+
+		l.ResetAttempts(key)
+	}
+
+	return false
+}
+
+// Increment the counter for a given key for a given decay time.
+func (l *RateLimiter) Hit(key string, decaySeconds ...int) int {
+	return l.Increment(key, decay(60, decaySeconds), 1)
+}
+
+// Increment the counter for a given key for a given decay time by a given amount.
+func (l *RateLimiter) Increment(key string, decaySeconds int, amount int) int {
+	_, hits := l.increment(key, decaySeconds, amount)
+
+	return hits
+}
+
+// Hit the given key unless the hit exceeds the maximum number of attempts.
+//
+// The counter that was hit is returned, so the hit can be taken back. A hit
+// over the limit is taken back right away; the counter does not go below zero
+// should it have expired in between, so it is started over by the next hit.
+func (l *RateLimiter) attempt(key string, maxAttempts int, decaySeconds int) (string, bool) {
+	counter, hits := l.increment(key, decaySeconds, 1)
+
+	if hits > maxAttempts {
+		l.takeBack(counter)
+
+		return counter, false
+	}
+
+	return counter, true
+}
+
+// Take back a hit of the given counter without going below zero.
+//
+// Stores without counter support fall back to their plain decrement.
+func (l *RateLimiter) takeBack(counter string) {
+	if _, err := l.cache.DecrementBy(counter, 1, true); errors.Is(err, ErrCountersNotSupported) {
+		l.cache.Decrement(counter)
+	}
+}
+
+// Increment the counter for a given key and return the counter along with the attempts.
+func (l *RateLimiter) increment(key string, decaySeconds int, amount int) (string, int) {
+	if l.sliding {
+		return l.incrementSliding(key, decaySeconds, amount)
+	}
+
+	l.cache.Add(key+":timer", int(l.cache.availableAt(decaySeconds).Unix()), decaySeconds)
+
+	added := l.cache.Add(key, 0, decaySeconds)
+
+	hits, _ := toInt(l.cache.Increment(key, amount))
+
+	// If the counter expired between the add and the increment, the store has
+	// started a new counter that never expires, so it is put again with the
+	// decay time to make sure it is reset eventually.
+	if !added && hits == amount {
+		l.cache.Put(key, amount, decaySeconds)
+	}
+
+	return key, hits
+}
+
+// Increment the counter of the current sliding window.
+//
+// Windows last at least a second. The length of the window is only recorded
+// by the first hit of each window, so a hit takes three round trips to the
+// store: adding and incrementing the counter, and reading the previous one.
+func (l *RateLimiter) incrementSliding(key string, decaySeconds int, amount int) (string, int) {
+	if decaySeconds < 1 {
+		decaySeconds = 1
+	}
+
+	window := &slidingWindow{limiter: l, key: key, decaySeconds: decaySeconds, now: l.cache.currentTime()}
+	current := window.currentKey()
+
+	// The counter of a window is read again while the next window is current,
+	// so it is kept for two windows.
+	added := l.cache.Add(current, 0, 2*decaySeconds)
+
+	hits, _ := toInt(l.cache.Increment(current, amount))
+
+	if hits == amount {
+		if !added {
+			l.cache.Put(current, amount, 2*decaySeconds)
+		}
+
+		window.record()
+	}
+
+	previous, _ := toInt(l.cache.Get(window.previousKey()))
+
+	return current, window.estimate(hits, previous)
+}
+
+// Decrement the counter for a given key for a given decay time by a given amount.
+func (l *RateLimiter) Decrement(key string, decaySeconds int, amount int) int {
+	return l.Increment(key, decaySeconds, amount*-1)
+}
+
+// Get the number of attempts for the given key.
+func (l *RateLimiter) Attempts(key string) int {
+	if l.sliding {
+		return l.window(key).attempts()
+	}
+
+	attempts, _ := toInt(l.cache.Get(key, 0))
+
+	return attempts
+}
+
+// Reset the number of attempts for the given key.
+func (l *RateLimiter) ResetAttempts(key string) bool {
+	if l.sliding {
+		window := l.window(key)
+
+		if window.decaySeconds <= 0 {
+			return false
+		}
+
+		l.cache.Forget(window.previousKey())
+
+		return l.cache.Forget(window.currentKey())
+	}
+
+	return l.cache.Forget(key)
+}
+
+// Get the number of retries left for the given key.
+func (l *RateLimiter) Remaining(key string, maxAttempts int) int {
+	remaining := maxAttempts - l.Attempts(key)
+
+	if remaining < 0 {
+		return 0
+	}
+
+	return remaining
+}
+
+// Get the number of retries left for the given key.
+func (l *RateLimiter) RetriesLeft(key string, maxAttempts int) int {
+	return l.Remaining(key, maxAttempts)
+}
+
+// Clear the hits and lockout timer for the given key.
+func (l *RateLimiter) Clear(key string) {
+	l.ResetAttempts(key)
+
+	l.cache.Forget(key + ":timer")
+	l.cache.Forget(key + ":window")
+}
+
+// Get the number of seconds until the "key" is accessible again.
+//
+// Sliding windows need the maximum number of attempts to tell when enough
+// hits have slid out of the window. Without it the time until every recorded
+// hit has left the window is returned.
+func (l *RateLimiter) AvailableIn(key string, maxAttempts ...int) int {
+	if l.sliding {
+		return l.window(key).availableIn(maxAttempts)
+	}
+
+	timer, _ := toInt(l.cache.Get(key + ":timer"))
+
+	if seconds := int64(timer) - l.cache.currentTime().Unix(); seconds > 0 {
+		return int(seconds)
+	}
+
+	return 0
+}
+
+// Get the rate limiter counting the attempts of the given limit.
+func (l *RateLimiter) forLimit(limit Limit) *RateLimiter {
+	if limit.Sliding && !l.sliding {
+		return l.SlidingWindow()
+	}
+
+	return l
+}
+
+// Get the sliding window of the given key at the current time.
+func (l *RateLimiter) window(key string) *slidingWindow {
+	decaySeconds, _ := toInt(l.cache.Get(key + ":window"))
+
+	return &slidingWindow{limiter: l, key: key, decaySeconds: decaySeconds, now: l.cache.currentTime()}
+}
+
+// Get the key the attempts of the given limit of a named limiter are counted under.
+//
+// Limits of one limiter may share their key, a per-minute and a per-hour limit
+// of the same user for instance, so the window and the number of attempts are
+// part of the key to keep their counters apart.
+func limitKey(name string, limit Limit) string {
+	window := "fixed"
+
+	if limit.Sliding {
+		window = "sliding"
+	}
+
+	return fmt.Sprintf("%s:%s:%s:%d:%d", name, limit.Key, window, limit.MaxAttempts, limit.DecaySeconds)
+}
+
+// The sliding window of a key at a point in time.
+type slidingWindow struct {
+	// The rate limiter holding the counters.
+	limiter *RateLimiter
+	// The rate limited key.
+	key string
+	// The length of the window in seconds, zero when nothing was recorded.
+	decaySeconds int
+	// The point in time.
+	now time.Time
+}
+
+// Record the length of the window, so it is known when reading the counters.
+func (w *slidingWindow) record() {
+	w.limiter.cache.Put(w.key+":window", w.decaySeconds, 2*w.decaySeconds)
+}
+
+// Get the number of the fixed window holding the current time.
+func (w *slidingWindow) index() int64 {
+	return w.now.Unix() / int64(w.decaySeconds)
+}
+
+// Get the key counting the hits of the current fixed window.
+func (w *slidingWindow) currentKey() string {
+	return fmt.Sprintf("%s:%d", w.key, w.index())
+}
+
+// Get the key counting the hits of the previous fixed window.
+func (w *slidingWindow) previousKey() string {
+	return fmt.Sprintf("%s:%d", w.key, w.index()-1)
+}
+
+// Get the share of the current fixed window that has passed.
+func (w *slidingWindow) elapsed() float64 {
+	start := time.Unix(w.index()*int64(w.decaySeconds), 0)
+
+	return w.now.Sub(start).Seconds() / float64(w.decaySeconds)
+}
+
+// Get the hits of the current and the previous fixed window.
+func (w *slidingWindow) hits() (current int, previous int) {
+	values := w.limiter.cache.Many([]string{w.currentKey(), w.previousKey()})
+
+	current, _ = toInt(values[w.currentKey()])
+	previous, _ = toInt(values[w.previousKey()])
+
+	return current, previous
+}
+
+// Get the estimated number of hits within the sliding window.
+func (w *slidingWindow) attempts() int {
+	if w.decaySeconds <= 0 {
+		return 0
+	}
+
+	return w.estimate(w.hits())
+}
+
+// Estimate the hits within the sliding window from the hits of the fixed windows.
+func (w *slidingWindow) estimate(current int, previous int) int {
+	return current + int(float64(previous)*(1-w.elapsed()))
+}
+
+// Get the number of seconds until the estimated hits drop below the maximum.
+func (w *slidingWindow) availableIn(maxAttempts []int) int {
+	if w.decaySeconds <= 0 {
+		return 0
+	}
+
+	current, previous := w.hits()
+	elapsed := w.elapsed()
+	window := float64(w.decaySeconds)
+
+	if maxAttempts == nil {
+		if current > 0 {
+			return int(math.Ceil((2 - elapsed) * window))
+		}
+
+		if previous > 0 {
+			return int(math.Ceil((1 - elapsed) * window))
+		}
+
+		return 0
+	}
+
+	limit := float64(maxAttempts[0])
+
+	if float64(current)+float64(previous)*(1-elapsed) < limit {
+		return 0
+	}
+
+	// Within the current window the estimate only drops as the previous window
+	// slides out, which is enough when the current hits alone are below the
+	// maximum. Otherwise the current hits must slide out during the next window.
+	if float64(current) < limit && previous > 0 {
+		until := 1 - (limit-float64(current))/float64(previous)
+
+		return int(math.Ceil((until - elapsed) * window))
+	}
+
+	until := 1 - limit/float64(current)
+
+	return int(math.Ceil((1 - elapsed + until) * window))
+}
@@ -0,0 +1,507 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	contracts "github.com/garavel-core/framework/contracts/cache"
+	"github.com/garavel-core/framework/redis"
+)
+
+// The script subtracting ARGV[1] from the counter KEYS[1] without going below zero.
+//
+// The decrement is taken back when it went below zero, and a counter that was
+// positive is then brought down to exactly zero. The arithmetic is left to
+// DECRBY and INCRBY, as Lua numbers cannot hold every int64.
+const flooredDecrementScript = `-- cache:floored-decrement
+local value = redis.call('DECRBY', KEYS[1], ARGV[1])
+if value >= 0 then
+  return value
+end
+local current = redis.call('INCRBY', KEYS[1], ARGV[1])
+if current <= 0 then
+  return current
+end
+return redis.call('DECRBY', KEYS[1], redis.call('GET', KEYS[1]))`
+
+// The script adding ARGV[1] to the counter KEYS[1] only when it exists.
+const incrementExistingScript = `-- cache:increment-existing
+if redis.call('EXISTS', KEYS[1]) == 0 then
+  return false
+end
+return redis.call('INCRBY', KEYS[1], ARGV[1])`
+
+type RedisStore struct {
+	interactsWithTime
+	// The Redis connection instance.
+	connection *redis.Connection
+	// A string that should be prepended to keys.
+	prefix string
+	// The serializer of the stored values.
+	serializer contracts.Serializer
+}
+
+// Create a new Redis store.
+func NewRedisStore(connection *redis.Connection, prefix ...string) *RedisStore {
+	store := &RedisStore{connection: connection, serializer: GobSerializer{}}
+
+	if prefix != nil {
+		store.SetPrefix(prefix[0])
+	}
+
+	return store
+}
+
+// Set the clock the store reads the current time from, the system clock when nil.
+func (s *RedisStore) SetClock(clock func() time.Time) *RedisStore {
+	s.clock = clock
+
+	return s
+}
+
+// Retrieve an item from the cache by key.
+func (s *RedisStore) Get(key string) any {
+	value, _, _ := s.GetCtx(context.Background(), key)
+
+	return value
+}
+
+// Retrieve an item from the cache by key, reporting whether it was found.
+func (s *RedisStore) GetCtx(ctx context.Context, key string) (any, bool, error) {
+	reply, err := s.connection.CommandContext(ctx, "GET", s.prefix+key)
+
+	if err != nil || reply == nil {
+		return nil, false, err
+	}
+
+	value, err := s.unserialize(reply)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	return value, true, nil
+}
+
+// Retrieve multiple items from the cache by key.
+//
+// Items not found in the cache will have a null value.
+func (s *RedisStore) Many(keys []string) map[string]any {
+	results, err := s.ManyCtx(context.Background(), keys)
+
+	if err != nil {
+		return nullValues(keys)
+	}
+
+	return results
+}
+
+// Retrieve multiple items from the cache by key, missing items have a nil value.
+func (s *RedisStore) ManyCtx(ctx context.Context, keys []string) (map[string]any, error) {
+	results := make(map[string]any, len(keys))
+
+	if len(keys) == 0 {
+		return results, nil
+	}
+
+	args := make([]any, len(keys)+1)
+	args[0] = "MGET"
+
+	for i, key := range keys {
+		args[i+1] = s.prefix + key
+	}
+
+	reply, err := s.connection.CommandContext(ctx, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	values, _ := reply.([]any)
+
+	for i, key := range keys {
+		results[key] = nil
+
+		if i >= len(values) || values[i] == nil {
+			continue
+		}
+
+		if results[key], err = s.unserialize(values[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+// Store an item in the cache for a given number of seconds.
+func (s *RedisStore) Put(key string, value any, seconds int) bool {
+	return s.PutCtx(context.Background(), key, value, seconds) == nil
+}
+
+// Store an item in the cache for a given number of seconds.
+func (s *RedisStore) PutCtx(ctx context.Context, key string, value any, seconds int) error {
+	return s.PutManyCtx(ctx, map[string]any{key: value}, seconds)
+}
+
+// Store multiple items in the cache for a given number of seconds.
+func (s *RedisStore) PutMany(values map[string]any, seconds int) bool {
+	return s.PutManyCtx(context.Background(), values, seconds) == nil
+}
+
+// Store multiple items in the cache for a given number of seconds.
+func (s *RedisStore) PutManyCtx(ctx context.Context, values map[string]any, seconds int) error {
+	return s.putMany(ctx, values, "SETEX", atLeastOne(seconds))
+}
+
+// Store an item in the cache for a given duration, keeping sub-second precision.
+func (s *RedisStore) PutFor(key string, value any, ttl time.Duration) bool {
+	return s.PutManyFor(map[string]any{key: value}, ttl)
+}
+
+// Store multiple items in the cache for a given duration, keeping sub-second precision.
+func (s *RedisStore) PutManyFor(values map[string]any, ttl time.Duration) bool {
+	return s.putMany(context.Background(), values, "PSETEX", atLeastOne(int(ttl.Milliseconds()))) == nil
+}
+
+// Store multiple items in a single round trip with the given expiring set command.
+func (s *RedisStore) putMany(ctx context.Context, values map[string]any, command string, ttl int) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	commands := make([][]any, 0, len(values))
+
+	for key, value := range values {
+		data, err := s.serialize(value)
+
+		if err != nil {
+			return err
+		}
+
+		commands = append(commands, []any{command, s.prefix + key, ttl, data})
+	}
+
+	replies, err := s.connection.PipelineContext(ctx, commands...)
+
+	if err != nil {
+		return err
+	}
+
+	for _, reply := range replies {
+		if err, ok := reply.(redis.Error); ok {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Store an item in the cache if the key does not exist.
+func (s *RedisStore) Add(key string, value any, ttl ...any) bool {
+	duration, ok := s.ttlDuration(ttl...)
+
+	if !ok {
+		return false
+	}
+
+	added, err := s.add(context.Background(), key, value, duration)
+
+	return err == nil && added
+}
+
+// Store an item in the cache if the key does not exist, reporting whether it was stored.
+func (s *RedisStore) AddCtx(ctx context.Context, key string, value any, seconds int) (bool, error) {
+	return s.add(ctx, key, value, time.Duration(seconds)*time.Second)
+}
+
+// Store an item if the key does not exist, keeping it forever when the TTL is not positive.
+func (s *RedisStore) add(ctx context.Context, key string, value any, ttl time.Duration) (bool, error) {
+	data, err := s.serialize(value)
+
+	if err != nil {
+		return false, err
+	}
+
+	args := []any{"SET", s.prefix + key, data}
+
+	if ttl > 0 {
+		args = append(args, "PX", atLeastOne(int(ttl.Milliseconds())))
+	}
+
+	reply, err := s.connection.CommandContext(ctx, append(args, "NX")...)
+
+	return err == nil && reply == "OK", err
+}
+
+// Increment the value of an item in the cache.
+func (s *RedisStore) Increment(key string, value ...any) any {
+	result, err := s.IncrementBy(key, int64(incrementStep(value...)))
+
+	if err != nil {
+		return false
+	}
+
+	return int(result)
+}
+
+// Increment the value of an item in the cache and return the new value.
+//
+// INCRBY would create a missing item, so the existence check and the increment
+// run together as a script.
+func (s *RedisStore) IncrementCtx(ctx context.Context, key string, value int64) (int64, error) {
+	reply, err := s.connection.CommandContext(ctx, "EVAL", incrementExistingScript, 1, s.prefix+key, value)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if reply == nil {
+		return 0, ErrMissing
+	}
+
+	return reply.(int64), nil
+}
+
+// Decrement the value of an item in the cache.
+func (s *RedisStore) Decrement(key string, value ...any) any {
+	result, err := s.DecrementBy(key, int64(incrementStep(value...)), false)
+
+	if err != nil {
+		return false
+	}
+
+	return int(result)
+}
+
+// Decrement the value of an item in the cache and return the new value.
+func (s *RedisStore) DecrementCtx(ctx context.Context, key string, value int64) (int64, error) {
+	if value == math.MinInt64 {
+		return 0, ErrOverflow
+	}
+
+	return s.IncrementCtx(ctx, key, -value)
+}
+
+// Add the given step to an integer counter and return the new value.
+func (s *RedisStore) IncrementBy(key string, value int64) (int64, error) {
+	reply, err := s.connection.Command("INCRBY", s.prefix+key, value)
+
+	if err != nil {
+		return 0, counterError(err)
+	}
+
+	return reply.(int64), nil
+}
+
+// Subtract the given step from an integer counter, stopping at zero when floor is set.
+//
+// Redis has no floored decrement, so it runs as a script, which Redis runs
+// atomically: nobody observes the counter below zero in between.
+func (s *RedisStore) DecrementBy(key string, value int64, floor bool) (int64, error) {
+	if value == math.MinInt64 {
+		return 0, ErrOverflow
+	}
+
+	if !floor || value <= 0 {
+		return s.IncrementBy(key, -value)
+	}
+
+	reply, err := s.connection.Command("EVAL", flooredDecrementScript, 1, s.prefix+key, value)
+
+	if err != nil {
+		return 0, counterError(err)
+	}
+
+	return reply.(int64), nil
+}
+
+// Add the given step to a floating point counter and return the new value.
+func (s *RedisStore) IncrementFloat(key string, value float64) (float64, error) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, ErrOverflow
+	}
+
+	reply, err := s.connection.Command("INCRBYFLOAT", s.prefix+key, value)
+
+	if err != nil {
+		return 0, counterError(err)
+	}
+
+	data, _ := reply.([]byte)
+
+	return strconv.ParseFloat(string(data), 64)
+}
+
+// Store an item in the cache indefinitely.
+func (s *RedisStore) Forever(key string, value any) bool {
+	return s.ForeverCtx(context.Background(), key, value) == nil
+}
+
+// Store an item in the cache indefinitely.
+func (s *RedisStore) ForeverCtx(ctx context.Context, key string, value any) error {
+	data, err := s.serialize(value)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = s.connection.CommandContext(ctx, "SET", s.prefix+key, data)
+
+	return err
+}
+
+// Remove an item from the cache.
+func (s *RedisStore) Forget(key string) bool {
+	forgotten, err := s.ForgetCtx(context.Background(), key)
+
+	return err == nil && forgotten
+}
+
+// Remove an item from the cache, reporting whether it existed.
+func (s *RedisStore) ForgetCtx(ctx context.Context, key string) (bool, error) {
+	reply, err := s.connection.CommandContext(ctx, "DEL", s.prefix+key)
+
+	if err != nil {
+		return false, err
+	}
+
+	return reply.(int64) > 0, nil
+}
+
+// Remove all items from the cache.
+func (s *RedisStore) Flush() bool {
+	return s.FlushCtx(context.Background()) == nil
+}
+
+// Remove all items from the cache.
+func (s *RedisStore) FlushCtx(ctx context.Context) error {
+	_, err := s.connection.CommandContext(ctx, "FLUSHDB")
+
+	return err
+}
+
+// Check that the Redis server is reachable.
+func (s *RedisStore) Ping() error {
+	_, err := s.connection.Command("PING")
+
+	return err
+}
+
+// Get the Redis connection instance.
+func (s *RedisStore) Connection() *redis.Connection {
+	return s.connection
+}
+
+// Set the connection to be used.
+func (s *RedisStore) SetConnection(connection *redis.Connection) *RedisStore {
+	s.connection = connection
+
+	return s
+}
+
+// Begin executing a new tags operation.
+func (s *RedisStore) Tags(names ...string) contracts.TaggedCache {
+	return NewTaggedCache(s, NewTagSet(s, names))
+}
+
+// Get the serializer of the stored values.
+func (s *RedisStore) GetSerializer() contracts.Serializer {
+	return s.serializer
+}
+
+// Set the serializer of the stored values.
+func (s *RedisStore) SetSerializer(serializer contracts.Serializer) *RedisStore {
+	s.serializer = serializer
+
+	return s
+}
+
+// Get the cache key prefix.
+func (s *RedisStore) GetPrefix() string {
+	return s.prefix
+}
+
+// Set the cache key prefix.
+func (s *RedisStore) SetPrefix(prefix string) *RedisStore {
+	if prefix != "" {
+		prefix += ":"
+	}
+
+	s.prefix = prefix
+
+	return s
+}
+
+// Serialize the value.
+//
+// Numbers are stored as plain decimal strings so INCRBY, DECRBY and
+// INCRBYFLOAT keep working on them, every other value goes through the
+// cache serializer. Floats with an integral value are read back as integers.
+func (s *RedisStore) serialize(value any) (any, error) {
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return v, nil
+	case int32:
+		return int64(v), nil
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	}
+
+	return s.serializer.Serialize(value)
+}
+
+// Unserialize the value.
+func (s *RedisStore) unserialize(value any) (any, error) {
+	data, ok := value.([]byte)
+
+	if !ok {
+		return nil, fmt.Errorf("cache: unexpected redis reply of type %T", value)
+	}
+
+	if i, err := strconv.Atoi(string(data)); err == nil {
+		return i, nil
+	}
+
+	if f, err := strconv.ParseFloat(string(data), 64); err == nil {
+		return f, nil
+	}
+
+	return s.serializer.Unserialize(data)
+}
+
+// Translate the error reply of a counter command.
+func counterError(err error) error {
+	var reply redis.Error
+
+	if !errors.As(err, &reply) {
+		return err
+	}
+
+	switch message := string(reply); {
+	case strings.Contains(message, "overflow"), strings.Contains(message, "NaN or Infinity"):
+		return ErrOverflow
+	case strings.Contains(message, "not an integer"), strings.Contains(message, "not a valid float"):
+		return ErrNotNumeric
+	}
+
+	return err
+}
+
+// Clamp the given amount to a minimum of one.
+func atLeastOne(amount int) int {
+	if amount < 1 {
+		return 1
+	}
+
+	return amount
+}
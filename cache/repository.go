@@ -1,28 +1,68 @@
 package cache
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
 	contracts "github.com/garavel-core/framework/contracts/cache"
+	"github.com/garavel-core/framework/contracts/events"
+	"github.com/garavel-core/framework/internal/singleflight"
 	"github.com/garavel-core/framework/support/arr"
 	"github.com/garavel-core/framework/support/helpers"
 )
 
 type Repository struct {
+	interactsWithTime
 	// The cache store implementation.
 	store contracts.Store
 	// The default number of seconds to store items.
 	defaultCacheTime int
 	// The event dispatcher implementation.
-	// events contracts.Dispatcher
+	events events.Dispatcher
+	// The cache store configuration options.
+	config map[string]any
+	// The tags attached to the events of this repository.
+	eventTags []string
+	// The callback that turns a key into the key of the stored item.
+	keyResolver func(key string) string
+	// The keys being refreshed in the background by Flexible.
+	refreshing sync.Map
+	// The group coalescing concurrent Remember calls, nil when disabled.
+	flights *singleflight.Group
+	// The number of seconds the cross-process remember lock is held, zero when disabled.
+	rememberLockSeconds int
+	// The number of seconds to wait for the cross-process remember lock.
+	rememberLockWait int
 }
 
+// The error returned when tagging is used with a store that does not support it.
+var ErrTaggingNotSupported = errors.New("cache: this cache store does not support tagging")
+
 // Create a new cache repository instance.
-func NewRepository(store contracts.Store) *Repository {
-	return &Repository{store: store, defaultCacheTime: 3600}
+func NewRepository(store contracts.Store, config ...map[string]any) *Repository {
+	repository := &Repository{store: store, defaultCacheTime: 3600, config: map[string]any{}, flights: &singleflight.Group{}}
+
+	if config != nil && config[0] != nil {
+		repository.config = config[0]
+	}
+
+	return repository
+}
+
+// Set the clock the repository reads the current time from, the system clock when nil.
+func (r *Repository) SetClock(clock func() time.Time) *Repository {
+	r.clock = clock
+
+	return r
 }
 
 // Determine if an item exists in the cache.
 func (r *Repository) Has(key string) bool {
-	return r.store.Get(key) != nil
+	return r.Get(key) != nil
 }
 
 // Determine if an item doesn't exist in the cache.
@@ -38,14 +78,14 @@ func (r *Repository) Get(key string, defaultValue ...any) any {
 	// the default value for this cache value. This default could be a callback
 	// so we will execute the value function which will resolve it if needed.
 	if value == nil {
-		// r.event(NewCacheMissed(key))
+		r.event(NewCacheMissed(r.GetName(), key, r.eventTags...))
 
 		if defaultValue != nil && defaultValue[0] != nil {
-			value = helpers.Value(defaultValue)
+			value = helpers.Value(defaultValue[0])
 		}
 
 	} else {
-		// r.event(NewCacheHit(key, value))
+		r.event(NewCacheHit(r.GetName(), key, value, r.eventTags...))
 	}
 
 	return value
@@ -56,7 +96,7 @@ func (r *Repository) Get(key string, defaultValue ...any) any {
 // Items not found in the cache will have a null value.
 func (r *Repository) Many(keys any) map[string]any {
 	if defaults, ok := keys.(map[string]any); ok {
-		return arr.Map(r.store.Many(arr.Keys(defaults)), func(value any, key string) any {
+		return arr.Map(r.many(arr.Keys(defaults)), func(value any, key string) any {
 			if value == nil {
 				return defaults[key]
 			}
@@ -65,7 +105,33 @@ func (r *Repository) Many(keys any) map[string]any {
 		})
 	}
 
-	return r.store.Many(keys.([]string))
+	return r.many(keys.([]string))
+}
+
+// Retrieve multiple items from the store by their item keys.
+func (r *Repository) many(keys []string) map[string]any {
+	itemKeys := make([]string, len(keys))
+
+	for i, key := range keys {
+		itemKeys[i] = r.itemKey(key)
+	}
+
+	values := r.store.Many(itemKeys)
+	results := make(map[string]any, len(keys))
+
+	for i, key := range keys {
+		value := values[itemKeys[i]]
+
+		if value == nil {
+			r.event(NewCacheMissed(r.GetName(), key, r.eventTags...))
+		} else {
+			r.event(NewCacheHit(r.GetName(), key, value, r.eventTags...))
+		}
+
+		results[key] = value
+	}
+
+	return results
 }
 
 // Obtains multiple cache items by their unique keys.
@@ -93,22 +159,42 @@ func (r *Repository) Pull(key string, defaultValue ...any) any {
 }
 
 // Store an item in the cache.
+//
+// The TTL may be a number of seconds, a time.Duration, the time.Time the item
+// expires at, a duration string such as "10m", or a callback computing any of
+// those from the value. A missing TTL stores the item indefinitely.
 func (r *Repository) Put(key string, value any, ttl ...any) bool {
 	if ttl == nil || ttl[0] == nil {
 		return r.Forever(key, value)
 	}
 
-	seconds := r.getSeconds(ttl)
+	duration, ok := r.getDuration(ttl[0], value)
+
+	// TTLs that cannot be resolved leave the cache untouched rather than being
+	// taken for an expiration, which would remove the existing item.
+	if !ok {
+		return false
+	}
 
-	if seconds <= 0 {
+	if duration <= 0 {
 		return r.Forget(key)
 	}
 
-	result := r.store.Put(r.itemKey(key), value, seconds)
+	seconds := durationSeconds(duration)
+
+	var result bool
+
+	// Stores that keep sub-second precision receive the exact duration, every
+	// other store receives the number of whole seconds covering it.
+	if store, ok := r.store.(contracts.PreciseStore); ok && duration%time.Second != 0 {
+		result = store.PutFor(r.itemKey(key), value, duration)
+	} else {
+		result = r.store.Put(r.itemKey(key), value, seconds)
+	}
 
-	// if result {
-	//     r.event(NewKeyWritten(key, value, seconds))
-	// }
+	if result {
+		r.event(NewKeyWritten(r.GetName(), key, value, seconds, r.eventTags...))
+	}
 
 	return result
 }
@@ -119,24 +205,43 @@ func (r *Repository) Set(key string, value any, ttl ...any) bool {
 }
 
 // Store multiple items in the cache for a given number of seconds.
+//
+// Callback TTLs are called with the whole map of values.
 func (r *Repository) PutMany(values map[string]any, ttl ...any) bool {
 	if ttl == nil || ttl[0] == nil {
 		return r.putManyForever(values)
 	}
 
-	seconds := r.getSeconds(ttl)
+	duration, ok := r.getDuration(ttl[0], values)
 
-	if seconds <= 0 {
+	if !ok {
+		return false
+	}
+
+	if duration <= 0 {
 		return r.DeleteMultiple(arr.Keys(values))
 	}
 
-	result := r.store.PutMany(values, seconds)
+	seconds := durationSeconds(duration)
+	items := make(map[string]any, len(values))
+
+	for key, value := range values {
+		items[r.itemKey(key)] = value
+	}
+
+	var result bool
 
-	// if result {
-	//     for key, value := range values {
-	//         r.event(NewKeyWritten(key, value, seconds))
-	//     }
-	// }
+	if store, ok := r.store.(contracts.PreciseStore); ok && duration%time.Second != 0 {
+		result = store.PutManyFor(items, duration)
+	} else {
+		result = r.store.PutMany(items, seconds)
+	}
+
+	if result {
+		for key, value := range values {
+			r.event(NewKeyWritten(r.GetName(), key, value, seconds, r.eventTags...))
+		}
+	}
 
 	return result
 }
@@ -161,12 +266,12 @@ func (r *Repository) SetMultiple(values map[string]any, ttl ...any) bool {
 
 // Store an item in the cache if the key does not exist.
 func (r *Repository) Add(key string, value any, ttl ...any) bool {
-	var seconds any
+	var duration any
 
 	if ttl != nil && ttl[0] != nil {
-		seconds = r.getSeconds(ttl)
+		resolved, ok := r.getDuration(ttl[0], value)
 
-		if seconds.(int) <= 0 {
+		if !ok || resolved <= 0 {
 			return false
 		}
 
@@ -174,15 +279,17 @@ func (r *Repository) Add(key string, value any, ttl ...any) bool {
 		// has a chance to override this logic. Some drivers better support the way
 		// this operation should work with a total "atomic" implementation of it.
 		if store, ok := any(r.store).(contracts.AtomicStore); ok {
-			return store.Add(r.itemKey(key), value, seconds)
+			return store.Add(r.itemKey(key), value, resolved)
 		}
+
+		duration = resolved
 	}
 
 	// If the value did not exist in the cache, we will put the value in the cache
 	// so it exists for subsequent requests. Then, we will return true so it is
 	// easy to know if the value gets added. Otherwise, we will return false.
 	if r.Get(key) == nil {
-		return r.Put(key, value, seconds)
+		return r.Put(key, value, duration)
 	}
 
 	return false
@@ -190,21 +297,21 @@ func (r *Repository) Add(key string, value any, ttl ...any) bool {
 
 // Increment the value of an item in the cache.
 func (r *Repository) Increment(key string, value ...any) any {
-	return r.store.Increment(key, value...)
+	return r.store.Increment(r.itemKey(key), value...)
 }
 
 // Decrement the value of an item in the cache.
 func (r *Repository) Decrement(key string, value ...any) any {
-	return r.store.Decrement(key, value...)
+	return r.store.Decrement(r.itemKey(key), value...)
 }
 
 // Store an item in the cache indefinitely.
 func (r *Repository) Forever(key string, value any) bool {
 	result := r.store.Forever(r.itemKey(key), value)
 
-	// if result {
-	//     r.event(NewKeyWritten(key, value))
-	// }
+	if result {
+		r.event(NewKeyWritten(r.GetName(), key, value, 0, r.eventTags...))
+	}
 
 	return result
 }
@@ -220,11 +327,9 @@ func (r *Repository) Remember(key string, ttl any, callback func() any) any {
 		return value
 	}
 
-	value = callback()
-
-	r.Put(key, value, helpers.Value(ttl, value))
-
-	return value
+	return r.remember(key, callback, func(value any) {
+		r.Put(key, value, ttl)
+	})
 }
 
 // Get an item from the cache, or execute the given Closure and store the result forever.
@@ -243,17 +348,188 @@ func (r *Repository) RememberForever(key string, callback func() any) any {
 		return value
 	}
 
-	return helpers.Tap(callback(), func(value any) {
+	return r.remember(key, callback, func(value any) {
 		r.Forever(key, value)
 	})
 }
 
+// Execute the callback of a missed remembered item and store its result.
+//
+// Concurrent misses of the same key within this repository share a single
+// run of the callback. When the remember lock is enabled and the store
+// provides locks, processes also take turns recomputing the item, and the
+// ones that waited pick up the value stored by the first.
+func (r *Repository) remember(key string, callback func() any, store func(value any)) any {
+	compute := func() any {
+		if provider, ok := r.store.(contracts.LockProvider); ok && r.rememberLockSeconds > 0 {
+			return r.rememberLocked(provider, key, callback, store)
+		}
+
+		return helpers.Tap(callback(), store)
+	}
+
+	if r.flights == nil {
+		return compute()
+	}
+
+	value, _ := r.flights.Do(r.itemKey(key), compute)
+
+	return value
+}
+
+// Execute the callback of a missed remembered item while holding its lock.
+func (r *Repository) rememberLocked(provider contracts.LockProvider, key string, callback func() any, store func(value any)) any {
+	recompute := func() any {
+		// Another process may have stored the item while we were waiting for the
+		// lock, in which case there is no need to call the callback again.
+		if value := r.store.Get(r.itemKey(key)); value != nil {
+			return value
+		}
+
+		return helpers.Tap(callback(), store)
+	}
+
+	lock := provider.Lock("garavel:cache:remember:lock:"+r.itemKey(key), r.rememberLockSeconds)
+
+	value, err := lock.Block(r.rememberLockWait, recompute)
+
+	// If the lock could not be acquired in time the process holding it is taking
+	// too long, so rather than failing the caller we compute the item anyway.
+	if err != nil {
+		return recompute()
+	}
+
+	return value
+}
+
+// Enable or disable sharing one callback run between concurrent Remember calls of a key.
+//
+// Coalescing is enabled by default.
+func (r *Repository) CoalesceRemember(enabled bool) *Repository {
+	if !enabled {
+		r.flights = nil
+	} else if r.flights == nil {
+		r.flights = &singleflight.Group{}
+	}
+
+	return r
+}
+
+// Recompute missed remembered items under a cache lock shared between processes.
+//
+// The lock is held for at most the given number of seconds and other callers
+// wait up to the given number of seconds for the item before computing it
+// themselves. Zero seconds disables the lock. Stores that do not provide
+// locks are not affected.
+func (r *Repository) LockRemember(seconds int, wait int) *Repository {
+	r.rememberLockSeconds = seconds
+	r.rememberLockWait = wait
+
+	return r
+}
+
+// Retrieve an item from the cache by key, refreshing it in the background if it is stale.
+//
+// The TTL holds the fresh and the stale window, each accepting anything Put
+// accepts. Values younger than the fresh window are returned as they are.
+// Older values are returned right away while a single background goroutine
+// calls the callback and stores its result again. Once the stale window has
+// passed the value is gone and the callback is called synchronously.
+//
+// Either window being a TTL Put does not support panics with ErrInvalidTTL
+// before the cache or the callback is touched.
+func (r *Repository) Flexible(key string, ttl [2]any, callback func() any) any {
+	createdKey := "garavel:cache:flexible:created:" + key
+
+	values := r.many([]string{key, createdKey})
+	value := values[key]
+
+	fresh, ok := r.getDuration(ttl[0], value)
+
+	if !ok {
+		panic(invalidTTL(ttl[0]))
+	}
+
+	if _, ok := r.getDuration(ttl[1], value); !ok {
+		panic(invalidTTL(ttl[1]))
+	}
+
+	created, ok := toInt(values[createdKey])
+
+	if value == nil || !ok {
+		value = callback()
+
+		r.putFlexible(key, value, ttl[1])
+
+		return value
+	}
+
+	if r.currentTime().Sub(time.UnixMilli(int64(created))) < fresh {
+		return value
+	}
+
+	r.refresh(key, created, ttl[1], callback)
+
+	return value
+}
+
+// Refresh the flexible item in the background unless a refresh is running.
+//
+// Goroutines of this repository are coalesced in process, stores that
+// provide locks also keep other processes from refreshing the same item.
+// A panicking callback releases both and is reported as a CacheRefreshFailed
+// event instead of crashing the process, leaving the stale value in place.
+func (r *Repository) refresh(key string, created int, ttl any, callback func() any) {
+	if _, running := r.refreshing.LoadOrStore(r.itemKey(key), struct{}{}); running {
+		return
+	}
+
+	go func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				r.event(NewCacheRefreshFailed(r.GetName(), key, fmt.Errorf("cache: the refresh panicked: %v", recovered), r.eventTags...))
+			}
+		}()
+
+		defer r.refreshing.Delete(r.itemKey(key))
+
+		update := func() any {
+			// Another process may have refreshed the item while we were waiting, in
+			// which case its creation time has moved on and there is nothing left
+			// for us to do but keep the value that was just computed elsewhere.
+			if current, _ := toInt(r.store.Get(r.itemKey("garavel:cache:flexible:created:" + key))); current != created {
+				return nil
+			}
+
+			r.putFlexible(key, callback(), ttl)
+
+			return nil
+		}
+
+		if store, ok := r.store.(contracts.LockProvider); ok {
+			stale, _ := r.getDuration(ttl, nil)
+
+			store.Lock("garavel:cache:flexible:lock:"+r.itemKey(key), durationSeconds(stale)).Get(update)
+		} else {
+			update()
+		}
+	}()
+}
+
+// Store the flexible item along with the time it was created.
+func (r *Repository) putFlexible(key string, value any, ttl any) bool {
+	return r.PutMany(map[string]any{
+		key:                                     value,
+		"garavel:cache:flexible:created:" + key: int(r.currentTime().UnixMilli()),
+	}, ttl)
+}
+
 // Remove an item from the cache.
 func (r *Repository) Forget(key string) bool {
 	return helpers.Tap(r.store.Forget(r.itemKey(key)), func(result bool) {
-		// if result {
-		//     r.event(NewKeyForgotten(key))
-		// }
+		if result {
+			r.event(NewKeyForgotten(r.GetName(), key, r.eventTags...))
+		}
 	})
 }
 
@@ -281,31 +557,100 @@ func (r *Repository) Clear() bool {
 }
 
 // Begin executing a new tags operation if the store supports it.
-// func (r *Repository) Tags(names ...string) (contracts.TaggableStore, error) {
-//     store, ok := any(r.store).(contracts.TaggableStore);
+func (r *Repository) Tags(names ...string) (contracts.TaggedCache, error) {
+	store, ok := r.store.(contracts.TaggableStore)
+
+	if !ok {
+		return nil, ErrTaggingNotSupported
+	}
 
-//     if !ok {
-//         return nil, errors.New("This cache store does not support tagging.")
-//     }
+	cache := store.Tags(names...)
 
-//     cache := store.Tags(names...)
+	// The tagged cache shares the configuration of this repository, so events
+	// it fires carry the same store name and reach the same dispatcher.
+	if tagged, ok := cache.(*TaggedCache); ok {
+		r.share(tagged.Repository)
 
-//     if r.events != nil {
-//         cache.SetEventDispatcher(r.events)
-//     }
+		// Tags nest like namespaces: tagging a namespace resolves the tag
+		// identifiers and the tagged items through it, so they stay apart from
+		// other namespaces and go away when the namespace is flushed.
+		if r.keyResolver != nil {
+			resolve := tagged.keyResolver
 
-//     return cache.SetDefaultCacheTime(r.defaultCacheTime), nil
-// }
+			tagged.tags.parent = r.itemKey
+			tagged.keyResolver = func(key string) string {
+				return r.itemKey(resolve(key))
+			}
+		}
+	}
+
+	return cache, nil
+}
+
+// Begin a new repository scoped to the given namespace.
+func (r *Repository) Namespace(name string) *NamespacedCache {
+	cache := NewNamespacedCache(r.store, name)
+
+	// Namespaces nest: the version key and the items of the namespace are
+	// resolved through this repository, so flushing an outer namespace or tag
+	// also invalidates every namespace inside it.
+	cache.parent = r.itemKey
+	cache.eventTags = r.eventTags
+	r.share(cache.Repository)
+
+	return cache
+}
+
+// Share the configuration of this repository with a derived repository.
+func (r *Repository) share(repository *Repository) {
+	repository.config = r.config
+	repository.flights = r.flights
+	repository.SetDefaultCacheTime(r.defaultCacheTime)
+	repository.LockRemember(r.rememberLockSeconds, r.rememberLockWait)
+
+	if r.events != nil {
+		repository.SetEventDispatcher(r.events)
+	}
+}
 
 // Format the key for a cache item.
 func (r *Repository) itemKey(key string) string {
+	if r.keyResolver != nil {
+		key = r.keyResolver(key)
+	}
+
+	if store, ok := r.store.(contracts.KeyLimitedStore); ok {
+		return fitKey(key, store.MaxKeyLength())
+	}
+
 	return key
 }
 
-// Calculate the number of seconds for the given TTL.
-func (r *Repository) getSeconds(ttl any) int {
-	// TODO
-	return 0
+// Shorten a key longer than the given limit to a hash of the whole key.
+//
+// The key keeps as much of its beginning as fits in front of the hash, so
+// shortened keys stay recognisable while the same key always maps to the
+// same stored item.
+func fitKey(key string, limit int) string {
+	if limit <= 0 || len(key) <= limit {
+		return key
+	}
+
+	sum := sha1.Sum([]byte(key))
+	hash := hex.EncodeToString(sum[:])
+
+	if limit <= len(hash)+1 {
+		return hash
+	}
+
+	return key[:limit-len(hash)-1] + ":" + hash
+}
+
+// Calculate the duration of the given TTL, resolving callbacks with the value.
+//
+// TTLs that cannot be resolved are reported as not ok.
+func (r *Repository) getDuration(ttl any, value any) (time.Duration, bool) {
+	return r.parseDuration(ttl, value)
 }
 
 // Get the default cache time.
@@ -325,19 +670,26 @@ func (r *Repository) GetStore() contracts.Store {
 	return r.store
 }
 
+// Get the name of the cache store.
+func (r *Repository) GetName() string {
+	name, _ := r.config["store"].(string)
+
+	return name
+}
+
 // Fire an event for this cache instance.
-// func (r *Repository)	 event(event any) {
-//     if r.events != nil {
-//         r.events.dispatch(event)
-//     }
-// }
+func (r *Repository) event(event any) {
+	if r.events != nil {
+		r.events.Dispatch(event)
+	}
+}
 
 // Get the event dispatcher instance.
-// func (r *Repository) GetEventDispatcher() contracts.Dispatcher {
-//     return r.events;
-// }
+func (r *Repository) GetEventDispatcher() events.Dispatcher {
+	return r.events
+}
 
 // Set the event dispatcher instance.
-// func (r *Repository)	SetEventDispatcher(events contracts.Dispatcher)  {
-//     r.events = events;
-// }
+func (r *Repository) SetEventDispatcher(events events.Dispatcher) {
+	r.events = events
+}
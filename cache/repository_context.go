@@ -0,0 +1,306 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	contracts "github.com/garavel-core/framework/contracts/cache"
+	"github.com/garavel-core/framework/support/arr"
+)
+
+// The result of a remembered callback run through the context-first API.
+type contextResult struct {
+	// The value returned by the callback.
+	value any
+	// The error returned by the callback or by storing its value.
+	err error
+}
+
+// Get the context-first API of the cache store.
+func (r *Repository) contextStore() contracts.ContextStore {
+	return AsContextStore(r.store)
+}
+
+// Determine if an item exists in the cache.
+func (r *Repository) HasCtx(ctx context.Context, key string) (bool, error) {
+	_, found, err := r.GetCtx(ctx, key)
+
+	return found, err
+}
+
+// Retrieve an item from the cache by key, reporting whether it was found.
+//
+// Unlike Get, a failing store is reported as an error instead of a miss.
+func (r *Repository) GetCtx(ctx context.Context, key string) (any, bool, error) {
+	value, found, err := r.contextStore().GetCtx(ctx, r.itemKey(key))
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	if found {
+		r.event(NewCacheHit(r.GetName(), key, value, r.eventTags...))
+	} else {
+		r.event(NewCacheMissed(r.GetName(), key, r.eventTags...))
+	}
+
+	return value, found, nil
+}
+
+// Retrieve multiple items from the cache by key, missing items have a nil value.
+func (r *Repository) ManyCtx(ctx context.Context, keys []string) (map[string]any, error) {
+	itemKeys := make([]string, len(keys))
+
+	for i, key := range keys {
+		itemKeys[i] = r.itemKey(key)
+	}
+
+	values, err := r.contextStore().ManyCtx(ctx, itemKeys)
+
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]any, len(keys))
+
+	for i, key := range keys {
+		value := values[itemKeys[i]]
+
+		if value == nil {
+			r.event(NewCacheMissed(r.GetName(), key, r.eventTags...))
+		} else {
+			r.event(NewCacheHit(r.GetName(), key, value, r.eventTags...))
+		}
+
+		results[key] = value
+	}
+
+	return results, nil
+}
+
+// Retrieve an item from the cache and delete it.
+func (r *Repository) PullCtx(ctx context.Context, key string) (any, bool, error) {
+	value, found, err := r.GetCtx(ctx, key)
+
+	if err != nil || !found {
+		return nil, false, err
+	}
+
+	if _, err := r.ForgetCtx(ctx, key); err != nil {
+		return nil, false, err
+	}
+
+	return value, true, nil
+}
+
+// Store an item in the cache.
+//
+// The TTL is read the same way as by Put. Sub-second TTLs are rounded up to
+// whole seconds unless the store keeps sub-second precision, in which case
+// failures of that store are reported as ErrStoreFailed.
+func (r *Repository) PutCtx(ctx context.Context, key string, value any, ttl ...any) error {
+	if ttl == nil || ttl[0] == nil {
+		return r.ForeverCtx(ctx, key, value)
+	}
+
+	duration, ok := r.getDuration(ttl[0], value)
+
+	if !ok {
+		return invalidTTL(ttl[0])
+	}
+
+	if duration <= 0 {
+		_, err := r.ForgetCtx(ctx, key)
+
+		return err
+	}
+
+	seconds := durationSeconds(duration)
+
+	var err error
+
+	if store, ok := r.store.(contracts.PreciseStore); ok && duration%time.Second != 0 {
+		if err = ctx.Err(); err == nil {
+			err = failure(store.PutFor(r.itemKey(key), value, duration))
+		}
+	} else {
+		err = r.contextStore().PutCtx(ctx, r.itemKey(key), value, seconds)
+	}
+
+	if err == nil {
+		r.event(NewKeyWritten(r.GetName(), key, value, seconds, r.eventTags...))
+	}
+
+	return err
+}
+
+// Store multiple items in the cache.
+//
+// Callback TTLs are called with the whole map of values.
+func (r *Repository) PutManyCtx(ctx context.Context, values map[string]any, ttl ...any) error {
+	if ttl == nil || ttl[0] == nil {
+		for key, value := range values {
+			if err := r.ForeverCtx(ctx, key, value); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	duration, ok := r.getDuration(ttl[0], values)
+
+	if !ok {
+		return invalidTTL(ttl[0])
+	}
+
+	if duration <= 0 {
+		for _, key := range arr.Keys(values) {
+			if _, err := r.ForgetCtx(ctx, key); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	seconds := durationSeconds(duration)
+	items := make(map[string]any, len(values))
+
+	for key, value := range values {
+		items[r.itemKey(key)] = value
+	}
+
+	var err error
+
+	if store, ok := r.store.(contracts.PreciseStore); ok && duration%time.Second != 0 {
+		if err = ctx.Err(); err == nil {
+			err = failure(store.PutManyFor(items, duration))
+		}
+	} else {
+		err = r.contextStore().PutManyCtx(ctx, items, seconds)
+	}
+
+	if err == nil {
+		for key, value := range values {
+			r.event(NewKeyWritten(r.GetName(), key, value, seconds, r.eventTags...))
+		}
+	}
+
+	return err
+}
+
+// Store an item in the cache if the key does not exist, reporting whether it was stored.
+func (r *Repository) AddCtx(ctx context.Context, key string, value any, ttl ...any) (bool, error) {
+	seconds := 0
+
+	if ttl != nil && ttl[0] != nil {
+		duration, ok := r.getDuration(ttl[0], value)
+
+		if !ok {
+			return false, invalidTTL(ttl[0])
+		}
+
+		if duration <= 0 {
+			return false, nil
+		}
+
+		seconds = durationSeconds(duration)
+	}
+
+	added, err := r.contextStore().AddCtx(ctx, r.itemKey(key), value, seconds)
+
+	if added && err == nil {
+		r.event(NewKeyWritten(r.GetName(), key, value, seconds, r.eventTags...))
+	}
+
+	return added, err
+}
+
+// Increment the value of an item in the cache and return the new value.
+func (r *Repository) IncrementCtx(ctx context.Context, key string, value int64) (int64, error) {
+	return r.contextStore().IncrementCtx(ctx, r.itemKey(key), value)
+}
+
+// Decrement the value of an item in the cache and return the new value.
+func (r *Repository) DecrementCtx(ctx context.Context, key string, value int64) (int64, error) {
+	return r.contextStore().DecrementCtx(ctx, r.itemKey(key), value)
+}
+
+// Store an item in the cache indefinitely.
+func (r *Repository) ForeverCtx(ctx context.Context, key string, value any) error {
+	err := r.contextStore().ForeverCtx(ctx, r.itemKey(key), value)
+
+	if err == nil {
+		r.event(NewKeyWritten(r.GetName(), key, value, 0, r.eventTags...))
+	}
+
+	return err
+}
+
+// Get an item from the cache, or execute the given callback and store the result.
+//
+// The callback is coalesced and locked the same way as by Remember, so
+// concurrent callers may share the run started with another caller's
+// context. A value whose storing failed is returned along with the error.
+func (r *Repository) RememberCtx(ctx context.Context, key string, ttl any, callback func(ctx context.Context) (any, error)) (any, error) {
+	return r.rememberCtx(ctx, key, callback, func(value any) error {
+		return r.PutCtx(ctx, key, value, ttl)
+	})
+}
+
+// Get an item from the cache, or execute the given callback and store the result forever.
+func (r *Repository) RememberForeverCtx(ctx context.Context, key string, callback func(ctx context.Context) (any, error)) (any, error) {
+	return r.rememberCtx(ctx, key, callback, func(value any) error {
+		return r.ForeverCtx(ctx, key, value)
+	})
+}
+
+// Get an item from the cache, or execute the callback and store its result.
+func (r *Repository) rememberCtx(ctx context.Context, key string, callback func(ctx context.Context) (any, error), store func(value any) error) (any, error) {
+	value, found, err := r.GetCtx(ctx, key)
+
+	if err != nil || found {
+		return value, err
+	}
+
+	result := r.remember(key, func() any {
+		value, err := callback(ctx)
+
+		return &contextResult{value: value, err: err}
+	}, func(result any) {
+		if result, ok := result.(*contextResult); ok && result.err == nil {
+			result.err = store(result.value)
+		}
+	})
+
+	if result, ok := result.(*contextResult); ok {
+		return result.value, result.err
+	}
+
+	// The item was stored by another process while this one waited for the
+	// remember lock, so the raw cached value is returned.
+	return result, nil
+}
+
+// Remove an item from the cache, reporting whether it existed.
+func (r *Repository) ForgetCtx(ctx context.Context, key string) (bool, error) {
+	forgotten, err := r.contextStore().ForgetCtx(ctx, r.itemKey(key))
+
+	if forgotten && err == nil {
+		r.event(NewKeyForgotten(r.GetName(), key, r.eventTags...))
+	}
+
+	return forgotten, err
+}
+
+// Wipes clean the entire cache's keys.
+func (r *Repository) ClearCtx(ctx context.Context) error {
+	return r.contextStore().FlushCtx(ctx)
+}
+
+// Get the error reporting a TTL that cannot be resolved.
+func invalidTTL(ttl any) error {
+	return fmt.Errorf("%w: %#v", ErrInvalidTTL, ttl)
+}
@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"errors"
+
+	contracts "github.com/garavel-core/framework/contracts/cache"
+)
+
+// The error returned when counters are used with a store that does not support them.
+//
+// A store without contracts.CounterStore cannot tell how long an item has
+// left to live, so it cannot change an item and keep its expiration.
+var ErrCountersNotSupported = errors.New("cache: this cache store does not support counters")
+
+// Add the given step to an integer counter and return the new value.
+//
+// A missing item counts as zero, a negative step decrements the counter and
+// ErrNotNumeric or ErrOverflow are returned when the item cannot be changed.
+// Stores without counter support return ErrCountersNotSupported.
+func (r *Repository) IncrementBy(key string, value int64) (int64, error) {
+	return incrementStore(r.store, r.itemKey(key), value)
+}
+
+// Subtract the given step from an integer counter and return the new value.
+//
+// When floor is given as true the counter stops at zero instead of going
+// below it, while a counter that already is below zero is left unchanged.
+func (r *Repository) DecrementBy(key string, value int64, floor ...bool) (int64, error) {
+	return decrementStore(r.store, r.itemKey(key), value, floor != nil && floor[0])
+}
+
+// Add the given step to a floating point counter and return the new value.
+func (r *Repository) IncrementFloat(key string, value float64) (float64, error) {
+	return incrementFloatStore(r.store, r.itemKey(key), value)
+}
+
+// Add the given step to an integer counter of the given store.
+func incrementStore(store contracts.Store, key string, value int64) (int64, error) {
+	if store, ok := store.(contracts.CounterStore); ok {
+		return store.IncrementBy(key, value)
+	}
+
+	return 0, ErrCountersNotSupported
+}
+
+// Subtract the given step from an integer counter of the given store.
+func decrementStore(store contracts.Store, key string, value int64, floor bool) (int64, error) {
+	if store, ok := store.(contracts.CounterStore); ok {
+		return store.DecrementBy(key, value, floor)
+	}
+
+	return 0, ErrCountersNotSupported
+}
+
+// Add the given step to a floating point counter of the given store.
+func incrementFloatStore(store contracts.Store, key string, value float64) (float64, error) {
+	if store, ok := store.(contracts.CounterStore); ok {
+		return store.IncrementFloat(key, value)
+	}
+
+	return 0, ErrCountersNotSupported
+}
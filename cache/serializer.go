@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+
+	"github.com/garavel-core/framework/internal/msgpack"
+)
+
+// The envelope used to encode arbitrary values with gob.
+//
+// Gob cannot encode a bare interface value, so every value is wrapped in a
+// struct with an interface field. Concrete types other than the builtin ones
+// must be registered with gob, or with RegisterType, before they can round-trip.
+type serializedValue struct {
+	Value any
+}
+
+// The envelope used to encode arbitrary values with JSON.
+type jsonValue struct {
+	// The registered name of the type of the value, empty for other types.
+	Type string `json:"type,omitempty"`
+	// The encoded value.
+	Value json.RawMessage `json:"value"`
+}
+
+// GobSerializer serializes values with encoding/gob.
+//
+// Gob flattens pointers, so pointers decode into the values they point to.
+type GobSerializer struct{}
+
+// Generate a storable representation of a value.
+func (GobSerializer) Serialize(value any) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	if err := gob.NewEncoder(&buffer).Encode(serializedValue{Value: value}); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// Create a value from a stored representation.
+func (GobSerializer) Unserialize(data []byte) (any, error) {
+	var envelope serializedValue
+
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&envelope); err != nil {
+		return nil, err
+	}
+
+	return envelope.Value, nil
+}
+
+// JSONSerializer serializes values with encoding/json.
+//
+// Values of registered types decode back into their type, every other value
+// decodes the way encoding/json decodes into an interface.
+type JSONSerializer struct {
+	// The registry of the types values decode into.
+	registry *TypeRegistry
+}
+
+// Create a new JSON serializer using the given type registry or the default one.
+func NewJSONSerializer(registry ...*TypeRegistry) *JSONSerializer {
+	return &JSONSerializer{registry: typeRegistry(registry)}
+}
+
+// Generate a storable representation of a value.
+func (s *JSONSerializer) Serialize(value any) ([]byte, error) {
+	data, err := json.Marshal(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(jsonValue{Type: s.registry.nameOfValue(value), Value: data})
+}
+
+// Create a value from a stored representation.
+func (s *JSONSerializer) Unserialize(data []byte) (any, error) {
+	var envelope jsonValue
+
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, err
+	}
+
+	target, err := s.registry.target(envelope.Type)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(envelope.Value, target.Interface()); err != nil {
+		return nil, err
+	}
+
+	return target.Elem().Interface(), nil
+}
+
+// MsgpackSerializer serializes values with MessagePack.
+//
+// Values of registered types decode back into their type, every other value
+// decodes into the generic values of the encoding, keeping integers intact.
+type MsgpackSerializer struct {
+	// The registry of the types values decode into.
+	registry *TypeRegistry
+}
+
+// Create a new MessagePack serializer using the given type registry or the default one.
+func NewMsgpackSerializer(registry ...*TypeRegistry) *MsgpackSerializer {
+	return &MsgpackSerializer{registry: typeRegistry(registry)}
+}
+
+// Generate a storable representation of a value.
+func (s *MsgpackSerializer) Serialize(value any) ([]byte, error) {
+	return msgpack.Marshal([]any{s.registry.nameOfValue(value), value})
+}
+
+// Create a value from a stored representation.
+func (s *MsgpackSerializer) Unserialize(data []byte) (any, error) {
+	var envelope []any
+
+	if err := msgpack.Unmarshal(data, &envelope); err != nil {
+		return nil, err
+	}
+
+	if len(envelope) != 2 {
+		return nil, fmt.Errorf("cache: invalid MessagePack envelope")
+	}
+
+	name, ok := envelope[0].(string)
+
+	if !ok {
+		return nil, fmt.Errorf("cache: invalid MessagePack envelope")
+	}
+
+	target, err := s.registry.target(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := msgpack.Convert(envelope[1], target.Interface()); err != nil {
+		return nil, err
+	}
+
+	return target.Elem().Interface(), nil
+}
+
+// Get the given type registry or the default one.
+func typeRegistry(registry []*TypeRegistry) *TypeRegistry {
+	if registry != nil && registry[0] != nil {
+		return registry[0]
+	}
+
+	return DefaultTypeRegistry
+}
@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+
+	contracts "github.com/garavel-core/framework/contracts/cache"
+	"github.com/garavel-core/framework/support/str"
+)
+
+type TagSet struct {
+	// The cache store implementation.
+	store contracts.Store
+	// The tag names.
+	names []string
+	// The callback that resolves keys of the enclosing repository, nil when none.
+	parent func(key string) string
+}
+
+// Create a new TagSet instance.
+func NewTagSet(store contracts.Store, names []string) *TagSet {
+	return &TagSet{store: store, names: names}
+}
+
+// Reset all tags in the set.
+func (t *TagSet) Reset() {
+	for _, name := range t.names {
+		t.ResetTag(name)
+	}
+}
+
+// Reset the tag and return the new tag identifier.
+func (t *TagSet) ResetTag(name string) string {
+	id := str.Random(20)
+
+	t.store.Forever(t.TagKey(name), id)
+
+	return id
+}
+
+// Flush all the tags in the set.
+func (t *TagSet) Flush() {
+	for _, name := range t.names {
+		t.FlushTag(name)
+	}
+}
+
+// Flush the tag from the cache.
+func (t *TagSet) FlushTag(name string) {
+	t.store.Forget(t.TagKey(name))
+}
+
+// Get a unique namespace that changes when any of the tags are flushed.
+func (t *TagSet) GetNamespace() string {
+	return strings.Join(t.tagIds(), "|")
+}
+
+// Get an array of tag identifiers for all of the tags in the set.
+func (t *TagSet) tagIds() []string {
+	ids := make([]string, len(t.names))
+
+	for i, name := range t.names {
+		ids[i] = t.TagId(name)
+	}
+
+	return ids
+}
+
+// Get the unique tag identifier for a given tag.
+func (t *TagSet) TagId(name string) string {
+	if id := t.store.Get(t.TagKey(name)); id != nil {
+		return fmt.Sprint(id)
+	}
+
+	// When the store can add atomically, concurrent first uses of the same tag
+	// agree on a single identifier instead of overwriting each other's.
+	if store, ok := t.store.(contracts.AtomicStore); ok {
+		id := str.Random(20)
+
+		if store.Add(t.TagKey(name), id) {
+			return id
+		}
+
+		if id := t.store.Get(t.TagKey(name)); id != nil {
+			return fmt.Sprint(id)
+		}
+	}
+
+	return t.ResetTag(name)
+}
+
+// Get the tag identifier key for a given tag.
+func (t *TagSet) TagKey(name string) string {
+	key := "tag:" + name + ":key"
+
+	if t.parent != nil {
+		return t.parent(key)
+	}
+
+	return key
+}
+
+// Get all of the tag names in the set.
+func (t *TagSet) GetNames() []string {
+	return t.names
+}
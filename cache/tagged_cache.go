@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+
+	contracts "github.com/garavel-core/framework/contracts/cache"
+)
+
+type TaggedCache struct {
+	*Repository
+	// The tag set instance.
+	tags *TagSet
+}
+
+// Create a new tagged cache instance.
+func NewTaggedCache(store contracts.Store, tags *TagSet) *TaggedCache {
+	cache := &TaggedCache{Repository: NewRepository(store), tags: tags}
+
+	cache.keyResolver = cache.taggedItemKey
+	cache.eventTags = tags.GetNames()
+
+	return cache
+}
+
+// Remove all items from the cache.
+func (c *TaggedCache) Flush() bool {
+	c.tags.Reset()
+
+	return true
+}
+
+// Wipes clean the entire cache's keys.
+func (c *TaggedCache) Clear() bool {
+	return c.Flush()
+}
+
+// Wipes clean the entire cache's keys.
+func (c *TaggedCache) ClearCtx(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.Flush()
+
+	return nil
+}
+
+// Get a fully qualified key for a tagged item.
+func (c *TaggedCache) taggedItemKey(key string) string {
+	sum := sha1.Sum([]byte(c.tags.GetNamespace()))
+
+	return hex.EncodeToString(sum[:]) + ":" + key
+}
+
+// Get the tag set instance.
+func (c *TaggedCache) GetTags() *TagSet {
+	return c.tags
+}
@@ -0,0 +1,343 @@
+package cache
+
+import (
+	"time"
+
+	contracts "github.com/garavel-core/framework/contracts/cache"
+)
+
+// TieredStore keeps a local store in front of a remote store.
+//
+// Reads are answered by the local store when possible and otherwise by the
+// remote store, whose values are copied into the local store for at most the
+// local TTL. Writes go to both stores. Since other processes only write to
+// the remote store, the local TTL bounds how long a process may serve a value
+// that was changed elsewhere.
+type TieredStore struct {
+	interactsWithTime
+	// The local store, usually in memory.
+	local contracts.Store
+	// The remote store holding the values of record.
+	remote contracts.Store
+	// The longest time values are kept in the local store.
+	localTTL time.Duration
+}
+
+// Create a new tiered store.
+func NewTieredStore(local contracts.Store, remote contracts.Store) *TieredStore {
+	return &TieredStore{local: local, remote: remote, localTTL: time.Minute}
+}
+
+// Set the clock the store reads the current time from, the system clock when nil.
+func (s *TieredStore) SetClock(clock func() time.Time) *TieredStore {
+	s.clock = clock
+
+	return s
+}
+
+// Retrieve an item from the cache by key.
+func (s *TieredStore) Get(key string) any {
+	if value := s.local.Get(key); value != nil {
+		return value
+	}
+
+	value := s.remote.Get(key)
+
+	if value != nil {
+		s.putLocal(key, value, s.localTTL)
+	}
+
+	return value
+}
+
+// Retrieve multiple items from the cache by key.
+//
+// Items not found in the cache will have a null value. Only the keys missing
+// from the local store are fetched from the remote store, in a single batch.
+func (s *TieredStore) Many(keys []string) map[string]any {
+	results := s.local.Many(keys)
+
+	var missing []string
+
+	for _, key := range keys {
+		if results[key] == nil {
+			missing = append(missing, key)
+		}
+	}
+
+	if missing == nil {
+		return results
+	}
+
+	backfill := make(map[string]any)
+
+	for key, value := range s.remote.Many(missing) {
+		results[key] = value
+
+		if value != nil {
+			backfill[key] = value
+		}
+	}
+
+	if len(backfill) != 0 {
+		s.putManyLocal(backfill, s.localTTL)
+	}
+
+	return results
+}
+
+// Store an item in the cache for a given number of seconds.
+func (s *TieredStore) Put(key string, value any, seconds int) bool {
+	if !s.remote.Put(key, value, seconds) {
+		s.local.Forget(key)
+
+		return false
+	}
+
+	s.putLocal(key, value, s.localDuration(time.Duration(seconds)*time.Second))
+
+	return true
+}
+
+// Store multiple items in the cache for a given number of seconds.
+func (s *TieredStore) PutMany(values map[string]any, seconds int) bool {
+	if !s.remote.PutMany(values, seconds) {
+		s.forgetLocal(values)
+
+		return false
+	}
+
+	s.putManyLocal(values, s.localDuration(time.Duration(seconds)*time.Second))
+
+	return true
+}
+
+// Store an item in the cache for a given duration, keeping sub-second precision.
+func (s *TieredStore) PutFor(key string, value any, ttl time.Duration) bool {
+	var result bool
+
+	if store, ok := s.remote.(contracts.PreciseStore); ok {
+		result = store.PutFor(key, value, ttl)
+	} else {
+		result = s.remote.Put(key, value, durationSeconds(ttl))
+	}
+
+	if !result {
+		s.local.Forget(key)
+
+		return false
+	}
+
+	s.putLocal(key, value, s.localDuration(ttl))
+
+	return true
+}
+
+// Store multiple items in the cache for a given duration, keeping sub-second precision.
+func (s *TieredStore) PutManyFor(values map[string]any, ttl time.Duration) bool {
+	var result bool
+
+	if store, ok := s.remote.(contracts.PreciseStore); ok {
+		result = store.PutManyFor(values, ttl)
+	} else {
+		result = s.remote.PutMany(values, durationSeconds(ttl))
+	}
+
+	if !result {
+		s.forgetLocal(values)
+
+		return false
+	}
+
+	s.putManyLocal(values, s.localDuration(ttl))
+
+	return true
+}
+
+// Store an item in the cache if the key does not exist.
+//
+// Only the remote store decides whether the item exists, a value the local
+// store still holds may have been removed from the remote store already.
+func (s *TieredStore) Add(key string, value any, ttl ...any) bool {
+	duration, ok := s.ttlDuration(ttl...)
+
+	if !ok {
+		return false
+	}
+
+	var added bool
+
+	if store, ok := s.remote.(contracts.AtomicStore); ok {
+		added = store.Add(key, value, ttl...)
+	} else if s.remote.Get(key) == nil {
+		if seconds := durationSeconds(duration); seconds > 0 {
+			added = s.remote.Put(key, value, seconds)
+		} else {
+			added = s.remote.Forever(key, value)
+		}
+	}
+
+	if added {
+		s.putLocal(key, value, s.localDuration(duration))
+	}
+
+	return added
+}
+
+// Increment the value of an item in the cache.
+func (s *TieredStore) Increment(key string, value ...any) any {
+	return s.refreshLocal(key, s.remote.Increment(key, value...))
+}
+
+// Decrement the value of an item in the cache.
+func (s *TieredStore) Decrement(key string, value ...any) any {
+	return s.refreshLocal(key, s.remote.Decrement(key, value...))
+}
+
+// Add the given step to an integer counter and return the new value.
+//
+// The counter is changed by the remote store and the local copy is dropped,
+// since the remote store does not tell how long the counter has left to live.
+func (s *TieredStore) IncrementBy(key string, value int64) (int64, error) {
+	defer s.local.Forget(key)
+
+	return incrementStore(s.remote, key, value)
+}
+
+// Subtract the given step from an integer counter and return the new value.
+func (s *TieredStore) DecrementBy(key string, value int64, floor bool) (int64, error) {
+	defer s.local.Forget(key)
+
+	return decrementStore(s.remote, key, value, floor)
+}
+
+// Add the given step to a floating point counter and return the new value.
+func (s *TieredStore) IncrementFloat(key string, value float64) (float64, error) {
+	defer s.local.Forget(key)
+
+	return incrementFloatStore(s.remote, key, value)
+}
+
+// Store an item in the cache indefinitely.
+func (s *TieredStore) Forever(key string, value any) bool {
+	if !s.remote.Forever(key, value) {
+		s.local.Forget(key)
+
+		return false
+	}
+
+	s.putLocal(key, value, s.localTTL)
+
+	return true
+}
+
+// Remove an item from the cache.
+func (s *TieredStore) Forget(key string) bool {
+	s.local.Forget(key)
+
+	return s.remote.Forget(key)
+}
+
+// Remove all items from the cache.
+func (s *TieredStore) Flush() bool {
+	s.local.Flush()
+
+	return s.remote.Flush()
+}
+
+// Begin executing a new tags operation.
+func (s *TieredStore) Tags(names ...string) contracts.TaggedCache {
+	return NewTaggedCache(s, NewTagSet(s, names))
+}
+
+// Get a lock instance.
+//
+// Locks are always held by the remote store, since they guard processes
+// that do not share the local store.
+func (s *TieredStore) Lock(name string, seconds int, owner ...string) contracts.Lock {
+	if store, ok := s.remote.(contracts.LockProvider); ok {
+		return store.Lock(name, seconds, owner...)
+	}
+
+	return NewCacheLock(s.remote, name, seconds, owner...)
+}
+
+// Restore a lock instance using the owner identifier.
+func (s *TieredStore) RestoreLock(name string, owner string) contracts.Lock {
+	return s.Lock(name, 0, owner)
+}
+
+// Get the cache key prefix.
+func (s *TieredStore) GetPrefix() string {
+	return s.remote.GetPrefix()
+}
+
+// Get the local store.
+func (s *TieredStore) GetLocal() contracts.Store {
+	return s.local
+}
+
+// Get the remote store.
+func (s *TieredStore) GetRemote() contracts.Store {
+	return s.remote
+}
+
+// Get the longest time values are kept in the local store.
+func (s *TieredStore) GetLocalTTL() time.Duration {
+	return s.localTTL
+}
+
+// Set the longest time values are kept in the local store.
+func (s *TieredStore) SetLocalTTL(ttl time.Duration) *TieredStore {
+	s.localTTL = ttl
+
+	return s
+}
+
+// Get the time an item stored for the given duration is kept locally.
+//
+// Items stored indefinitely, given as a zero duration, are kept for the
+// local TTL like every other item outliving it.
+func (s *TieredStore) localDuration(ttl time.Duration) time.Duration {
+	if ttl <= 0 || ttl > s.localTTL {
+		return s.localTTL
+	}
+
+	return ttl
+}
+
+// Store an item in the local store for the given duration.
+func (s *TieredStore) putLocal(key string, value any, ttl time.Duration) {
+	if store, ok := s.local.(contracts.PreciseStore); ok {
+		store.PutFor(key, value, ttl)
+	} else {
+		s.local.Put(key, value, durationSeconds(ttl))
+	}
+}
+
+// Store multiple items in the local store for the given duration.
+func (s *TieredStore) putManyLocal(values map[string]any, ttl time.Duration) {
+	if store, ok := s.local.(contracts.PreciseStore); ok {
+		store.PutManyFor(values, ttl)
+	} else {
+		s.local.PutMany(values, durationSeconds(ttl))
+	}
+}
+
+// Remove the given items from the local store.
+func (s *TieredStore) forgetLocal(values map[string]any) {
+	for key := range values {
+		s.local.Forget(key)
+	}
+}
+
+// Replace the local copy of a counter with the value the remote store returned.
+func (s *TieredStore) refreshLocal(key string, value any) any {
+	if _, ok := value.(bool); ok || value == nil {
+		s.local.Forget(key)
+	} else {
+		s.putLocal(key, value, s.localTTL)
+	}
+
+	return value
+}
@@ -0,0 +1,456 @@
+package cache
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	contracts "github.com/garavel-core/framework/contracts/cache"
+	"github.com/garavel-core/framework/contracts/encryption"
+)
+
+// The algorithm compressing the values of a TransformingStore.
+type Compression byte
+
+const (
+	// Values are stored uncompressed.
+	NoCompression Compression = iota
+	// Values are compressed with gzip.
+	GzipCompression
+	// Values are compressed with raw DEFLATE, which saves the gzip header.
+	FlateCompression
+)
+
+// The flag set in the header of encrypted values.
+const encryptedFlag byte = 0x80
+
+// The number of bytes a value may decompress to by default.
+const DefaultDecompressionLimit = 64 << 20
+
+var (
+	// The error returned when a value decompresses to more bytes than allowed.
+	ErrDecompressionLimit = errors.New("cache: the value exceeds the decompression limit")
+	// The error returned when a stored value was not written by a TransformingStore.
+	errUntransformed = errors.New("cache: the value was not written by a transforming store")
+)
+
+// TransformingStore compresses and encrypts the values of another store.
+//
+// Values are serialized, compressed when they exceed the threshold and then
+// encrypted, so the wrapped store only ever sees opaque bytes. A header byte
+// records what was applied, which lets the settings change without breaking
+// the values already stored. The key and the header are authenticated along
+// with encrypted values, so a value copied to another key fails to decrypt.
+//
+// Numbers are neither compressed nor encrypted: they are kept by the wrapped
+// store as they are, so it can count them natively. Do not keep numbers that
+// must stay secret in a transforming store.
+type TransformingStore struct {
+	interactsWithTime
+	// The wrapped cache store.
+	store contracts.Store
+	// The serializer turning values into bytes.
+	serializer contracts.Serializer
+	// The compression algorithm.
+	compression Compression
+	// The number of bytes above which values are compressed.
+	threshold int
+	// The number of bytes a value may decompress to.
+	decompressionLimit int
+	// The encrypter of the values, nil when values are not encrypted.
+	encrypter encryption.Encrypter
+}
+
+// Create a new transforming store around the given store.
+func NewTransformingStore(store contracts.Store) *TransformingStore {
+	return &TransformingStore{store: store, serializer: GobSerializer{}, decompressionLimit: DefaultDecompressionLimit}
+}
+
+// Set the clock the store reads the current time from, the system clock when nil.
+func (s *TransformingStore) SetClock(clock func() time.Time) *TransformingStore {
+	s.clock = clock
+
+	return s
+}
+
+// Compress values larger than the given number of bytes with the given algorithm.
+func (s *TransformingStore) Compress(compression Compression, threshold int) *TransformingStore {
+	s.compression = compression
+	s.threshold = threshold
+
+	return s
+}
+
+// Set the number of bytes a value may decompress to.
+//
+// Values decompressing to more bytes are treated as missing, which keeps a
+// small corrupt or crafted value from exhausting the memory.
+func (s *TransformingStore) LimitDecompression(limit int) *TransformingStore {
+	s.decompressionLimit = limit
+
+	return s
+}
+
+// Encrypt values with the given encrypter.
+//
+// Rotating the key of the encrypter keeps values encrypted with its previous
+// keys readable until they expire.
+func (s *TransformingStore) Encrypt(encrypter encryption.Encrypter) *TransformingStore {
+	s.encrypter = encrypter
+
+	return s
+}
+
+// Set the serializer turning values into bytes.
+func (s *TransformingStore) SetSerializer(serializer contracts.Serializer) *TransformingStore {
+	s.serializer = serializer
+
+	return s
+}
+
+// Retrieve an item from the cache by key.
+func (s *TransformingStore) Get(key string) any {
+	return s.restore(key, s.store.Get(key))
+}
+
+// Retrieve multiple items from the cache by key.
+//
+// Items not found in the cache will have a null value.
+func (s *TransformingStore) Many(keys []string) map[string]any {
+	results := s.store.Many(keys)
+
+	for key, value := range results {
+		results[key] = s.restore(key, value)
+	}
+
+	return results
+}
+
+// Store an item in the cache for a given number of seconds.
+func (s *TransformingStore) Put(key string, value any, seconds int) bool {
+	data, err := s.transform(key, value)
+
+	return err == nil && s.store.Put(key, data, seconds)
+}
+
+// Store multiple items in the cache for a given number of seconds.
+func (s *TransformingStore) PutMany(values map[string]any, seconds int) bool {
+	items, err := s.transformMany(values)
+
+	return err == nil && s.store.PutMany(items, seconds)
+}
+
+// Store an item in the cache for a given duration, keeping sub-second precision.
+func (s *TransformingStore) PutFor(key string, value any, ttl time.Duration) bool {
+	data, err := s.transform(key, value)
+
+	if err != nil {
+		return false
+	}
+
+	if store, ok := s.store.(contracts.PreciseStore); ok {
+		return store.PutFor(key, data, ttl)
+	}
+
+	return s.store.Put(key, data, durationSeconds(ttl))
+}
+
+// Store multiple items in the cache for a given duration, keeping sub-second precision.
+func (s *TransformingStore) PutManyFor(values map[string]any, ttl time.Duration) bool {
+	items, err := s.transformMany(values)
+
+	if err != nil {
+		return false
+	}
+
+	if store, ok := s.store.(contracts.PreciseStore); ok {
+		return store.PutManyFor(items, ttl)
+	}
+
+	return s.store.PutMany(items, durationSeconds(ttl))
+}
+
+// Store an item in the cache if the key does not exist.
+func (s *TransformingStore) Add(key string, value any, ttl ...any) bool {
+	duration, ok := s.ttlDuration(ttl...)
+
+	if !ok {
+		return false
+	}
+
+	data, err := s.transform(key, value)
+
+	if err != nil {
+		return false
+	}
+
+	if store, ok := s.store.(contracts.AtomicStore); ok {
+		return store.Add(key, data, ttl...)
+	}
+
+	if s.store.Get(key) != nil {
+		return false
+	}
+
+	if seconds := durationSeconds(duration); seconds > 0 {
+		return s.store.Put(key, data, seconds)
+	}
+
+	return s.store.Forever(key, data)
+}
+
+// Increment the value of an item in the cache.
+//
+// The counter is kept by the wrapped store in plain text.
+func (s *TransformingStore) Increment(key string, value ...any) any {
+	return s.store.Increment(key, value...)
+}
+
+// Decrement the value of an item in the cache.
+//
+// The counter is kept by the wrapped store in plain text.
+func (s *TransformingStore) Decrement(key string, value ...any) any {
+	return s.store.Decrement(key, value...)
+}
+
+// Add the given step to an integer counter and return the new value.
+//
+// The counter is kept by the wrapped store in plain text.
+func (s *TransformingStore) IncrementBy(key string, value int64) (int64, error) {
+	return incrementStore(s.store, key, value)
+}
+
+// Subtract the given step from an integer counter and return the new value.
+//
+// The counter is kept by the wrapped store in plain text.
+func (s *TransformingStore) DecrementBy(key string, value int64, floor bool) (int64, error) {
+	return decrementStore(s.store, key, value, floor)
+}
+
+// Add the given step to a floating point counter and return the new value.
+//
+// The counter is kept by the wrapped store in plain text.
+func (s *TransformingStore) IncrementFloat(key string, value float64) (float64, error) {
+	return incrementFloatStore(s.store, key, value)
+}
+
+// Store an item in the cache indefinitely.
+func (s *TransformingStore) Forever(key string, value any) bool {
+	data, err := s.transform(key, value)
+
+	return err == nil && s.store.Forever(key, data)
+}
+
+// Remove an item from the cache.
+func (s *TransformingStore) Forget(key string) bool {
+	return s.store.Forget(key)
+}
+
+// Remove all items from the cache.
+func (s *TransformingStore) Flush() bool {
+	return s.store.Flush()
+}
+
+// Begin executing a new tags operation.
+func (s *TransformingStore) Tags(names ...string) contracts.TaggedCache {
+	return NewTaggedCache(s, NewTagSet(s, names))
+}
+
+// Get a lock instance.
+//
+// Locks of the wrapped store are used when it provides them, since lock
+// owners are not worth hiding and the wrapped store knows best how to hold them.
+func (s *TransformingStore) Lock(name string, seconds int, owner ...string) contracts.Lock {
+	if store, ok := s.store.(contracts.LockProvider); ok {
+		return store.Lock(name, seconds, owner...)
+	}
+
+	return NewCacheLock(s, name, seconds, owner...)
+}
+
+// Restore a lock instance using the owner identifier.
+func (s *TransformingStore) RestoreLock(name string, owner string) contracts.Lock {
+	return s.Lock(name, 0, owner)
+}
+
+// Get the cache key prefix.
+func (s *TransformingStore) GetPrefix() string {
+	return s.store.GetPrefix()
+}
+
+// Get the wrapped cache store.
+func (s *TransformingStore) GetStore() contracts.Store {
+	return s.store
+}
+
+// Serialize, compress and encrypt the value of the given key.
+//
+// Numbers are returned as they are, so they can be counted.
+func (s *TransformingStore) transform(key string, value any) (any, error) {
+	if _, ok := toFloat(value); ok {
+		return value, nil
+	}
+
+	data, err := s.serializer.Serialize(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	compression := NoCompression
+
+	if s.compression != NoCompression && len(data) > s.threshold {
+		if data, err = compress(s.compression, data); err != nil {
+			return nil, err
+		}
+
+		compression = s.compression
+	}
+
+	header := byte(compression)
+
+	if s.encrypter != nil {
+		header |= encryptedFlag
+
+		if data, err = s.encrypter.Encrypt(data, additionalData(header, key)); err != nil {
+			return nil, err
+		}
+	}
+
+	return append([]byte{header}, data...), nil
+}
+
+// Transform every value of the given map.
+func (s *TransformingStore) transformMany(values map[string]any) (map[string]any, error) {
+	items := make(map[string]any, len(values))
+
+	for key, value := range values {
+		data, err := s.transform(key, value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		items[key] = data
+	}
+
+	return items, nil
+}
+
+// Restore the value of the given key read from the wrapped store.
+//
+// Values that were not transformed, such as numbers, are returned as they
+// are. Values that cannot be restored, for example because the key that
+// encrypted them is gone, are treated as missing.
+func (s *TransformingStore) restore(key string, value any) any {
+	data, ok := value.([]byte)
+
+	if !ok {
+		return value
+	}
+
+	result, err := s.untransform(key, data)
+
+	if err != nil {
+		return nil
+	}
+
+	return result
+}
+
+// Decrypt, decompress and unserialize the bytes of the given key.
+func (s *TransformingStore) untransform(key string, data []byte) (any, error) {
+	if len(data) == 0 {
+		return nil, errUntransformed
+	}
+
+	header, data := data[0], data[1:]
+
+	var err error
+
+	if header&encryptedFlag != 0 {
+		if s.encrypter == nil {
+			return nil, errUntransformed
+		}
+
+		if data, err = s.encrypter.Decrypt(data, additionalData(header, key)); err != nil {
+			return nil, err
+		}
+	}
+
+	if compression := Compression(header &^ encryptedFlag); compression != NoCompression {
+		if data, err = decompress(compression, data, s.decompressionLimit); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.serializer.Unserialize(data)
+}
+
+// Get the data authenticated along with the encrypted value of the given key.
+func additionalData(header byte, key string) []byte {
+	return append([]byte{header}, key...)
+}
+
+// Compress the given bytes.
+func compress(compression Compression, data []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+	var writer io.WriteCloser
+	var err error
+
+	switch compression {
+	case GzipCompression:
+		writer = gzip.NewWriter(&buffer)
+	case FlateCompression:
+		if writer, err = flate.NewWriter(&buffer, flate.DefaultCompression); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("cache: compression [%d] is not supported", compression)
+	}
+
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// Decompress the given bytes, refusing to produce more than the limit.
+func decompress(compression Compression, data []byte, limit int) ([]byte, error) {
+	var reader io.ReadCloser
+	var err error
+
+	switch compression {
+	case GzipCompression:
+		if reader, err = gzip.NewReader(bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+	case FlateCompression:
+		reader = flate.NewReader(bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("cache: compression [%d] is not supported", compression)
+	}
+
+	defer reader.Close()
+
+	data, err = io.ReadAll(io.LimitReader(reader, int64(limit)+1))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > limit {
+		return nil, ErrDecompressionLimit
+	}
+
+	return data, nil
+}
@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"encoding/gob"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// TypeRegistry names the types values are decoded into.
+//
+// Serializers write the name of the type next to the value, so a value
+// stored as a registered type decodes back into that type instead of into
+// the generic maps and slices of the encoding.
+type TypeRegistry struct {
+	// The registered types by name.
+	types map[string]reflect.Type
+	// The names of the registered types.
+	names map[reflect.Type]string
+	// Guards the registered types.
+	mutex sync.RWMutex
+}
+
+// The registry used by serializers that were not given one.
+var DefaultTypeRegistry = NewTypeRegistry()
+
+// Create a new type registry holding the builtin types.
+func NewTypeRegistry() *TypeRegistry {
+	registry := &TypeRegistry{types: make(map[string]reflect.Type), names: make(map[reflect.Type]string)}
+
+	for _, value := range []any{
+		false, "", []byte(nil),
+		int(0), int8(0), int16(0), int32(0), int64(0),
+		uint(0), uint8(0), uint16(0), uint32(0), uint64(0),
+		float32(0), float64(0),
+		time.Time{}, time.Duration(0),
+		[]any(nil), []string(nil), []int(nil),
+		map[string]any(nil), map[string]string(nil), map[string]int(nil),
+	} {
+		if err := registry.Register(value); err != nil {
+			panic(err)
+		}
+	}
+
+	return registry
+}
+
+// Register the type of the given value.
+//
+// The name defaults to the name gob gives the type, such as "main.User" or
+// "*main.User". The type is registered with gob as well, so the gob
+// serializer can decode it from an interface, which fails when gob already
+// knows the type by another name.
+func (r *TypeRegistry) Register(value any, name ...string) error {
+	t := reflect.TypeOf(value)
+
+	if t == nil {
+		return nil
+	}
+
+	typeName := gobName(t)
+
+	if name != nil && name[0] != "" {
+		typeName = name[0]
+	}
+
+	if err := registerGob(t); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.types[typeName] = t
+	r.names[t] = typeName
+
+	return nil
+}
+
+// Get the name the given type is registered under.
+func (r *TypeRegistry) NameOf(t reflect.Type) (string, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	name, ok := r.names[t]
+
+	return name, ok
+}
+
+// Get the type registered under the given name.
+func (r *TypeRegistry) TypeOf(name string) (reflect.Type, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	t, ok := r.types[name]
+
+	return t, ok
+}
+
+// Get the name the type of the given value is registered under, if any.
+func (r *TypeRegistry) nameOfValue(value any) string {
+	if value == nil {
+		return ""
+	}
+
+	name, _ := r.NameOf(reflect.TypeOf(value))
+
+	return name
+}
+
+// Create a pointer to a new value of the type registered under the given name.
+//
+// An empty name creates a pointer to an interface.
+func (r *TypeRegistry) target(name string) (reflect.Value, error) {
+	if name == "" {
+		var value any
+
+		return reflect.ValueOf(&value), nil
+	}
+
+	t, ok := r.TypeOf(name)
+
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("cache: type [%s] is not registered", name)
+	}
+
+	return reflect.New(t), nil
+}
+
+// Register the type of the given value with the default type registry.
+func RegisterType(value any, name ...string) error {
+	return DefaultTypeRegistry.Register(value, name...)
+}
+
+// Get the name gob.Register gives the given type.
+func gobName(t reflect.Type) string {
+	name := t.String()
+	star := ""
+
+	if t.Name() == "" && t.Kind() == reflect.Pointer {
+		star = "*"
+		t = t.Elem()
+	}
+
+	if t.Name() != "" {
+		if t.PkgPath() == "" {
+			name = star + t.Name()
+		} else {
+			name = star + t.PkgPath() + "." + t.Name()
+		}
+	}
+
+	return name
+}
+
+// Register the given type with gob.
+//
+// Gob flattens pointers, so pointer types are registered as the type they
+// point to, which lets a type and a pointer to it both be registered.
+// gob.Register panics on conflicting registrations, which is turned into an error.
+func registerGob(t reflect.Type) (err error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("cache: type [%s] cannot be registered: %v", t, recovered)
+		}
+	}()
+
+	gob.Register(reflect.Zero(t).Interface())
+
+	return nil
+}
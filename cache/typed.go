@@ -0,0 +1,254 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	contracts "github.com/garavel-core/framework/contracts/cache"
+)
+
+// The error returned when a cached item was not written by a typed cache.
+var ErrNotEncoded = errors.New("cache: the item is not an encoded value")
+
+// The result of a callback remembered by a typed cache.
+type typedResult[T any] struct {
+	// The value returned by the callback.
+	value T
+	// The encoded value.
+	data []byte
+	// The error returned by the callback or the serializer.
+	err error
+}
+
+type Typed[T any] struct {
+	// The cache repository instance.
+	repository *Repository
+	// The serializer encoding the values.
+	serializer contracts.Serializer
+}
+
+// Create a new typed cache over the given repository.
+//
+// Values are encoded with the gob serializer unless another serializer is
+// given. T is registered with the default type registry, serializers using
+// another registry need it registered with theirs.
+func NewTyped[T any](repository *Repository, serializer ...contracts.Serializer) (*Typed[T], error) {
+	var zero T
+
+	if err := RegisterType(zero); err != nil {
+		return nil, err
+	}
+
+	typed := &Typed[T]{repository: repository, serializer: GobSerializer{}}
+
+	if serializer != nil && serializer[0] != nil {
+		typed.serializer = serializer[0]
+	}
+
+	return typed, nil
+}
+
+// Determine if an item exists in the cache.
+func (t *Typed[T]) Has(key string) bool {
+	return t.repository.Has(key)
+}
+
+// Retrieve an item from the cache by key.
+//
+// The boolean reports whether the item was found. Items that cannot be
+// decoded into T are reported as found along with the decoding error.
+func (t *Typed[T]) Get(key string) (T, bool, error) {
+	value := t.repository.Get(key)
+
+	if value == nil {
+		var zero T
+
+		return zero, false, nil
+	}
+
+	decoded, err := t.decode(key, value)
+
+	return decoded, true, err
+}
+
+// Retrieve multiple items from the cache by key.
+//
+// Items not found in the cache are left out of the result.
+func (t *Typed[T]) Many(keys []string) (map[string]T, error) {
+	results := make(map[string]T, len(keys))
+
+	for key, value := range t.repository.Many(keys) {
+		if value == nil {
+			continue
+		}
+
+		decoded, err := t.decode(key, value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		results[key] = decoded
+	}
+
+	return results, nil
+}
+
+// Retrieve an item from the cache and delete it.
+func (t *Typed[T]) Pull(key string) (T, bool, error) {
+	value, found, err := t.Get(key)
+
+	if found {
+		t.repository.Forget(key)
+	}
+
+	return value, found, err
+}
+
+// Store an item in the cache.
+func (t *Typed[T]) Put(key string, value T, ttl ...any) (bool, error) {
+	data, err := t.serializer.Serialize(value)
+
+	if err != nil {
+		return false, err
+	}
+
+	return t.repository.Put(key, data, ttl...), nil
+}
+
+// Store multiple items in the cache.
+func (t *Typed[T]) PutMany(values map[string]T, ttl ...any) (bool, error) {
+	items := make(map[string]any, len(values))
+
+	for key, value := range values {
+		data, err := t.serializer.Serialize(value)
+
+		if err != nil {
+			return false, err
+		}
+
+		items[key] = data
+	}
+
+	return t.repository.PutMany(items, ttl...), nil
+}
+
+// Store an item in the cache if the key does not exist.
+func (t *Typed[T]) Add(key string, value T, ttl ...any) (bool, error) {
+	data, err := t.serializer.Serialize(value)
+
+	if err != nil {
+		return false, err
+	}
+
+	return t.repository.Add(key, data, ttl...), nil
+}
+
+// Store an item in the cache indefinitely.
+func (t *Typed[T]) Forever(key string, value T) (bool, error) {
+	data, err := t.serializer.Serialize(value)
+
+	if err != nil {
+		return false, err
+	}
+
+	return t.repository.Forever(key, data), nil
+}
+
+// Get an item from the cache, or execute the given callback and store the result.
+//
+// Nothing is stored when the callback returns an error. Items that cannot be
+// decoded are treated as missing and computed again.
+func (t *Typed[T]) Remember(key string, ttl any, callback func() (T, error)) (T, error) {
+	return t.remember(key, callback, func(data []byte) {
+		t.repository.Put(key, data, ttl)
+	})
+}
+
+// Get an item from the cache, or execute the given callback and store the result forever.
+func (t *Typed[T]) RememberForever(key string, callback func() (T, error)) (T, error) {
+	return t.remember(key, callback, func(data []byte) {
+		t.repository.Forever(key, data)
+	})
+}
+
+// Get an item from the cache, or execute the callback and store its encoded result.
+func (t *Typed[T]) remember(key string, callback func() (T, error), store func(data []byte)) (T, error) {
+	if value, found, err := t.Get(key); found && err == nil {
+		return value, nil
+	}
+
+	// The callback runs through the repository so concurrent misses are still
+	// coalesced, which means the result may be shared with other callers or
+	// be the encoded item another process stored while we held no lock.
+	result := t.repository.remember(key, func() any {
+		value, err := callback()
+
+		if err != nil {
+			return typedResult[T]{value: value, err: err}
+		}
+
+		data, err := t.serializer.Serialize(value)
+
+		return typedResult[T]{value: value, data: data, err: err}
+	}, func(result any) {
+		if result, ok := result.(typedResult[T]); ok && result.err == nil {
+			store(result.data)
+		}
+	})
+
+	if result, ok := result.(typedResult[T]); ok {
+		return result.value, result.err
+	}
+
+	return t.decode(key, result)
+}
+
+// Remove an item from the cache.
+func (t *Typed[T]) Forget(key string) bool {
+	return t.repository.Forget(key)
+}
+
+// Get the underlying cache repository.
+func (t *Typed[T]) GetRepository() *Repository {
+	return t.repository
+}
+
+// Decode a stored item into a value of the typed cache.
+func (t *Typed[T]) decode(key string, value any) (T, error) {
+	var decoded T
+
+	data, ok := value.([]byte)
+
+	if !ok {
+		return decoded, fmt.Errorf("%w: [%s] holds a %T", ErrNotEncoded, key, value)
+	}
+
+	unserialized, err := t.serializer.Unserialize(data)
+
+	if err != nil {
+		return decoded, fmt.Errorf("cache: decoding [%s]: %w", key, err)
+	}
+
+	if unserialized == nil {
+		return decoded, nil
+	}
+
+	if decoded, ok := unserialized.(T); ok {
+		return decoded, nil
+	}
+
+	// Serializers flattening pointers return the value a pointer pointed to.
+	target := reflect.ValueOf(&decoded).Elem()
+
+	if target.Kind() == reflect.Pointer && reflect.TypeOf(unserialized) == target.Type().Elem() {
+		pointer := reflect.New(target.Type().Elem())
+		pointer.Elem().Set(reflect.ValueOf(unserialized))
+		target.Set(pointer)
+
+		return decoded, nil
+	}
+
+	return decoded, fmt.Errorf("cache: decoding [%s]: got a %T", key, unserialized)
+}
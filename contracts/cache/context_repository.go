@@ -0,0 +1,47 @@
+package cache
+
+import "context"
+
+type ContextRepository interface {
+	// Determine if an item exists in the cache.
+	HasCtx(ctx context.Context, key string) (bool, error)
+
+	// Retrieve an item from the cache by key, reporting whether it was found.
+	GetCtx(ctx context.Context, key string) (any, bool, error)
+
+	// Retrieve multiple items from the cache by key, missing items have a nil value.
+	ManyCtx(ctx context.Context, keys []string) (map[string]any, error)
+
+	// Retrieve an item from the cache and delete it.
+	PullCtx(ctx context.Context, key string) (any, bool, error)
+
+	// Store an item in the cache.
+	PutCtx(ctx context.Context, key string, value any, ttl ...any) error
+
+	// Store multiple items in the cache.
+	PutManyCtx(ctx context.Context, values map[string]any, ttl ...any) error
+
+	// Store an item in the cache if the key does not exist, reporting whether it was stored.
+	AddCtx(ctx context.Context, key string, value any, ttl ...any) (bool, error)
+
+	// Increment the value of an item in the cache and return the new value.
+	IncrementCtx(ctx context.Context, key string, value int64) (int64, error)
+
+	// Decrement the value of an item in the cache and return the new value.
+	DecrementCtx(ctx context.Context, key string, value int64) (int64, error)
+
+	// Store an item in the cache indefinitely.
+	ForeverCtx(ctx context.Context, key string, value any) error
+
+	// Get an item from the cache, or execute the given callback and store the result.
+	RememberCtx(ctx context.Context, key string, ttl any, callback func(ctx context.Context) (any, error)) (any, error)
+
+	// Get an item from the cache, or execute the given callback and store the result forever.
+	RememberForeverCtx(ctx context.Context, key string, callback func(ctx context.Context) (any, error)) (any, error)
+
+	// Remove an item from the cache, reporting whether it existed.
+	ForgetCtx(ctx context.Context, key string) (bool, error)
+
+	// Wipes clean the entire cache's keys.
+	ClearCtx(ctx context.Context) error
+}
@@ -0,0 +1,41 @@
+package cache
+
+import "context"
+
+type ContextStore interface {
+	// Retrieve an item from the cache by key, reporting whether it was found.
+	GetCtx(ctx context.Context, key string) (any, bool, error)
+
+	// Retrieve multiple items from the cache by key, missing items have a nil value.
+	ManyCtx(ctx context.Context, keys []string) (map[string]any, error)
+
+	// Store an item in the cache for a given number of seconds.
+	PutCtx(ctx context.Context, key string, value any, seconds int) error
+
+	// Store multiple items in the cache for a given number of seconds.
+	PutManyCtx(ctx context.Context, values map[string]any, seconds int) error
+
+	// Store an item in the cache if the key does not exist, reporting whether it was stored.
+	AddCtx(ctx context.Context, key string, value any, seconds int) (bool, error)
+
+	// Increment the value of an item in the cache and return the new value.
+	//
+	// A missing item is not created, the ErrMissing error of the cache
+	// package is returned instead.
+	IncrementCtx(ctx context.Context, key string, value int64) (int64, error)
+
+	// Decrement the value of an item in the cache and return the new value.
+	//
+	// A missing item is not created, the ErrMissing error of the cache
+	// package is returned instead.
+	DecrementCtx(ctx context.Context, key string, value int64) (int64, error)
+
+	// Store an item in the cache indefinitely.
+	ForeverCtx(ctx context.Context, key string, value any) error
+
+	// Remove an item from the cache, reporting whether it existed.
+	ForgetCtx(ctx context.Context, key string) (bool, error)
+
+	// Remove all items from the cache.
+	FlushCtx(ctx context.Context) error
+}
@@ -0,0 +1,12 @@
+package cache
+
+type CounterStore interface {
+	// Add the given step to an integer counter and return the new value.
+	IncrementBy(key string, value int64) (int64, error)
+
+	// Subtract the given step from an integer counter, stopping at zero when floor is set.
+	DecrementBy(key string, value int64, floor bool) (int64, error)
+
+	// Add the given step to a floating point counter and return the new value.
+	IncrementFloat(key string, value float64) (float64, error)
+}
@@ -0,0 +1,6 @@
+package cache
+
+type KeyLimitedStore interface {
+	// Get the maximum length of the keys the store accepts, zero when unlimited.
+	MaxKeyLength() int
+}
@@ -0,0 +1,23 @@
+package cache
+
+type Lock interface {
+	// Attempt to acquire the lock.
+	//
+	// Without a callback the result tells whether the lock was acquired. With a
+	// callback the callback runs while the lock is held, the lock is released
+	// afterwards and the result of the callback is returned, or false when the
+	// lock could not be acquired.
+	Get(callback ...func() any) any
+
+	// Attempt to acquire the lock for the given number of seconds.
+	Block(seconds int, callback ...func() any) (any, error)
+
+	// Release the lock.
+	Release() bool
+
+	// Returns the current owner of the lock.
+	Owner() string
+
+	// Releases this lock in disregard of ownership.
+	ForceRelease()
+}
@@ -0,0 +1,9 @@
+package cache
+
+type LockProvider interface {
+	// Get a lock instance.
+	Lock(name string, seconds int, owner ...string) Lock
+
+	// Restore a lock instance using the owner identifier.
+	RestoreLock(name string, owner string) Lock
+}
@@ -0,0 +1,6 @@
+package cache
+
+type PingableStore interface {
+	// Check that the backend of the store is reachable.
+	Ping() error
+}
@@ -0,0 +1,13 @@
+package cache
+
+import (
+	"time"
+)
+
+type PreciseStore interface {
+	// Store an item in the cache for a given duration, keeping sub-second precision.
+	PutFor(key string, value any, ttl time.Duration) bool
+
+	// Store multiple items in the cache for a given duration, keeping sub-second precision.
+	PutManyFor(values map[string]any, ttl time.Duration) bool
+}
@@ -0,0 +1,9 @@
+package cache
+
+type Serializer interface {
+	// Generate a storable representation of a value.
+	Serialize(value any) ([]byte, error)
+
+	// Create a value from a stored representation.
+	Unserialize(data []byte) (any, error)
+}
@@ -2,5 +2,5 @@ package cache
 
 type TaggableStore interface {
 	// Begin executing a new tags operation.
-	Tags(names ...string)
+	Tags(names ...string) TaggedCache
 }
@@ -0,0 +1,8 @@
+package cache
+
+type TaggedCache interface {
+	Repository
+
+	// Remove all items carrying the tags from the cache.
+	Flush() bool
+}
@@ -0,0 +1,18 @@
+package encryption
+
+type Encrypter interface {
+	// Encrypt the given value, authenticating the additional data when given.
+	Encrypt(value []byte, additionalData ...[]byte) ([]byte, error)
+
+	// Decrypt the given value, which must have been encrypted with the same additional data.
+	Decrypt(payload []byte, additionalData ...[]byte) ([]byte, error)
+
+	// Get the encryption key that the encrypter is currently using.
+	GetKey() []byte
+
+	// Get the current encryption key and all previous encryption keys.
+	GetAllKeys() [][]byte
+
+	// Get the previous encryption keys.
+	GetPreviousKeys() [][]byte
+}
@@ -0,0 +1,31 @@
+package events
+
+// A callback handling a dispatched event.
+//
+// Listeners receive the name of the event and its payload. When an event
+// object is dispatched it is the only element of the payload. Returning false
+// stops the event from propagating to the remaining listeners.
+type Listener func(event string, payload ...any) any
+
+type Dispatcher interface {
+	// Register an event listener with the dispatcher.
+	//
+	// The events may be a single name or a slice of names, and each name may
+	// contain "*" wildcards.
+	Listen(events any, listener Listener)
+
+	// Determine if a given event has listeners.
+	HasListeners(eventName string) bool
+
+	// Register an event subscriber with the dispatcher.
+	Subscribe(subscriber Subscriber)
+
+	// Dispatch an event until the first non-null response is returned.
+	Until(event any, payload ...any) any
+
+	// Dispatch an event and call the listeners.
+	Dispatch(event any, payload ...any) []any
+
+	// Remove a set of listeners from the dispatcher.
+	Forget(event string)
+}
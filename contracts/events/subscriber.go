@@ -0,0 +1,6 @@
+package events
+
+type Subscriber interface {
+	// Register the listeners for the subscriber.
+	Subscribe(events Dispatcher)
+}
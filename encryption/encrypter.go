@@ -0,0 +1,147 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The error returned when a payload cannot be decrypted with any of the keys.
+var ErrDecrypt = errors.New("encryption: the payload could not be decrypted")
+
+// Encrypter encrypts values with AES-GCM.
+//
+// Payloads hold the random nonce followed by the sealed value, whose
+// authentication tag makes tampered payloads fail to decrypt. Additional data,
+// such as the name the payload is stored under, is authenticated but not
+// stored, so a payload only decrypts with the data it was encrypted with.
+type Encrypter struct {
+	// The encryption key.
+	key []byte
+	// The previous keys that are still accepted for decryption.
+	previousKeys [][]byte
+	// The ciphers of the current key followed by the previous keys.
+	ciphers []cipher.AEAD
+}
+
+// Create a new encrypter instance.
+//
+// Keys must be 16, 24 or 32 bytes long, selecting AES-128, AES-192 or
+// AES-256. Values are always encrypted with the given key, while payloads
+// encrypted with any of the previous keys can still be decrypted, which lets
+// the key be rotated without losing what was encrypted before.
+func NewEncrypter(key []byte, previousKeys ...[]byte) (*Encrypter, error) {
+	encrypter := &Encrypter{key: key, previousKeys: previousKeys}
+
+	for _, key := range encrypter.GetAllKeys() {
+		aead, err := newCipher(key)
+
+		if err != nil {
+			return nil, err
+		}
+
+		encrypter.ciphers = append(encrypter.ciphers, aead)
+	}
+
+	return encrypter, nil
+}
+
+// Create a new AES-GCM cipher for the given key.
+func newCipher(key []byte) (cipher.AEAD, error) {
+	if !Supported(key) {
+		return nil, fmt.Errorf("encryption: keys must be 16, 24 or 32 bytes long, got %d", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// Determine if the given key is valid.
+func Supported(key []byte) bool {
+	switch len(key) {
+	case 16, 24, 32:
+		return true
+	}
+
+	return false
+}
+
+// Create a new random encryption key of the given number of bytes.
+func GenerateKey(size ...int) ([]byte, error) {
+	key := make([]byte, 32)
+
+	if size != nil {
+		key = make([]byte, size[0])
+	}
+
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// Encrypt the given value, authenticating the additional data when given.
+func (e *Encrypter) Encrypt(value []byte, additionalData ...[]byte) ([]byte, error) {
+	aead := e.ciphers[0]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(value)+aead.Overhead())
+
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, value, firstData(additionalData)), nil
+}
+
+// Decrypt the given value, which must have been encrypted with the same additional data.
+//
+// The current key is tried first, followed by the previous keys in order.
+func (e *Encrypter) Decrypt(payload []byte, additionalData ...[]byte) ([]byte, error) {
+	data := firstData(additionalData)
+
+	for _, aead := range e.ciphers {
+		if len(payload) < aead.NonceSize()+aead.Overhead() {
+			continue
+		}
+
+		nonce, sealed := payload[:aead.NonceSize()], payload[aead.NonceSize():]
+
+		if value, err := aead.Open(nil, nonce, sealed, data); err == nil {
+			return value, nil
+		}
+	}
+
+	return nil, ErrDecrypt
+}
+
+// Get the additional data passed to Encrypt or Decrypt, nil when none was.
+func firstData(additionalData [][]byte) []byte {
+	if additionalData == nil {
+		return nil
+	}
+
+	return additionalData[0]
+}
+
+// Get the encryption key that the encrypter is currently using.
+func (e *Encrypter) GetKey() []byte {
+	return e.key
+}
+
+// Get the current encryption key and all previous encryption keys.
+func (e *Encrypter) GetAllKeys() [][]byte {
+	return append([][]byte{e.key}, e.previousKeys...)
+}
+
+// Get the previous encryption keys.
+func (e *Encrypter) GetPreviousKeys() [][]byte {
+	return e.previousKeys
+}
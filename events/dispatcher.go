@@ -0,0 +1,235 @@
+package events
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	contracts "github.com/garavel-core/framework/contracts/events"
+	"github.com/garavel-core/framework/support/str"
+)
+
+type Dispatcher struct {
+	// The registered event listeners.
+	listeners map[string][]contracts.Listener
+	// The wildcard listeners.
+	wildcards map[string][]contracts.Listener
+	// The cached wildcard listeners.
+	wildcardsCache map[string][]contracts.Listener
+	// Guards the listeners against concurrent registration and dispatching.
+	mutex sync.RWMutex
+}
+
+// Create a new event dispatcher instance.
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{
+		listeners:      make(map[string][]contracts.Listener),
+		wildcards:      make(map[string][]contracts.Listener),
+		wildcardsCache: make(map[string][]contracts.Listener),
+	}
+}
+
+// Register an event listener with the dispatcher.
+func (d *Dispatcher) Listen(events any, listener contracts.Listener) {
+	var names []string
+
+	switch value := events.(type) {
+	case string:
+		names = []string{value}
+	case []string:
+		names = value
+	default:
+		names = []string{Name(events)}
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for _, event := range names {
+		if strings.Contains(event, "*") {
+			d.setupWildcardListen(event, listener)
+		} else {
+			d.listeners[event] = append(d.listeners[event], listener)
+		}
+	}
+}
+
+// Setup a wildcard listener callback.
+func (d *Dispatcher) setupWildcardListen(event string, listener contracts.Listener) {
+	d.wildcards[event] = append(d.wildcards[event], listener)
+
+	d.wildcardsCache = make(map[string][]contracts.Listener)
+}
+
+// Determine if a given event has listeners.
+func (d *Dispatcher) HasListeners(eventName string) bool {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	return len(d.listeners[eventName]) != 0 || len(d.wildcards[eventName]) != 0 || d.hasWildcardListeners(eventName)
+}
+
+// Determine if the given event has any wildcard listeners.
+func (d *Dispatcher) hasWildcardListeners(eventName string) bool {
+	for key := range d.wildcards {
+		if str.Is(key, eventName) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Register an event subscriber with the dispatcher.
+func (d *Dispatcher) Subscribe(subscriber contracts.Subscriber) {
+	subscriber.Subscribe(d)
+}
+
+// Fire an event until the first non-null response is returned.
+func (d *Dispatcher) Until(event any, payload ...any) any {
+	responses := d.dispatch(event, payload, true)
+
+	if len(responses) == 0 {
+		return nil
+	}
+
+	return responses[0]
+}
+
+// Fire an event and call the listeners.
+func (d *Dispatcher) Dispatch(event any, payload ...any) []any {
+	return d.dispatch(event, payload, false)
+}
+
+// Fire an event and call the listeners, optionally halting on the first response.
+func (d *Dispatcher) dispatch(event any, payload []any, halt bool) []any {
+	// When the given "event" is actually an object we will assume it is an event
+	// object and use the type as the event name and this event itself as the
+	// payload to the handler, which makes object based events quite simple.
+	name, payload := d.parseEventAndPayload(event, payload)
+
+	var responses []any
+
+	for _, listener := range d.GetListeners(name) {
+		response := listener(name, payload...)
+
+		// If a response is returned from the listener and event halting is enabled
+		// we will just return this response, and not call the rest of the event
+		// listeners. Otherwise we will add the response on the response list.
+		if halt && response != nil {
+			return []any{response}
+		}
+
+		// If a boolean false is returned from a listener, we will stop propagating
+		// the event to any further listeners down in the chain, else we keep on
+		// looping through the listeners and firing every one in our sequence.
+		if response == false {
+			break
+		}
+
+		responses = append(responses, response)
+	}
+
+	if halt {
+		return nil
+	}
+
+	return responses
+}
+
+// Parse the given event and payload and prepare them for dispatching.
+func (d *Dispatcher) parseEventAndPayload(event any, payload []any) (string, []any) {
+	if name, ok := event.(string); ok {
+		return name, payload
+	}
+
+	return Name(event), []any{event}
+}
+
+// Get all of the listeners for a given event name.
+func (d *Dispatcher) GetListeners(eventName string) []contracts.Listener {
+	d.mutex.RLock()
+	listeners := append([]contracts.Listener(nil), d.listeners[eventName]...)
+	wildcards, cached := d.wildcardsCache[eventName]
+	d.mutex.RUnlock()
+
+	if !cached {
+		wildcards = d.getWildcardListeners(eventName)
+	}
+
+	return append(listeners, wildcards...)
+}
+
+// Get the wildcard listeners for the event.
+func (d *Dispatcher) getWildcardListeners(eventName string) []contracts.Listener {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	var wildcards []contracts.Listener
+
+	for key, listeners := range d.wildcards {
+		if str.Is(key, eventName) {
+			wildcards = append(wildcards, listeners...)
+		}
+	}
+
+	d.wildcardsCache[eventName] = wildcards
+
+	return wildcards
+}
+
+// Remove a set of listeners from the dispatcher.
+func (d *Dispatcher) Forget(event string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if strings.Contains(event, "*") {
+		delete(d.wildcards, event)
+	} else {
+		delete(d.listeners, event)
+	}
+
+	for key := range d.wildcardsCache {
+		if str.Is(event, key) {
+			delete(d.wildcardsCache, key)
+		}
+	}
+}
+
+// Get the name an event object is dispatched under.
+//
+// The name is the import path of the package and the name of the type, such
+// as "github.com/garavel-core/framework/cache.CacheHit". Pointers are named
+// after the type they point to.
+func Name(event any) string {
+	t := reflect.TypeOf(event)
+
+	if t == nil {
+		return ""
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.PkgPath() == "" {
+		return t.String()
+	}
+
+	return t.PkgPath() + "." + t.Name()
+}
+
+// Register a listener for the event objects of the given type.
+func ListenTo[T any](dispatcher contracts.Dispatcher, listener func(event T)) {
+	var event T
+
+	dispatcher.Listen(Name(&event), func(_ string, payload ...any) any {
+		if len(payload) != 0 {
+			if value, ok := payload[0].(T); ok {
+				listener(value)
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,642 @@
+package msgpack
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// The error returned when the data ends in the middle of a value.
+var ErrShortData = errors.New("msgpack: unexpected end of data")
+
+// The error returned when arrays and maps are nested deeper than MaxDepth.
+var ErrTooDeep = errors.New("msgpack: maximum nesting depth exceeded")
+
+// The number of arrays and maps a value may be nested in.
+const MaxDepth = 1000
+
+type decoder struct {
+	data []byte
+	pos  int
+	// The number of arrays and maps the current value is nested in.
+	depth int
+}
+
+// Decode the given MessagePack data into the value the target points to.
+//
+// Decoding into an interface produces nil, bool, int64, float32, float64,
+// string, []byte, time.Time, []any and map[string]any values, or map[any]any
+// for maps with keys other than strings. Integers beyond the range of int64
+// decode into uint64.
+func Unmarshal(data []byte, target any) error {
+	v := reflect.ValueOf(target)
+
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return fmt.Errorf("msgpack: cannot decode into %T", target)
+	}
+
+	d := &decoder{data: data}
+
+	value, err := d.decode()
+
+	if err != nil {
+		return err
+	}
+
+	if d.pos != len(d.data) {
+		return fmt.Errorf("msgpack: %d trailing bytes", len(d.data)-d.pos)
+	}
+
+	return assign(v.Elem(), value)
+}
+
+// Decode the next value.
+func (d *decoder) decode() (any, error) {
+	b, err := d.readByte()
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case b <= 0x7f:
+		return int64(b), nil
+	case b >= 0xe0:
+		return int64(int8(b)), nil
+	case b&0xf0 == 0x80:
+		return d.decodeMap(int(b & 0x0f))
+	case b&0xf0 == 0x90:
+		return d.decodeArray(int(b & 0x0f))
+	case b&0xe0 == 0xa0:
+		return d.readString(int(b & 0x1f))
+	}
+
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.readLength(b - 0xc4)
+
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := d.read(n)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return append([]byte(nil), data...), nil
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.readLength(b - 0xc7)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return d.decodeExtension(n)
+	case 0xca:
+		u, err := d.readUint(4)
+
+		return math.Float32frombits(uint32(u)), err
+	case 0xcb:
+		u, err := d.readUint(8)
+
+		return math.Float64frombits(u), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := d.readUint(1 << (b - 0xcc))
+
+		// Unsigned integers decode into int64 like every other integer, unless
+		// they are too large to fit, so equal numbers decode into equal values.
+		if u > math.MaxInt64 {
+			return u, err
+		}
+
+		return int64(u), err
+	case 0xd0:
+		u, err := d.readUint(1)
+
+		return int64(int8(u)), err
+	case 0xd1:
+		u, err := d.readUint(2)
+
+		return int64(int16(u)), err
+	case 0xd2:
+		u, err := d.readUint(4)
+
+		return int64(int32(u)), err
+	case 0xd3:
+		u, err := d.readUint(8)
+
+		return int64(u), err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.decodeExtension(1 << (b - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.readLength(b - 0xd9)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return d.readString(n)
+	case 0xdc, 0xdd:
+		n, err := d.readLength(b - 0xdc + 1)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return d.decodeArray(n)
+	case 0xde, 0xdf:
+		n, err := d.readLength(b - 0xde + 1)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return d.decodeMap(n)
+	}
+
+	return nil, fmt.Errorf("msgpack: invalid code %#x", b)
+}
+
+// Decode an array with the given number of elements.
+func (d *decoder) decodeArray(n int) (any, error) {
+	// Every element takes at least a byte, so a length beyond the remaining
+	// data is corrupt and must not be trusted with an allocation.
+	if err := d.enter(n); err != nil {
+		return nil, err
+	}
+
+	defer d.leave()
+
+	values := make([]any, n)
+
+	for i := range values {
+		value, err := d.decode()
+
+		if err != nil {
+			return nil, err
+		}
+
+		values[i] = value
+	}
+
+	return values, nil
+}
+
+// Decode a map with the given number of entries.
+//
+// Maps with string keys only, which is what structs encode into, decode into
+// map[string]any and every other map into map[any]any.
+func (d *decoder) decodeMap(n int) (any, error) {
+	if err := d.enter(2 * n); err != nil {
+		return nil, err
+	}
+
+	defer d.leave()
+
+	keys := make([]any, n)
+	values := make([]any, n)
+	stringKeys := true
+
+	for i := 0; i < n; i++ {
+		key, err := d.decode()
+
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := d.decode()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := key.(string); !ok {
+			stringKeys = false
+		}
+
+		keys[i], values[i] = key, value
+	}
+
+	if stringKeys {
+		result := make(map[string]any, n)
+
+		for i, key := range keys {
+			result[key.(string)] = values[i]
+		}
+
+		return result, nil
+	}
+
+	result := make(map[any]any, n)
+
+	for i, key := range keys {
+		if key != nil && !reflect.TypeOf(key).Comparable() {
+			return nil, fmt.Errorf("msgpack: unhashable map key of type %T", key)
+		}
+
+		result[key] = values[i]
+	}
+
+	return result, nil
+}
+
+// Enter an array or map whose values take at least the given number of bytes.
+func (d *decoder) enter(size int) error {
+	if size < 0 || size > len(d.data)-d.pos {
+		return ErrShortData
+	}
+
+	if d.depth >= MaxDepth {
+		return ErrTooDeep
+	}
+
+	d.depth++
+
+	return nil
+}
+
+// Leave the array or map being decoded.
+func (d *decoder) leave() {
+	d.depth--
+}
+
+// Decode an extension with the given data length.
+func (d *decoder) decodeExtension(n int) (any, error) {
+	kind, err := d.readByte()
+
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := d.read(n)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if int8(kind) != timestampExtension {
+		return nil, fmt.Errorf("msgpack: unsupported extension type %d", int8(kind))
+	}
+
+	switch n {
+	case 4:
+		return time.Unix(int64(bigEndian(data)), 0), nil
+	case 8:
+		u := bigEndian(data)
+
+		return time.Unix(int64(u&0x3ffffffff), int64(u>>34)), nil
+	case 12:
+		return time.Unix(int64(bigEndian(data[4:])), int64(bigEndian(data[:4]))), nil
+	}
+
+	return nil, fmt.Errorf("msgpack: invalid timestamp length %d", n)
+}
+
+// Read a length of 1, 2 or 4 bytes, given as the power of two of its size.
+func (d *decoder) readLength(size byte) (int, error) {
+	u, err := d.readUint(1 << size)
+
+	return int(u), err
+}
+
+// Read a big-endian unsigned integer of the given number of bytes.
+func (d *decoder) readUint(n int) (uint64, error) {
+	data, err := d.read(n)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return bigEndian(data), nil
+}
+
+// Read a string of the given length.
+func (d *decoder) readString(n int) (string, error) {
+	data, err := d.read(n)
+
+	return string(data), err
+}
+
+func (d *decoder) readByte() (byte, error) {
+	data, err := d.read(1)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return data[0], nil
+}
+
+func (d *decoder) read(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.pos < n {
+		return nil, ErrShortData
+	}
+
+	data := d.data[d.pos : d.pos+n]
+	d.pos += n
+
+	return data, nil
+}
+
+func bigEndian(data []byte) uint64 {
+	var u uint64
+
+	for _, b := range data {
+		u = u<<8 | uint64(b)
+	}
+
+	return u
+}
+
+// Assign a decoded value to the destination, converting it to its type.
+func assign(dst reflect.Value, src any) error {
+	if src == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+
+		return nil
+	}
+
+	if dst.Type() == timeType {
+		if t, ok := src.(time.Time); ok {
+			dst.Set(reflect.ValueOf(t))
+
+			return nil
+		}
+
+		return mismatch(dst, src)
+	}
+
+	switch dst.Kind() {
+	case reflect.Interface:
+		value := reflect.ValueOf(src)
+
+		if !value.Type().AssignableTo(dst.Type()) {
+			return mismatch(dst, src)
+		}
+
+		dst.Set(value)
+	case reflect.Pointer:
+		value := reflect.New(dst.Type().Elem())
+
+		if err := assign(value.Elem(), src); err != nil {
+			return err
+		}
+
+		dst.Set(value)
+	case reflect.Bool:
+		b, ok := src.(bool)
+
+		if !ok {
+			return mismatch(dst, src)
+		}
+
+		dst.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+
+		switch n := src.(type) {
+		case int64:
+			i = n
+		case uint64:
+			if n > math.MaxInt64 {
+				return mismatch(dst, src)
+			}
+
+			i = int64(n)
+		default:
+			return mismatch(dst, src)
+		}
+
+		if dst.OverflowInt(i) {
+			return mismatch(dst, src)
+		}
+
+		dst.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var u uint64
+
+		switch n := src.(type) {
+		case uint64:
+			u = n
+		case int64:
+			if n < 0 {
+				return mismatch(dst, src)
+			}
+
+			u = uint64(n)
+		default:
+			return mismatch(dst, src)
+		}
+
+		if dst.OverflowUint(u) {
+			return mismatch(dst, src)
+		}
+
+		dst.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		switch n := src.(type) {
+		case float64:
+			dst.SetFloat(n)
+		case float32:
+			dst.SetFloat(float64(n))
+		case int64:
+			dst.SetFloat(float64(n))
+		case uint64:
+			dst.SetFloat(float64(n))
+		default:
+			return mismatch(dst, src)
+		}
+	case reflect.String:
+		switch s := src.(type) {
+		case string:
+			dst.SetString(s)
+		case []byte:
+			dst.SetString(string(s))
+		default:
+			return mismatch(dst, src)
+		}
+	case reflect.Slice:
+		return assignSlice(dst, src)
+	case reflect.Array:
+		return assignArray(dst, src)
+	case reflect.Map:
+		return assignMap(dst, src)
+	case reflect.Struct:
+		return assignStruct(dst, src)
+	default:
+		return mismatch(dst, src)
+	}
+
+	return nil
+}
+
+// Assign a decoded array or byte string to a slice.
+func assignSlice(dst reflect.Value, src any) error {
+	if dst.Type().Elem().Kind() == reflect.Uint8 {
+		switch data := src.(type) {
+		case []byte:
+			dst.Set(reflect.ValueOf(data).Convert(dst.Type()))
+
+			return nil
+		case string:
+			dst.Set(reflect.ValueOf([]byte(data)).Convert(dst.Type()))
+
+			return nil
+		}
+	}
+
+	values, ok := src.([]any)
+
+	if !ok {
+		return mismatch(dst, src)
+	}
+
+	slice := reflect.MakeSlice(dst.Type(), len(values), len(values))
+
+	for i, value := range values {
+		if err := assign(slice.Index(i), value); err != nil {
+			return err
+		}
+	}
+
+	dst.Set(slice)
+
+	return nil
+}
+
+// Assign a decoded array or byte string to an array.
+func assignArray(dst reflect.Value, src any) error {
+	if data, ok := src.([]byte); ok && dst.Type().Elem().Kind() == reflect.Uint8 {
+		reflect.Copy(dst, reflect.ValueOf(data))
+
+		return nil
+	}
+
+	values, ok := src.([]any)
+
+	if !ok || len(values) > dst.Len() {
+		return mismatch(dst, src)
+	}
+
+	for i, value := range values {
+		if err := assign(dst.Index(i), value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Assign a decoded map to a map.
+func assignMap(dst reflect.Value, src any) error {
+	result := reflect.MakeMap(dst.Type())
+
+	set := func(key any, value any) error {
+		k := reflect.New(dst.Type().Key()).Elem()
+
+		if err := assign(k, key); err != nil {
+			return err
+		}
+
+		v := reflect.New(dst.Type().Elem()).Elem()
+
+		if err := assign(v, value); err != nil {
+			return err
+		}
+
+		result.SetMapIndex(k, v)
+
+		return nil
+	}
+
+	switch entries := src.(type) {
+	case map[string]any:
+		for key, value := range entries {
+			if err := set(key, value); err != nil {
+				return err
+			}
+		}
+	case map[any]any:
+		for key, value := range entries {
+			if err := set(key, value); err != nil {
+				return err
+			}
+		}
+	default:
+		return mismatch(dst, src)
+	}
+
+	dst.Set(result)
+
+	return nil
+}
+
+// Assign a decoded map to the fields of a struct.
+//
+// Keys are matched against the field names exactly first and then without
+// regard to case. Keys without a matching field are ignored.
+func assignStruct(dst reflect.Value, src any) error {
+	entries, ok := src.(map[string]any)
+
+	if !ok {
+		return mismatch(dst, src)
+	}
+
+	fields := structFields(dst.Type())
+
+	for key, value := range entries {
+		if field, ok := matchingField(fields, key); ok {
+			if err := assign(dst.Field(field.index), value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Get the field matching the given key, preferring an exact match.
+func matchingField(fields []field, key string) (field, bool) {
+	for _, field := range fields {
+		if field.name == key {
+			return field, true
+		}
+	}
+
+	for _, field := range fields {
+		if strings.EqualFold(field.name, key) {
+			return field, true
+		}
+	}
+
+	return field{}, false
+}
+
+func mismatch(dst reflect.Value, src any) error {
+	return fmt.Errorf("msgpack: cannot decode %T into %s", src, dst.Type())
+}
+
+// Convert a value decoded into an interface to the value the target points to.
+//
+// This lets a part of a document decoded generically, such as the payload of
+// an envelope, be decoded into a concrete type once that type is known.
+func Convert(value any, target any) error {
+	v := reflect.ValueOf(target)
+
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return fmt.Errorf("msgpack: cannot decode into %T", target)
+	}
+
+	return assign(v.Elem(), value)
+}
@@ -0,0 +1,347 @@
+package msgpack
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The type of time.Time values, encoded with the timestamp extension.
+var timeType = reflect.TypeOf(time.Time{})
+
+// The extension type of timestamps.
+const timestampExtension = -1
+
+type encoder struct {
+	buffer []byte
+}
+
+// Encode the given value into MessagePack.
+//
+// Structs are encoded as maps keyed by their exported field names, which may
+// be renamed with a `msgpack:"name"` tag or skipped with `msgpack:"-"`, and
+// time.Time values use the timestamp extension.
+func Marshal(value any) ([]byte, error) {
+	e := &encoder{}
+
+	if err := e.encode(reflect.ValueOf(value)); err != nil {
+		return nil, err
+	}
+
+	return e.buffer, nil
+}
+
+// Encode the given value.
+func (e *encoder) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		e.write(0xc0)
+
+		return nil
+	}
+
+	if v.Type() == timeType {
+		e.writeTime(v.Interface().(time.Time))
+
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			e.write(0xc3)
+		} else {
+			e.write(0xc2)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.writeInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.writeUint(v.Uint())
+	case reflect.Float32:
+		e.write(0xca)
+		e.writeUint32(math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		e.write(0xcb)
+		e.writeUint64(math.Float64bits(v.Float()))
+	case reflect.String:
+		e.writeString(v.String())
+	case reflect.Slice:
+		if v.IsNil() {
+			e.write(0xc0)
+
+			return nil
+		}
+
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			e.writeBinary(v.Bytes())
+
+			return nil
+		}
+
+		return e.encodeArray(v)
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			data := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(data), v)
+			e.writeBinary(data)
+
+			return nil
+		}
+
+		return e.encodeArray(v)
+	case reflect.Map:
+		if v.IsNil() {
+			e.write(0xc0)
+
+			return nil
+		}
+
+		return e.encodeMap(v)
+	case reflect.Struct:
+		return e.encodeStruct(v)
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			e.write(0xc0)
+
+			return nil
+		}
+
+		return e.encode(v.Elem())
+	default:
+		return fmt.Errorf("msgpack: unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+// Encode the elements of a slice or an array.
+func (e *encoder) encodeArray(v reflect.Value) error {
+	e.writeArrayHeader(v.Len())
+
+	for i := 0; i < v.Len(); i++ {
+		if err := e.encode(v.Index(i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Encode the entries of a map.
+//
+// String keys are sorted so equal maps always encode to the same bytes.
+func (e *encoder) encodeMap(v reflect.Value) error {
+	keys := v.MapKeys()
+
+	if v.Type().Key().Kind() == reflect.String {
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+	}
+
+	e.writeMapHeader(len(keys))
+
+	for _, key := range keys {
+		if err := e.encode(key); err != nil {
+			return err
+		}
+
+		if err := e.encode(v.MapIndex(key)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Encode the exported fields of a struct as a map.
+func (e *encoder) encodeStruct(v reflect.Value) error {
+	fields := structFields(v.Type())
+
+	var included []field
+
+	for _, field := range fields {
+		if field.omitEmpty && v.Field(field.index).IsZero() {
+			continue
+		}
+
+		included = append(included, field)
+	}
+
+	e.writeMapHeader(len(included))
+
+	for _, field := range included {
+		e.writeString(field.name)
+
+		if err := e.encode(v.Field(field.index)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Write a signed integer in its shortest form.
+func (e *encoder) writeInt(i int64) {
+	switch {
+	case i >= 0:
+		e.writeUint(uint64(i))
+	case i >= -32:
+		e.write(byte(int8(i)))
+	case i >= math.MinInt8:
+		e.write(0xd0, byte(int8(i)))
+	case i >= math.MinInt16:
+		e.write(0xd1)
+		e.writeUint16(uint16(int16(i)))
+	case i >= math.MinInt32:
+		e.write(0xd2)
+		e.writeUint32(uint32(int32(i)))
+	default:
+		e.write(0xd3)
+		e.writeUint64(uint64(i))
+	}
+}
+
+// Write an unsigned integer in its shortest form.
+func (e *encoder) writeUint(u uint64) {
+	switch {
+	case u <= 0x7f:
+		e.write(byte(u))
+	case u <= math.MaxUint8:
+		e.write(0xcc, byte(u))
+	case u <= math.MaxUint16:
+		e.write(0xcd)
+		e.writeUint16(uint16(u))
+	case u <= math.MaxUint32:
+		e.write(0xce)
+		e.writeUint32(uint32(u))
+	default:
+		e.write(0xcf)
+		e.writeUint64(u)
+	}
+}
+
+// Write a string.
+func (e *encoder) writeString(s string) {
+	switch n := len(s); {
+	case n <= 31:
+		e.write(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		e.write(0xd9, byte(n))
+	case n <= math.MaxUint16:
+		e.write(0xda)
+		e.writeUint16(uint16(n))
+	default:
+		e.write(0xdb)
+		e.writeUint32(uint32(n))
+	}
+
+	e.buffer = append(e.buffer, s...)
+}
+
+// Write a byte string.
+func (e *encoder) writeBinary(data []byte) {
+	switch n := len(data); {
+	case n <= math.MaxUint8:
+		e.write(0xc4, byte(n))
+	case n <= math.MaxUint16:
+		e.write(0xc5)
+		e.writeUint16(uint16(n))
+	default:
+		e.write(0xc6)
+		e.writeUint32(uint32(n))
+	}
+
+	e.buffer = append(e.buffer, data...)
+}
+
+// Write the header of an array with the given number of elements.
+func (e *encoder) writeArrayHeader(n int) {
+	switch {
+	case n <= 15:
+		e.write(0x90 | byte(n))
+	case n <= math.MaxUint16:
+		e.write(0xdc)
+		e.writeUint16(uint16(n))
+	default:
+		e.write(0xdd)
+		e.writeUint32(uint32(n))
+	}
+}
+
+// Write the header of a map with the given number of entries.
+func (e *encoder) writeMapHeader(n int) {
+	switch {
+	case n <= 15:
+		e.write(0x80 | byte(n))
+	case n <= math.MaxUint16:
+		e.write(0xde)
+		e.writeUint16(uint16(n))
+	default:
+		e.write(0xdf)
+		e.writeUint32(uint32(n))
+	}
+}
+
+// Write a time with the 96-bit timestamp extension, which covers every time.
+func (e *encoder) writeTime(t time.Time) {
+	// The extension type is written as a signed byte, 0xff being -1.
+	e.write(0xc7, 12, 0xff)
+	e.writeUint32(uint32(t.Nanosecond()))
+	e.writeUint64(uint64(t.Unix()))
+}
+
+func (e *encoder) write(bytes ...byte) {
+	e.buffer = append(e.buffer, bytes...)
+}
+
+func (e *encoder) writeUint16(u uint16) {
+	e.buffer = append(e.buffer, byte(u>>8), byte(u))
+}
+
+func (e *encoder) writeUint32(u uint32) {
+	e.buffer = append(e.buffer, byte(u>>24), byte(u>>16), byte(u>>8), byte(u))
+}
+
+func (e *encoder) writeUint64(u uint64) {
+	e.buffer = append(e.buffer, byte(u>>56), byte(u>>48), byte(u>>40), byte(u>>32), byte(u>>24), byte(u>>16), byte(u>>8), byte(u))
+}
+
+// An encoded struct field.
+type field struct {
+	// The key the field is encoded under.
+	name string
+	// The index of the field within the struct.
+	index int
+	// Indicates if the field is left out when it holds its zero value.
+	omitEmpty bool
+}
+
+// Get the encoded fields of the given struct type.
+func structFields(t reflect.Type) []field {
+	var fields []field
+
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+
+		if !structField.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(structField.Tag.Get("msgpack"), ",")
+
+		if name == "-" && options == "" {
+			continue
+		}
+
+		if name == "" {
+			name = structField.Name
+		}
+
+		fields = append(fields, field{name: name, index: i, omitEmpty: options == "omitempty"})
+	}
+
+	return fields
+}
@@ -0,0 +1,72 @@
+// Package netctx ties the I/O of network connections to contexts, shared by
+// the Redis and memcached clients.
+package netctx
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// Get the time an exchange must finish by, zero when it may wait forever.
+//
+// The deadline is the earlier of the timeout from now and the deadline of
+// the context.
+func Deadline(ctx context.Context, timeout time.Duration) time.Time {
+	var result time.Time
+
+	if timeout > 0 {
+		result = time.Now().Add(timeout)
+	}
+
+	if ctxDeadline, ok := ctx.Deadline(); ok && (result.IsZero() || ctxDeadline.Before(result)) {
+		result = ctxDeadline
+	}
+
+	return result
+}
+
+// Get the error of the context that caused an exchange to fail, nil when the
+// failure is unrelated to the context.
+func Err(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// The deadline of the context is also set on the network connection,
+	// which may notice it expired before the context itself does.
+	if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+		return context.DeadlineExceeded
+	}
+
+	return nil
+}
+
+// Interrupt the pending I/O of the network connection once the context is done.
+//
+// The returned function stops watching and reports whether the context
+// interrupted the connection.
+func Watch(ctx context.Context, netConn net.Conn) func() bool {
+	if ctx.Done() == nil {
+		return func() bool { return false }
+	}
+
+	stop := make(chan struct{})
+	interrupted := make(chan bool, 1)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			netConn.SetDeadline(time.Unix(1, 0))
+			interrupted <- true
+		case <-stop:
+			interrupted <- false
+		}
+	}()
+
+	return func() bool {
+		close(stop)
+
+		return <-interrupted
+	}
+}
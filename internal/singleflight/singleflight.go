@@ -0,0 +1,72 @@
+package singleflight
+
+import "sync"
+
+// A call in flight or completed.
+type call struct {
+	wg sync.WaitGroup
+	// The result of the call.
+	value any
+	// The recovered panic of the call, if it panicked.
+	panicked any
+	// The number of callers sharing the result.
+	dups int
+}
+
+// Group coalesces concurrent calls sharing the same key.
+type Group struct {
+	mutex sync.Mutex
+	calls map[string]*call
+}
+
+// Execute the callback, making sure only one execution is in flight for the
+// given key at a time. Duplicate callers wait for the original to complete
+// and receive the same result. A panic of the callback is raised again in
+// every caller.
+func (g *Group) Do(key string, callback func() any) (value any, shared bool) {
+	g.mutex.Lock()
+
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+
+	if c, exists := g.calls[key]; exists {
+		c.dups++
+		g.mutex.Unlock()
+		c.wg.Wait()
+
+		if c.panicked != nil {
+			panic(c.panicked)
+		}
+
+		return c.value, true
+	}
+
+	c := &call{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mutex.Unlock()
+
+	g.run(key, c, callback)
+
+	if c.panicked != nil {
+		panic(c.panicked)
+	}
+
+	return c.value, c.dups > 0
+}
+
+// Run the callback of the call and release the waiting callers.
+func (g *Group) run(key string, c *call, callback func() any) {
+	defer func() {
+		c.panicked = recover()
+
+		g.mutex.Lock()
+		delete(g.calls, key)
+		g.mutex.Unlock()
+
+		c.wg.Done()
+	}()
+
+	c.value = callback()
+}
@@ -0,0 +1,377 @@
+// Package sqltest provides an in-memory database/sql driver for the tests of
+// the database cache store.
+package sqltest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// The error returned for inserts of a key that already exists.
+var ErrDuplicateKey = errors.New("sqltest: duplicate key")
+
+// A row of a cache table.
+type databaseRow struct {
+	value      string
+	expiration int64
+}
+
+// The statements of a cache store matched after quotes are removed and
+// placeholders are turned into question marks.
+var (
+	createPattern         = regexp.MustCompile(`^create table if not exists (\w+) \(key varchar\(255\) not null primary key, value text not null, expiration bigint not null\)$`)
+	selectManyPattern     = regexp.MustCompile(`^select key, value, expiration from (\w+) where key in \(\?(?:, \?)*\)$`)
+	selectOnePattern      = regexp.MustCompile(`^select value, expiration from (\w+) where key = \?( for update)?$`)
+	insertPattern         = regexp.MustCompile(`^insert into (\w+) \(key, value, expiration\) values \(\?, \?, \?\)$`)
+	upsertPattern         = regexp.MustCompile(`^insert into (\w+) \(key, value, expiration\) values \(\?, \?, \?\) (?:on duplicate key update value = values\(value\), expiration = values\(expiration\)|on conflict \(key\) do update set value = excluded\.value, expiration = excluded\.expiration)$`)
+	replaceExpiredPattern = regexp.MustCompile(`^update (\w+) set value = \?, expiration = \? where key = \? and expiration <= \?$`)
+	updateValuePattern    = regexp.MustCompile(`^update (\w+) set value = \? where key = \?$`)
+	deletePattern         = regexp.MustCompile(`^delete from (\w+) where key = \?$`)
+	prunePattern          = regexp.MustCompile(`^delete from (\w+) where expiration <= \?$`)
+	flushPattern          = regexp.MustCompile(`^delete from (\w+)$`)
+	placeholderPattern    = regexp.MustCompile(`\$\d+`)
+)
+
+// Database is an in-memory stand-in for the database of a cache DatabaseStore.
+//
+// It is not an SQL engine: it understands the statements the store issues to
+// its cache table, in the MySQL, Postgres and SQLite dialects, and rejects
+// everything else. Transactions are serialized and take a snapshot of the
+// tables, which is restored when they are rolled back.
+type Database struct {
+	// The rows of every table, by table and key.
+	tables map[string]map[string]databaseRow
+	// The statements received, in order.
+	statements []string
+	// Guards the tables and statements.
+	mutex sync.Mutex
+	// Held by the running transaction, and by statements run outside of one.
+	serial sync.Mutex
+}
+
+// Create a new empty database.
+func NewDatabase() *Database {
+	return &Database{tables: map[string]map[string]databaseRow{}}
+}
+
+// Open a handle on the database.
+func (d *Database) Open() *sql.DB {
+	return sql.OpenDB(databaseConnector{database: d})
+}
+
+// Get the statements received so far, in order.
+func (d *Database) Statements() []string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return append([]string(nil), d.statements...)
+}
+
+// Get the number of rows in the given table, expired ones included.
+func (d *Database) Rows(table string) int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return len(d.tables[table])
+}
+
+// Run a statement against the tables and return the rows it selected.
+func (d *Database) run(query string, args []driver.NamedValue) ([][]driver.Value, int64, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.statements = append(d.statements, query)
+
+	statement := placeholderPattern.ReplaceAllString(strings.NewReplacer("`", "", `"`, "").Replace(query), "?")
+	values := make([]any, len(args))
+
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+
+	if match := createPattern.FindStringSubmatch(statement); match != nil {
+		if d.tables[match[1]] == nil {
+			d.tables[match[1]] = map[string]databaseRow{}
+		}
+
+		return nil, 0, nil
+	}
+
+	var pattern *regexp.Regexp
+	var match []string
+
+	for _, pattern = range []*regexp.Regexp{selectManyPattern, selectOnePattern, insertPattern, upsertPattern, replaceExpiredPattern, updateValuePattern, deletePattern, prunePattern, flushPattern} {
+		if match = pattern.FindStringSubmatch(statement); match != nil {
+			break
+		}
+	}
+
+	if match == nil {
+		return nil, 0, fmt.Errorf("sqltest: unsupported statement [%s]", query)
+	}
+
+	table, ok := d.tables[match[1]]
+
+	if !ok {
+		return nil, 0, fmt.Errorf("sqltest: table [%s] does not exist", match[1])
+	}
+
+	switch pattern {
+	case selectManyPattern:
+		var rows [][]driver.Value
+
+		for _, key := range values {
+			if row, ok := table[key.(string)]; ok {
+				rows = append(rows, []driver.Value{key, row.value, row.expiration})
+			}
+		}
+
+		return rows, 0, nil
+	case selectOnePattern:
+		if row, ok := table[values[0].(string)]; ok {
+			return [][]driver.Value{{row.value, row.expiration}}, 0, nil
+		}
+
+		return nil, 0, nil
+	case insertPattern, upsertPattern:
+		key := values[0].(string)
+
+		if _, exists := table[key]; exists && pattern == insertPattern {
+			return nil, 0, ErrDuplicateKey
+		}
+
+		table[key] = databaseRow{value: values[1].(string), expiration: values[2].(int64)}
+
+		return nil, 1, nil
+	case replaceExpiredPattern:
+		key := values[2].(string)
+
+		if row, ok := table[key]; ok && row.expiration <= values[3].(int64) {
+			table[key] = databaseRow{value: values[0].(string), expiration: values[1].(int64)}
+
+			return nil, 1, nil
+		}
+
+		return nil, 0, nil
+	case updateValuePattern:
+		key := values[1].(string)
+
+		if row, ok := table[key]; ok {
+			table[key] = databaseRow{value: values[0].(string), expiration: row.expiration}
+
+			return nil, 1, nil
+		}
+
+		return nil, 0, nil
+	case deletePattern:
+		if _, ok := table[values[0].(string)]; ok {
+			delete(table, values[0].(string))
+
+			return nil, 1, nil
+		}
+
+		return nil, 0, nil
+	case prunePattern:
+		var affected int64
+
+		for key, row := range table {
+			if row.expiration <= values[0].(int64) {
+				delete(table, key)
+				affected++
+			}
+		}
+
+		return nil, affected, nil
+	}
+
+	affected := int64(len(table))
+	d.tables[match[1]] = map[string]databaseRow{}
+
+	return nil, affected, nil
+}
+
+// Take a copy of every table.
+func (d *Database) snapshot() map[string]map[string]databaseRow {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	tables := make(map[string]map[string]databaseRow, len(d.tables))
+
+	for name, rows := range d.tables {
+		tables[name] = make(map[string]databaseRow, len(rows))
+
+		for key, row := range rows {
+			tables[name][key] = row
+		}
+	}
+
+	return tables
+}
+
+// Restore the tables from a snapshot.
+func (d *Database) restore(tables map[string]map[string]databaseRow) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.tables = tables
+}
+
+type databaseConnector struct {
+	database *Database
+}
+
+func (c databaseConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &databaseConn{database: c.database}, nil
+}
+
+func (c databaseConnector) Driver() driver.Driver {
+	return databaseDriver{}
+}
+
+type databaseDriver struct{}
+
+func (databaseDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("sqltest: open the database with Database.Open")
+}
+
+type databaseConn struct {
+	database *Database
+	// The tables before the running transaction, nil outside of one.
+	snapshot map[string]map[string]databaseRow
+}
+
+func (c *databaseConn) Prepare(query string) (driver.Stmt, error) {
+	return databaseStmt{conn: c, query: query}, nil
+}
+
+func (c *databaseConn) Close() error {
+	return nil
+}
+
+func (c *databaseConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *databaseConn) BeginTx(ctx context.Context, options driver.TxOptions) (driver.Tx, error) {
+	c.database.serial.Lock()
+	c.snapshot = c.database.snapshot()
+
+	return c, nil
+}
+
+func (c *databaseConn) Commit() error {
+	c.snapshot = nil
+	c.database.serial.Unlock()
+
+	return nil
+}
+
+func (c *databaseConn) Rollback() error {
+	c.database.restore(c.snapshot)
+	c.snapshot = nil
+	c.database.serial.Unlock()
+
+	return nil
+}
+
+func (c *databaseConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	_, affected, err := c.run(query, args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return driver.RowsAffected(affected), nil
+}
+
+func (c *databaseConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows, _, err := c.run(query, args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &databaseRows{rows: rows, columns: columnsOf(query)}, nil
+}
+
+// Run a statement, waiting for the running transaction of other connections.
+func (c *databaseConn) run(query string, args []driver.NamedValue) ([][]driver.Value, int64, error) {
+	if c.snapshot == nil {
+		c.database.serial.Lock()
+		defer c.database.serial.Unlock()
+	}
+
+	return c.database.run(query, args)
+}
+
+type databaseStmt struct {
+	conn  *databaseConn
+	query string
+}
+
+func (s databaseStmt) Close() error {
+	return nil
+}
+
+func (s databaseStmt) NumInput() int {
+	return -1
+}
+
+func (s databaseStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, named(args))
+}
+
+func (s databaseStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, named(args))
+}
+
+type databaseRows struct {
+	rows    [][]driver.Value
+	columns []string
+}
+
+func (r *databaseRows) Columns() []string {
+	return r.columns
+}
+
+func (r *databaseRows) Close() error {
+	return nil
+}
+
+func (r *databaseRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+
+	return nil
+}
+
+// Get the names of the columns selected by a query.
+func columnsOf(query string) []string {
+	if strings.Contains(query, "in (") {
+		return []string{"key", "value", "expiration"}
+	}
+
+	return []string{"value", "expiration"}
+}
+
+// Turn positional statement arguments into named ones.
+func named(args []driver.Value) []driver.NamedValue {
+	values := make([]driver.NamedValue, len(args))
+
+	for i, arg := range args {
+		values[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+
+	return values
+}
@@ -0,0 +1,594 @@
+// Package memcached implements a client for the memcached text protocol.
+package memcached
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/garavel-core/framework/internal/netctx"
+)
+
+var (
+	// The error returned when a key is not present on the server.
+	ErrCacheMiss = errors.New("memcached: cache miss")
+	// The error returned when a conditional write was not performed.
+	ErrNotStored = errors.New("memcached: item not stored")
+	// The error returned when a key is not usable with the protocol.
+	ErrMalformedKey = errors.New("memcached: key is too long or contains invalid characters")
+	// The error returned when a compare-and-swap found the item changed.
+	ErrCASConflict = errors.New("memcached: compare-and-swap conflict")
+	// The error returned when a closed client is used.
+	ErrClosed = errors.New("memcached: client is closed")
+	// The error returned when a reply cannot be understood, which leaves the
+	// connection out of step with the server.
+	ErrProtocol = errors.New("memcached: protocol error")
+)
+
+// An error reported by the server with a CLIENT_ERROR or SERVER_ERROR reply.
+type ServerError string
+
+func (e ServerError) Error() string {
+	return "memcached: " + string(e)
+}
+
+// The longest key accepted by the server.
+const MaxKeyLength = 250
+
+// The largest relative expiration, later expirations must be UNIX timestamps.
+const MaxRelativeExpiration = 60 * 60 * 24 * 30
+
+type Options struct {
+	// The "host:port" address of the memcached server.
+	Address string
+	// The maximum time to wait for a new network connection.
+	DialTimeout time.Duration
+	// The maximum time a single command may take, zero waits forever.
+	Timeout time.Duration
+	// The maximum number of idle network connections kept for reuse.
+	PoolSize int
+}
+
+// An item stored on the memcached server.
+type Item struct {
+	// The key of the item.
+	Key string
+	// The raw value of the item.
+	Value []byte
+	// Opaque flags stored alongside the value.
+	Flags uint32
+	// The expiration, in seconds relative to now or as a UNIX timestamp.
+	Expiration int64
+	// The compare-and-swap token of the item, set when it is read.
+	CasID uint64
+}
+
+// A network connection to the memcached server.
+type conn struct {
+	netConn net.Conn
+	rw      *bufio.ReadWriter
+}
+
+type Client struct {
+	// The options used to open network connections.
+	options Options
+	// The idle network connections ready for reuse.
+	idle chan *conn
+	// Guards the closed flag.
+	mutex sync.Mutex
+	// Indicates if the client has been closed.
+	closed bool
+}
+
+// Create a new memcached client.
+//
+// Network connections are opened lazily and pooled, so the returned value is
+// safe for concurrent use.
+func NewClient(options Options) *Client {
+	if options.Address == "" {
+		options.Address = "127.0.0.1:11211"
+	}
+
+	if options.DialTimeout == 0 {
+		options.DialTimeout = 5 * time.Second
+	}
+
+	if options.PoolSize <= 0 {
+		options.PoolSize = 10
+	}
+
+	return &Client{options: options, idle: make(chan *conn, options.PoolSize)}
+}
+
+// Get the item stored under the given key.
+func (c *Client) Get(key string) (*Item, error) {
+	return c.GetContext(context.Background(), key)
+}
+
+// Get the item stored under the given key, giving up when the context is done.
+func (c *Client) GetContext(ctx context.Context, key string) (*Item, error) {
+	items, err := c.GetMultiContext(ctx, []string{key})
+
+	if err != nil {
+		return nil, err
+	}
+
+	item, exists := items[key]
+
+	if !exists {
+		return nil, ErrCacheMiss
+	}
+
+	return item, nil
+}
+
+// Get the items stored under the given keys, missing keys are left out.
+func (c *Client) GetMulti(keys []string) (map[string]*Item, error) {
+	return c.GetMultiContext(context.Background(), keys)
+}
+
+// Get the items stored under the given keys, giving up when the context is done.
+func (c *Client) GetMultiContext(ctx context.Context, keys []string) (map[string]*Item, error) {
+	items := make(map[string]*Item, len(keys))
+
+	if len(keys) == 0 {
+		return items, nil
+	}
+
+	for _, key := range keys {
+		if !legalKey(key) {
+			return nil, ErrMalformedKey
+		}
+	}
+
+	err := c.run(ctx, func(cn *conn) error {
+		if _, err := fmt.Fprintf(cn.rw, "gets %s\r\n", strings.Join(keys, " ")); err != nil {
+			return err
+		}
+
+		if err := cn.rw.Flush(); err != nil {
+			return err
+		}
+
+		for {
+			line, err := readLine(cn.rw.Reader)
+
+			if err != nil {
+				return err
+			}
+
+			if line == "END" {
+				return nil
+			}
+
+			item, err := readItem(cn.rw.Reader, line)
+
+			if err != nil {
+				return err
+			}
+
+			items[item.Key] = item
+		}
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// Store the item unconditionally.
+func (c *Client) Set(item *Item) error {
+	return c.SetContext(context.Background(), item)
+}
+
+// Store the item unconditionally, giving up when the context is done.
+func (c *Client) SetContext(ctx context.Context, item *Item) error {
+	return c.store(ctx, "set", item)
+}
+
+// Store the item only if its key is not already present.
+func (c *Client) Add(item *Item) error {
+	return c.AddContext(context.Background(), item)
+}
+
+// Store the item only if its key is not already present, giving up when the context is done.
+func (c *Client) AddContext(ctx context.Context, item *Item) error {
+	return c.store(ctx, "add", item)
+}
+
+// Store the item only if it was not changed since it was read.
+//
+// The item must carry the CasID it was read with. ErrCASConflict is returned
+// when the item changed in the meantime and ErrCacheMiss when it was removed.
+func (c *Client) CompareAndSwap(item *Item) error {
+	return c.CompareAndSwapContext(context.Background(), item)
+}
+
+// Store the item only if it was not changed since it was read, giving up when the context is done.
+func (c *Client) CompareAndSwapContext(ctx context.Context, item *Item) error {
+	return c.store(ctx, "cas", item)
+}
+
+// Increment the numeric value of the key, returning the new value.
+func (c *Client) Increment(key string, delta uint64) (uint64, error) {
+	return c.IncrementContext(context.Background(), key, delta)
+}
+
+// Increment the numeric value of the key, giving up when the context is done.
+func (c *Client) IncrementContext(ctx context.Context, key string, delta uint64) (uint64, error) {
+	return c.incrDecr(ctx, "incr", key, delta)
+}
+
+// Decrement the numeric value of the key, returning the new value.
+//
+// The server never decrements a value below zero.
+func (c *Client) Decrement(key string, delta uint64) (uint64, error) {
+	return c.DecrementContext(context.Background(), key, delta)
+}
+
+// Decrement the numeric value of the key, giving up when the context is done.
+func (c *Client) DecrementContext(ctx context.Context, key string, delta uint64) (uint64, error) {
+	return c.incrDecr(ctx, "decr", key, delta)
+}
+
+// Delete the item stored under the given key.
+func (c *Client) Delete(key string) error {
+	return c.DeleteContext(context.Background(), key)
+}
+
+// Delete the item stored under the given key, giving up when the context is done.
+func (c *Client) DeleteContext(ctx context.Context, key string) error {
+	if !legalKey(key) {
+		return ErrMalformedKey
+	}
+
+	return c.run(ctx, func(cn *conn) error {
+		line, err := cn.roundTrip(fmt.Sprintf("delete %s\r\n", key))
+
+		if err != nil {
+			return err
+		}
+
+		switch line {
+		case "DELETED":
+			return nil
+		case "NOT_FOUND":
+			return ErrCacheMiss
+		}
+
+		return replyError(line)
+	})
+}
+
+// Invalidate every item on the server.
+func (c *Client) FlushAll() error {
+	return c.FlushAllContext(context.Background())
+}
+
+// Invalidate every item on the server, giving up when the context is done.
+func (c *Client) FlushAllContext(ctx context.Context) error {
+	return c.run(ctx, func(cn *conn) error {
+		line, err := cn.roundTrip("flush_all\r\n")
+
+		if err != nil {
+			return err
+		}
+
+		if line != "OK" {
+			return replyError(line)
+		}
+
+		return nil
+	})
+}
+
+// Check that the server is reachable.
+func (c *Client) Ping() error {
+	return c.PingContext(context.Background())
+}
+
+// Check that the server is reachable, giving up when the context is done.
+func (c *Client) PingContext(ctx context.Context) error {
+	return c.run(ctx, func(cn *conn) error {
+		line, err := cn.roundTrip("version\r\n")
+
+		if err != nil {
+			return err
+		}
+
+		if !strings.HasPrefix(line, "VERSION ") {
+			return replyError(line)
+		}
+
+		return nil
+	})
+}
+
+// Close the client and every pooled network connection.
+func (c *Client) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return nil
+	}
+
+	c.closed = true
+
+	close(c.idle)
+
+	for cn := range c.idle {
+		cn.netConn.Close()
+	}
+
+	return nil
+}
+
+// Run a storage command.
+func (c *Client) store(ctx context.Context, verb string, item *Item) error {
+	if !legalKey(item.Key) {
+		return ErrMalformedKey
+	}
+
+	return c.run(ctx, func(cn *conn) error {
+		command := fmt.Sprintf("%s %s %d %d %d", verb, item.Key, item.Flags, item.Expiration, len(item.Value))
+
+		if verb == "cas" {
+			command += " " + strconv.FormatUint(item.CasID, 10)
+		}
+
+		if _, err := cn.rw.WriteString(command + "\r\n"); err != nil {
+			return err
+		}
+
+		if _, err := cn.rw.Write(item.Value); err != nil {
+			return err
+		}
+
+		line, err := cn.roundTrip("\r\n")
+
+		if err != nil {
+			return err
+		}
+
+		switch line {
+		case "STORED":
+			return nil
+		case "NOT_STORED":
+			return ErrNotStored
+		case "EXISTS":
+			return ErrCASConflict
+		case "NOT_FOUND":
+			return ErrCacheMiss
+		}
+
+		return replyError(line)
+	})
+}
+
+// Run an incr or decr command.
+func (c *Client) incrDecr(ctx context.Context, verb string, key string, delta uint64) (uint64, error) {
+	if !legalKey(key) {
+		return 0, ErrMalformedKey
+	}
+
+	var value uint64
+
+	err := c.run(ctx, func(cn *conn) error {
+		line, err := cn.roundTrip(fmt.Sprintf("%s %s %d\r\n", verb, key, delta))
+
+		if err != nil {
+			return err
+		}
+
+		if line == "NOT_FOUND" {
+			return ErrCacheMiss
+		}
+
+		if value, err = strconv.ParseUint(line, 10, 64); err != nil {
+			return replyError(line)
+		}
+
+		return nil
+	})
+
+	return value, err
+}
+
+// Run the callback with a network connection.
+//
+// Connections are only returned to the pool when the callback succeeded or
+// read a complete reply of the server, such as a miss or an error line.
+// Network and protocol errors, after which the connection may be out of step
+// with the server, discard the connection, as does a cancelled context.
+func (c *Client) run(ctx context.Context, callback func(*conn) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	cn, err := c.acquire(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	cn.netConn.SetDeadline(netctx.Deadline(ctx, c.options.Timeout))
+
+	stop := netctx.Watch(ctx, cn.netConn)
+	err = callback(cn)
+
+	if interrupted := stop(); interrupted || err != nil {
+		if ctxErr := netctx.Err(ctx); ctxErr != nil {
+			cn.netConn.Close()
+
+			return ctxErr
+		}
+	}
+
+	var serverError ServerError
+
+	if err == nil || errors.Is(err, ErrCacheMiss) || errors.Is(err, ErrNotStored) || errors.Is(err, ErrCASConflict) || errors.As(err, &serverError) {
+		c.release(cn)
+	} else {
+		cn.netConn.Close()
+	}
+
+	return err
+}
+
+// Take an idle network connection or open a new one.
+func (c *Client) acquire(ctx context.Context) (*conn, error) {
+	c.mutex.Lock()
+	closed := c.closed
+	c.mutex.Unlock()
+
+	if closed {
+		return nil, ErrClosed
+	}
+
+	select {
+	case cn, ok := <-c.idle:
+		if ok {
+			return cn, nil
+		}
+
+		return nil, ErrClosed
+	default:
+	}
+
+	dialer := &net.Dialer{Timeout: c.options.DialTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", c.options.Address)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &conn{netConn: netConn, rw: bufio.NewReadWriter(bufio.NewReader(netConn), bufio.NewWriter(netConn))}, nil
+}
+
+// Return a healthy network connection to the pool.
+func (c *Client) release(cn *conn) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		cn.netConn.Close()
+
+		return
+	}
+
+	select {
+	case c.idle <- cn:
+	default:
+		cn.netConn.Close()
+	}
+}
+
+// Write the command and read the single line reply.
+func (cn *conn) roundTrip(command string) (string, error) {
+	if _, err := cn.rw.WriteString(command); err != nil {
+		return "", err
+	}
+
+	if err := cn.rw.Flush(); err != nil {
+		return "", err
+	}
+
+	return readLine(cn.rw.Reader)
+}
+
+// Read an item following its "VALUE <key> <flags> <bytes> [<cas>]" header.
+func readItem(reader *bufio.Reader, header string) (*Item, error) {
+	fields := strings.Fields(header)
+
+	if len(fields) < 4 || fields[0] != "VALUE" {
+		return nil, replyError(header)
+	}
+
+	flags, err := strconv.ParseUint(fields[2], 10, 32)
+
+	if err != nil {
+		return nil, protocolError("malformed VALUE line: " + header)
+	}
+
+	size, err := strconv.Atoi(fields[3])
+
+	if err != nil || size < 0 {
+		return nil, protocolError("malformed VALUE line: " + header)
+	}
+
+	value := make([]byte, size+2)
+
+	if _, err := io.ReadFull(reader, value); err != nil {
+		return nil, err
+	}
+
+	if !bytes.HasSuffix(value, []byte("\r\n")) {
+		return nil, protocolError("corrupt value for key " + fields[1])
+	}
+
+	item := &Item{Key: fields[1], Value: value[:size], Flags: uint32(flags)}
+
+	if len(fields) > 4 {
+		if item.CasID, err = strconv.ParseUint(fields[4], 10, 64); err != nil {
+			return nil, protocolError("malformed VALUE line: " + header)
+		}
+	}
+
+	return item, nil
+}
+
+// Read a CRLF terminated line without its terminator.
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+// Convert an unexpected reply into an error.
+//
+// Only error lines of the server are ServerErrors, any other reply is a
+// protocol error.
+func replyError(line string) error {
+	for _, prefix := range []string{"CLIENT_ERROR ", "SERVER_ERROR "} {
+		if strings.HasPrefix(line, prefix) {
+			return ServerError(strings.TrimPrefix(line, prefix))
+		}
+	}
+
+	return protocolError("unexpected reply: " + line)
+}
+
+// Get a protocol error with the given detail.
+func protocolError(detail string) error {
+	return fmt.Errorf("%w: %s", ErrProtocol, detail)
+}
+
+// Determine if the key may be sent to the server.
+func legalKey(key string) bool {
+	if len(key) == 0 || len(key) > MaxKeyLength {
+		return false
+	}
+
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+
+	return true
+}
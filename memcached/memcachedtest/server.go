@@ -0,0 +1,345 @@
+// Package memcachedtest provides an in-process memcached server for tests.
+//
+// The server understands the retrieval, storage, counter and deletion
+// commands of the text protocol and applies the same expiration rules as
+// memcached: zero never expires, values up to thirty days are relative and
+// anything larger is an absolute UNIX timestamp.
+package memcachedtest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/garavel-core/framework/memcached"
+)
+
+// A value held by the fake server.
+type entry struct {
+	value     []byte
+	flags     uint32
+	cas       uint64
+	expiresAt time.Time
+}
+
+type Server struct {
+	// The listener accepting client connections.
+	listener net.Listener
+	// The items of the server.
+	items map[string]entry
+	// The last CAS unique handed out.
+	cas uint64
+	// Guards the items.
+	mutex sync.Mutex
+	// The open client connections.
+	conns map[net.Conn]struct{}
+	// Tracks the connection handlers.
+	wg sync.WaitGroup
+	// The raw expiration of every storage command received, by key.
+	expirations map[string]int64
+	// The clock the server reads the current time from.
+	clock func() time.Time
+}
+
+// Start a new fake memcached server on a random local port.
+func NewServer() *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		panic(fmt.Sprintf("memcachedtest: failed to listen: %v", err))
+	}
+
+	server := &Server{
+		listener:    listener,
+		items:       make(map[string]entry),
+		conns:       make(map[net.Conn]struct{}),
+		expirations: make(map[string]int64),
+		clock:       time.Now,
+	}
+
+	server.wg.Add(1)
+
+	go server.serve()
+
+	return server
+}
+
+// Get the "host:port" address of the server.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Set the clock the server reads the current time from to expire items.
+func (s *Server) SetClock(clock func() time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.clock = clock
+}
+
+// Get the expiration sent with the last storage command for the key.
+func (s *Server) Expiration(key string) int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.expirations[key]
+}
+
+// Stop the server and disconnect every client.
+func (s *Server) Close() {
+	s.listener.Close()
+
+	s.mutex.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mutex.Unlock()
+
+	s.wg.Wait()
+}
+
+// Accept client connections until the listener closes.
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+
+		if err != nil {
+			return
+		}
+
+		s.mutex.Lock()
+		s.conns[conn] = struct{}{}
+		s.mutex.Unlock()
+
+		s.wg.Add(1)
+
+		go s.handle(conn)
+	}
+}
+
+// Serve the commands of a single client.
+func (s *Server) handle(conn net.Conn) {
+	defer s.wg.Done()
+
+	defer func() {
+		conn.Close()
+
+		s.mutex.Lock()
+		delete(s.conns, conn)
+		s.mutex.Unlock()
+	}()
+
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+
+	for {
+		line, err := reader.ReadString('\n')
+
+		if err != nil {
+			return
+		}
+
+		fields := strings.Fields(line)
+
+		if len(fields) == 0 {
+			writer.WriteString("ERROR\r\n")
+		} else if !s.exec(reader, writer, fields) {
+			return
+		}
+
+		if err := writer.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// Execute a single command, returning false when the connection should close.
+func (s *Server) exec(r *bufio.Reader, w *bufio.Writer, fields []string) bool {
+	switch command := fields[0]; command {
+	case "get", "gets":
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		for _, key := range fields[1:] {
+			if item, ok := s.get(key); ok {
+				if command == "gets" {
+					fmt.Fprintf(w, "VALUE %s %d %d %d\r\n", key, item.flags, len(item.value), item.cas)
+				} else {
+					fmt.Fprintf(w, "VALUE %s %d %d\r\n", key, item.flags, len(item.value))
+				}
+
+				w.Write(item.value)
+				w.WriteString("\r\n")
+			}
+		}
+
+		w.WriteString("END\r\n")
+	case "set", "add", "replace", "cas":
+		if len(fields) < 5 || (command == "cas" && len(fields) < 6) {
+			w.WriteString("ERROR\r\n")
+
+			return true
+		}
+
+		flags, err1 := strconv.ParseUint(fields[2], 10, 32)
+		expiration, err2 := strconv.ParseInt(fields[3], 10, 64)
+		size, err3 := strconv.Atoi(fields[4])
+
+		if err1 != nil || err2 != nil || err3 != nil || size < 0 {
+			w.WriteString("CLIENT_ERROR bad command line format\r\n")
+
+			return false
+		}
+
+		data := make([]byte, size+2)
+
+		if _, err := io.ReadFull(r, data); err != nil {
+			return false
+		}
+
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		key := fields[1]
+		_, exists := s.get(key)
+		s.expirations[key] = expiration
+
+		if (command == "add" && exists) || (command == "replace" && !exists) {
+			w.WriteString("NOT_STORED\r\n")
+
+			return true
+		}
+
+		if command == "cas" {
+			if !exists {
+				w.WriteString("NOT_FOUND\r\n")
+
+				return true
+			}
+
+			if strconv.FormatUint(s.items[key].cas, 10) != fields[5] {
+				w.WriteString("EXISTS\r\n")
+
+				return true
+			}
+		}
+
+		s.cas++
+		s.items[key] = entry{value: data[:size], flags: uint32(flags), cas: s.cas, expiresAt: s.expiresAt(expiration)}
+
+		w.WriteString("STORED\r\n")
+	case "incr", "decr":
+		if len(fields) < 3 {
+			w.WriteString("ERROR\r\n")
+
+			return true
+		}
+
+		delta, err := strconv.ParseUint(fields[2], 10, 64)
+
+		if err != nil {
+			w.WriteString("CLIENT_ERROR invalid numeric delta argument\r\n")
+
+			return true
+		}
+
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		item, ok := s.get(fields[1])
+
+		if !ok {
+			w.WriteString("NOT_FOUND\r\n")
+
+			return true
+		}
+
+		current, err := strconv.ParseUint(string(item.value), 10, 64)
+
+		if err != nil {
+			w.WriteString("CLIENT_ERROR cannot increment or decrement non-numeric value\r\n")
+
+			return true
+		}
+
+		if command == "incr" {
+			current += delta
+		} else if delta > current {
+			current = 0
+		} else {
+			current -= delta
+		}
+
+		s.cas++
+		item.value = strconv.AppendUint(nil, current, 10)
+		item.cas = s.cas
+		s.items[fields[1]] = item
+
+		fmt.Fprintf(w, "%d\r\n", current)
+	case "delete":
+		if len(fields) < 2 {
+			w.WriteString("ERROR\r\n")
+
+			return true
+		}
+
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		if _, ok := s.get(fields[1]); ok {
+			delete(s.items, fields[1])
+			w.WriteString("DELETED\r\n")
+		} else {
+			w.WriteString("NOT_FOUND\r\n")
+		}
+	case "flush_all":
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		s.items = make(map[string]entry)
+
+		w.WriteString("OK\r\n")
+	case "version":
+		w.WriteString("VERSION 1.6.0-memcachedtest\r\n")
+	case "quit":
+		return false
+	default:
+		w.WriteString("ERROR\r\n")
+	}
+
+	return true
+}
+
+// Get a live item, removing it when it has expired.
+func (s *Server) get(key string) (entry, bool) {
+	item, exists := s.items[key]
+
+	if exists && !item.expiresAt.IsZero() && !s.clock().Before(item.expiresAt) {
+		delete(s.items, key)
+
+		return entry{}, false
+	}
+
+	return item, exists
+}
+
+// Resolve a protocol expiration into the time the item expires.
+func (s *Server) expiresAt(expiration int64) time.Time {
+	switch {
+	case expiration == 0:
+		return time.Time{}
+	case expiration < 0:
+		return time.Unix(0, 0)
+	case expiration > memcached.MaxRelativeExpiration:
+		return time.Unix(expiration, 0)
+	}
+
+	return s.clock().Add(time.Duration(expiration) * time.Second)
+}
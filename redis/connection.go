@@ -0,0 +1,271 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/garavel-core/framework/internal/netctx"
+)
+
+// The error returned when a closed connection is used.
+var ErrClosed = errors.New("redis: connection is closed")
+
+type Options struct {
+	// The "host:port" address of the Redis server.
+	Address string
+	// The password sent with AUTH, if any.
+	Password string
+	// The database selected after connecting.
+	Database int
+	// The maximum time to wait for a new network connection.
+	DialTimeout time.Duration
+	// The maximum time a single command may take, zero waits forever.
+	Timeout time.Duration
+	// The maximum number of idle network connections kept for reuse.
+	PoolSize int
+}
+
+// A network connection to the Redis server.
+type conn struct {
+	netConn net.Conn
+	reader  *bufio.Reader
+	writer  *bufio.Writer
+}
+
+type Connection struct {
+	// The options used to open network connections.
+	options Options
+	// The idle network connections ready for reuse.
+	idle chan *conn
+	// Guards the closed flag.
+	mutex sync.Mutex
+	// Indicates if the connection has been closed.
+	closed bool
+}
+
+// Create a new Redis connection.
+//
+// Network connections are opened lazily and pooled, so the returned value is
+// safe for concurrent use.
+func NewConnection(options Options) *Connection {
+	if options.Address == "" {
+		options.Address = "127.0.0.1:6379"
+	}
+
+	if options.DialTimeout == 0 {
+		options.DialTimeout = 5 * time.Second
+	}
+
+	if options.PoolSize <= 0 {
+		options.PoolSize = 10
+	}
+
+	return &Connection{options: options, idle: make(chan *conn, options.PoolSize)}
+}
+
+// Run a command against the Redis server.
+func (c *Connection) Command(args ...any) (any, error) {
+	return c.CommandContext(context.Background(), args...)
+}
+
+// Run a command against the Redis server, giving up when the context is done.
+func (c *Connection) CommandContext(ctx context.Context, args ...any) (any, error) {
+	replies, err := c.PipelineContext(ctx, args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return replyError(replies[0])
+}
+
+// Send several commands in a single round trip and return all of their replies.
+//
+// Error replies are returned in place, so one failing command does not hide
+// the results of the others.
+func (c *Connection) Pipeline(commands ...[]any) ([]any, error) {
+	return c.PipelineContext(context.Background(), commands...)
+}
+
+// Send several commands in a single round trip, giving up when the context is done.
+//
+// The deadline of the context shortens the command timeout. A cancelled
+// context interrupts the round trip and the network connection is discarded,
+// since its replies can no longer be matched with their commands.
+func (c *Connection) PipelineContext(ctx context.Context, commands ...[]any) ([]any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	cn, err := c.acquire(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stop := netctx.Watch(ctx, cn.netConn)
+	replies, err := cn.pipeline(netctx.Deadline(ctx, c.options.Timeout), commands)
+
+	if interrupted := stop(); interrupted || err != nil {
+		if ctxErr := netctx.Err(ctx); ctxErr != nil {
+			cn.netConn.Close()
+
+			return nil, ctxErr
+		}
+	}
+
+	if err != nil {
+		cn.netConn.Close()
+
+		return nil, err
+	}
+
+	c.release(cn)
+
+	return replies, nil
+}
+
+// Close the connection and every pooled network connection.
+func (c *Connection) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return nil
+	}
+
+	c.closed = true
+
+	close(c.idle)
+
+	for cn := range c.idle {
+		cn.netConn.Close()
+	}
+
+	return nil
+}
+
+// Take an idle network connection or open a new one.
+func (c *Connection) acquire(ctx context.Context) (*conn, error) {
+	c.mutex.Lock()
+	closed := c.closed
+	c.mutex.Unlock()
+
+	if closed {
+		return nil, ErrClosed
+	}
+
+	select {
+	case cn, ok := <-c.idle:
+		if ok {
+			return cn, nil
+		}
+
+		return nil, ErrClosed
+	default:
+	}
+
+	return c.dial(ctx)
+}
+
+// Return a healthy network connection to the pool.
+func (c *Connection) release(cn *conn) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		cn.netConn.Close()
+
+		return
+	}
+
+	select {
+	case c.idle <- cn:
+	default:
+		cn.netConn.Close()
+	}
+}
+
+// Open and prepare a new network connection.
+func (c *Connection) dial(ctx context.Context) (*conn, error) {
+	dialer := &net.Dialer{Timeout: c.options.DialTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", c.options.Address)
+
+	if err != nil {
+		return nil, err
+	}
+
+	cn := &conn{netConn: netConn, reader: bufio.NewReader(netConn), writer: bufio.NewWriter(netConn)}
+
+	var setup [][]any
+
+	if c.options.Password != "" {
+		setup = append(setup, []any{"AUTH", c.options.Password})
+	}
+
+	if c.options.Database != 0 {
+		setup = append(setup, []any{"SELECT", c.options.Database})
+	}
+
+	if setup != nil {
+		replies, err := cn.pipeline(netctx.Deadline(ctx, c.options.Timeout), setup)
+
+		if err == nil {
+			for _, reply := range replies {
+				if _, err = replyError(reply); err != nil {
+					break
+				}
+			}
+		}
+
+		if err != nil {
+			netConn.Close()
+
+			return nil, err
+		}
+	}
+
+	return cn, nil
+}
+
+// Write the commands and read one reply for each of them.
+func (cn *conn) pipeline(deadline time.Time, commands [][]any) ([]any, error) {
+	cn.netConn.SetDeadline(deadline)
+
+	for _, command := range commands {
+		if err := WriteCommand(cn.writer, command...); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cn.writer.Flush(); err != nil {
+		return nil, err
+	}
+
+	replies := make([]any, len(commands))
+
+	for i := range replies {
+		reply, err := ReadReply(cn.reader)
+
+		if err != nil {
+			return nil, err
+		}
+
+		replies[i] = reply
+	}
+
+	return replies, nil
+}
+
+// Turn an error reply into an error.
+func replyError(reply any) (any, error) {
+	if err, ok := reply.(Error); ok {
+		return nil, err
+	}
+
+	return reply, nil
+}
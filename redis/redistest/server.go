@@ -0,0 +1,567 @@
+// Package redistest provides an in-process Redis server for tests.
+//
+// The server speaks enough of RESP to exercise clients of the framework: the
+// string, counter and key commands plus AUTH, SELECT and PING, and EVAL of the
+// scripts the framework sends. It keeps every database in memory and honors
+// expiry lazily on access.
+package redistest
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/garavel-core/framework/redis"
+)
+
+// A value held by the fake server.
+type entry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+type Server struct {
+	// The listener accepting client connections.
+	listener net.Listener
+	// The databases of the server, indexed by number.
+	databases map[int]map[string]entry
+	// Guards the databases.
+	mutex sync.Mutex
+	// The open client connections.
+	conns map[net.Conn]struct{}
+	// Tracks the connection handlers.
+	wg sync.WaitGroup
+	// The commands received, in order.
+	commands [][]string
+	// The clock the server reads the current time from.
+	clock func() time.Time
+}
+
+// Start a new fake Redis server on a random local port.
+func NewServer() *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		panic(fmt.Sprintf("redistest: failed to listen: %v", err))
+	}
+
+	server := &Server{
+		listener:  listener,
+		databases: make(map[int]map[string]entry),
+		conns:     make(map[net.Conn]struct{}),
+		clock:     time.Now,
+	}
+
+	server.wg.Add(1)
+
+	go server.serve()
+
+	return server
+}
+
+// Get the "host:port" address of the server.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Set the clock the server reads the current time from to expire keys.
+func (s *Server) SetClock(clock func() time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.clock = clock
+}
+
+// Get the names of every command received so far, upper cased.
+func (s *Server) Commands() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	names := make([]string, len(s.commands))
+
+	for i, command := range s.commands {
+		names[i] = command[0]
+	}
+
+	return names
+}
+
+// Stop the server and disconnect every client.
+func (s *Server) Close() {
+	s.listener.Close()
+
+	s.mutex.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mutex.Unlock()
+
+	s.wg.Wait()
+}
+
+// Accept client connections until the listener closes.
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+
+		if err != nil {
+			return
+		}
+
+		s.mutex.Lock()
+		s.conns[conn] = struct{}{}
+		s.mutex.Unlock()
+
+		s.wg.Add(1)
+
+		go s.handle(conn)
+	}
+}
+
+// Serve the commands of a single client.
+func (s *Server) handle(conn net.Conn) {
+	defer s.wg.Done()
+
+	defer func() {
+		conn.Close()
+
+		s.mutex.Lock()
+		delete(s.conns, conn)
+		s.mutex.Unlock()
+	}()
+
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+	database := 0
+
+	for {
+		reply, err := redis.ReadReply(reader)
+
+		if err != nil {
+			return
+		}
+
+		args, ok := commandArguments(reply)
+
+		if !ok {
+			writeError(writer, "ERR Protocol error")
+		} else {
+			s.exec(writer, &database, args)
+		}
+
+		// Pipelined commands are answered together once the client stops sending.
+		if reader.Buffered() == 0 {
+			if err := writer.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Execute a single command.
+func (s *Server) exec(w *bufio.Writer, database *int, args []string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	args[0] = strings.ToUpper(args[0])
+	s.commands = append(s.commands, args)
+
+	db := s.databases[*database]
+
+	if db == nil {
+		db = make(map[string]entry)
+		s.databases[*database] = db
+	}
+
+	now := s.clock()
+
+	get := func(key string) (entry, bool) {
+		item, exists := db[key]
+
+		if exists && !item.expiresAt.IsZero() && !now.Before(item.expiresAt) {
+			delete(db, key)
+
+			return entry{}, false
+		}
+
+		return item, exists
+	}
+
+	// Read the integer held by a key, zero when missing, replying with an
+	// error when the key holds something else.
+	counter := func(key string) (entry, int64, bool) {
+		item, _ := get(key)
+
+		if item.value == nil {
+			return item, 0, true
+		}
+
+		current, err := strconv.ParseInt(string(item.value), 10, 64)
+
+		if err != nil {
+			writeError(w, "ERR value is not an integer or out of range")
+
+			return item, 0, false
+		}
+
+		return item, current, true
+	}
+
+	// Write the new value of a counter and reply with it.
+	count := func(key string, item entry, value int64) {
+		item.value = strconv.AppendInt(nil, value, 10)
+		db[key] = item
+
+		writeInteger(w, value)
+	}
+
+	// Add the step to a counter and reply with the new value.
+	increment := func(key string, step int64) {
+		item, current, ok := counter(key)
+
+		if !ok {
+			return
+		}
+
+		if (step > 0 && current > math.MaxInt64-step) || (step < 0 && current < math.MinInt64-step) {
+			writeError(w, "ERR increment or decrement would overflow")
+
+			return
+		}
+
+		count(key, item, current+step)
+	}
+
+	switch name, argc := args[0], len(args)-1; {
+	case name == "PING":
+		writeSimple(w, "PONG")
+	case name == "AUTH" && argc >= 1:
+		writeSimple(w, "OK")
+	case name == "SELECT" && argc == 1:
+		index, err := strconv.Atoi(args[1])
+
+		if err != nil {
+			writeError(w, "ERR invalid DB index")
+
+			return
+		}
+
+		*database = index
+
+		writeSimple(w, "OK")
+	case name == "GET" && argc == 1:
+		if item, ok := get(args[1]); ok {
+			writeBulk(w, item.value)
+		} else {
+			writeBulk(w, nil)
+		}
+	case name == "MGET" && argc >= 1:
+		fmt.Fprintf(w, "*%d\r\n", argc)
+
+		for _, key := range args[1:] {
+			if item, ok := get(key); ok {
+				writeBulk(w, item.value)
+			} else {
+				writeBulk(w, nil)
+			}
+		}
+	case name == "SET" && argc >= 2:
+		item := entry{value: []byte(args[2])}
+		nx, xx := false, false
+
+		for i := 3; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "NX":
+				nx = true
+			case "XX":
+				xx = true
+			case "EX", "PX":
+				if i+1 >= len(args) {
+					writeError(w, "ERR syntax error")
+
+					return
+				}
+
+				amount, err := strconv.ParseInt(args[i+1], 10, 64)
+
+				if err != nil || amount <= 0 {
+					writeError(w, "ERR invalid expire time in 'set' command")
+
+					return
+				}
+
+				unit := time.Second
+
+				if strings.ToUpper(args[i]) == "PX" {
+					unit = time.Millisecond
+				}
+
+				item.expiresAt = now.Add(time.Duration(amount) * unit)
+				i++
+			default:
+				writeError(w, "ERR syntax error")
+
+				return
+			}
+		}
+
+		if _, exists := get(args[1]); (nx && exists) || (xx && !exists) {
+			writeBulk(w, nil)
+
+			return
+		}
+
+		db[args[1]] = item
+
+		writeSimple(w, "OK")
+	case (name == "SETEX" || name == "PSETEX") && argc == 3:
+		amount, err := strconv.ParseInt(args[2], 10, 64)
+
+		if err != nil || amount <= 0 {
+			writeError(w, "ERR invalid expire time in '"+strings.ToLower(name)+"' command")
+
+			return
+		}
+
+		unit := time.Second
+
+		if name == "PSETEX" {
+			unit = time.Millisecond
+		}
+
+		db[args[1]] = entry{value: []byte(args[3]), expiresAt: now.Add(time.Duration(amount) * unit)}
+
+		writeSimple(w, "OK")
+	case (name == "INCR" || name == "DECR") && argc == 1, (name == "INCRBY" || name == "DECRBY") && argc == 2:
+		step := int64(1)
+
+		if argc == 2 {
+			var err error
+
+			if step, err = strconv.ParseInt(args[2], 10, 64); err != nil {
+				writeError(w, "ERR value is not an integer or out of range")
+
+				return
+			}
+		}
+
+		if strings.HasPrefix(name, "DECR") {
+			if step == math.MinInt64 {
+				writeError(w, "ERR decrement would overflow")
+
+				return
+			}
+
+			step = -step
+		}
+
+		increment(args[1], step)
+	case name == "INCRBYFLOAT" && argc == 2:
+		step, err := strconv.ParseFloat(args[2], 64)
+
+		if err != nil {
+			writeError(w, "ERR value is not a valid float")
+
+			return
+		}
+
+		item, _ := get(args[1])
+		current := float64(0)
+
+		if item.value != nil {
+			if current, err = strconv.ParseFloat(string(item.value), 64); err != nil {
+				writeError(w, "ERR value is not a valid float")
+
+				return
+			}
+		}
+
+		result := current + step
+
+		if math.IsNaN(result) || math.IsInf(result, 0) {
+			writeError(w, "ERR increment would produce NaN or Infinity")
+
+			return
+		}
+
+		item.value = []byte(strconv.FormatFloat(result, 'f', -1, 64))
+		db[args[1]] = item
+
+		writeBulk(w, item.value)
+	case name == "EVAL" && argc >= 2:
+		numkeys, err := strconv.Atoi(args[2])
+
+		if err != nil || numkeys < 0 || numkeys > argc-2 {
+			writeError(w, "ERR Number of keys can't be greater than number of args")
+
+			return
+		}
+
+		keys, arguments := args[3:3+numkeys], args[3+numkeys:]
+
+		// There is no Lua interpreter: the scripts of the framework are told
+		// apart by the name on their first line and run natively.
+		script := strings.SplitN(args[1], "\n", 2)[0]
+
+		if len(keys) != 1 || len(arguments) != 1 {
+			writeError(w, "NOSCRIPT the fake server cannot run this script")
+
+			return
+		}
+
+		step, err := strconv.ParseInt(arguments[0], 10, 64)
+
+		if err != nil {
+			writeError(w, "ERR value is not an integer or out of range")
+
+			return
+		}
+
+		switch script {
+		case "-- cache:increment-existing":
+			if _, exists := get(keys[0]); !exists {
+				writeBulk(w, nil)
+
+				return
+			}
+
+			increment(keys[0], step)
+		case "-- cache:floored-decrement":
+			item, current, ok := counter(keys[0])
+
+			if !ok {
+				return
+			}
+
+			if step == math.MinInt64 || (step > 0 && current < math.MinInt64+step) || (step < 0 && current > math.MaxInt64+step) {
+				writeError(w, "ERR increment or decrement would overflow")
+
+				return
+			}
+
+			result := current - step
+
+			if result < 0 {
+				result = current
+
+				if current > 0 {
+					result = 0
+				}
+			}
+
+			count(keys[0], item, result)
+		default:
+			writeError(w, "NOSCRIPT the fake server cannot run this script")
+		}
+	case name == "DEL" && argc >= 1, name == "EXISTS" && argc >= 1:
+		count := int64(0)
+
+		for _, key := range args[1:] {
+			if _, exists := get(key); exists {
+				count++
+
+				if name == "DEL" {
+					delete(db, key)
+				}
+			}
+		}
+
+		writeInteger(w, count)
+	case name == "EXPIRE" && argc == 2:
+		seconds, err := strconv.ParseInt(args[2], 10, 64)
+
+		if err != nil {
+			writeError(w, "ERR value is not an integer or out of range")
+
+			return
+		}
+
+		item, exists := get(args[1])
+
+		if !exists {
+			writeInteger(w, 0)
+
+			return
+		}
+
+		item.expiresAt = now.Add(time.Duration(seconds) * time.Second)
+		db[args[1]] = item
+
+		writeInteger(w, 1)
+	case name == "TTL" && argc == 1:
+		item, exists := get(args[1])
+
+		switch {
+		case !exists:
+			writeInteger(w, -2)
+		case item.expiresAt.IsZero():
+			writeInteger(w, -1)
+		default:
+			writeInteger(w, int64(item.expiresAt.Sub(now).Round(time.Second)/time.Second))
+		}
+	case name == "FLUSHDB":
+		s.databases[*database] = make(map[string]entry)
+
+		writeSimple(w, "OK")
+	case name == "FLUSHALL":
+		s.databases = make(map[int]map[string]entry)
+
+		writeSimple(w, "OK")
+	default:
+		writeError(w, fmt.Sprintf("ERR unknown command '%s'", args[0]))
+	}
+}
+
+// Convert a RESP array of bulk strings into command arguments.
+func commandArguments(reply any) ([]string, bool) {
+	values, ok := reply.([]any)
+
+	if !ok || len(values) == 0 {
+		return nil, false
+	}
+
+	args := make([]string, len(values))
+
+	for i, value := range values {
+		data, ok := value.([]byte)
+
+		if !ok {
+			return nil, false
+		}
+
+		args[i] = string(data)
+	}
+
+	return args, true
+}
+
+func writeSimple(w *bufio.Writer, value string) {
+	fmt.Fprintf(w, "+%s\r\n", value)
+}
+
+func writeError(w *bufio.Writer, message string) {
+	fmt.Fprintf(w, "-%s\r\n", message)
+}
+
+func writeInteger(w *bufio.Writer, value int64) {
+	fmt.Fprintf(w, ":%d\r\n", value)
+}
+
+func writeBulk(w *bufio.Writer, value []byte) {
+	if value == nil {
+		w.WriteString("$-1\r\n")
+
+		return
+	}
+
+	fmt.Fprintf(w, "$%d\r\n", len(value))
+	w.Write(value)
+	w.WriteString("\r\n")
+}
@@ -0,0 +1,145 @@
+package redis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// An error reply sent by the Redis server.
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+// The error returned when a reply is not valid RESP.
+var ErrProtocol = errors.New("redis: invalid RESP reply")
+
+// Write the given arguments as a RESP array of bulk strings.
+func WriteCommand(writer *bufio.Writer, args ...any) error {
+	if _, err := fmt.Fprintf(writer, "*%d\r\n", len(args)); err != nil {
+		return err
+	}
+
+	for _, arg := range args {
+		data := argumentBytes(arg)
+
+		if _, err := fmt.Fprintf(writer, "$%d\r\n", len(data)); err != nil {
+			return err
+		}
+
+		if _, err := writer.Write(data); err != nil {
+			return err
+		}
+
+		if _, err := writer.WriteString("\r\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Read a single RESP reply.
+//
+// Simple strings are returned as string, integers as int64, bulk strings as
+// []byte and arrays as []any. Null bulk strings and arrays are returned as nil
+// and error replies as an Error value in the reply, not as the read error.
+func ReadReply(reader *bufio.Reader) (any, error) {
+	line, err := readLine(reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) == 0 {
+		return nil, ErrProtocol
+	}
+
+	switch line[0] {
+	case '+':
+		return string(line[1:]), nil
+	case '-':
+		return Error(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(string(line[1:]), 10, 64)
+	case '$':
+		length, err := strconv.Atoi(string(line[1:]))
+
+		if err != nil {
+			return nil, ErrProtocol
+		}
+
+		if length < 0 {
+			return nil, nil
+		}
+
+		data := make([]byte, length+2)
+
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+
+		return data[:length], nil
+	case '*':
+		length, err := strconv.Atoi(string(line[1:]))
+
+		if err != nil {
+			return nil, ErrProtocol
+		}
+
+		if length < 0 {
+			return nil, nil
+		}
+
+		values := make([]any, length)
+
+		for i := range values {
+			if values[i], err = ReadReply(reader); err != nil {
+				return nil, err
+			}
+		}
+
+		return values, nil
+	}
+
+	return nil, ErrProtocol
+}
+
+// Read a CRLF terminated line without its terminator.
+func readLine(reader *bufio.Reader) ([]byte, error) {
+	line, err := reader.ReadSlice('\n')
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, ErrProtocol
+	}
+
+	return line[:len(line)-2], nil
+}
+
+// Get the wire representation of a command argument.
+func argumentBytes(arg any) []byte {
+	switch v := arg.(type) {
+	case []byte:
+		return v
+	case string:
+		return []byte(v)
+	case int:
+		return strconv.AppendInt(nil, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(nil, v, 10)
+	case float64:
+		return strconv.AppendFloat(nil, v, 'f', -1, 64)
+	case nil:
+		return []byte{}
+	}
+
+	return []byte(fmt.Sprint(arg))
+}
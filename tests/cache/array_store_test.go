@@ -0,0 +1,82 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/garavel-core/framework/cache"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArrayStore(t *testing.T) {
+	t.Run("items can be set and retrieved", func(t *testing.T) {
+		store := cache.NewArrayStore()
+		assert.True(t, store.Put("foo", "bar", 10))
+		assert.Equal(t, "bar", store.Get("foo"))
+		assert.Nil(t, store.Get("missing"))
+	})
+
+	t.Run("multiple items can be set and retrieved", func(t *testing.T) {
+		store := cache.NewArrayStore()
+		assert.True(t, store.PutMany(map[string]any{"foo": "bar", "baz": "qux"}, 10))
+		assert.Equal(t, map[string]any{"foo": "bar", "baz": "qux", "quz": nil}, store.Many([]string{"foo", "baz", "quz"}))
+	})
+
+	t.Run("items expire", func(t *testing.T) {
+		store := cache.NewArrayStore()
+		store.Add("foo", "bar", 50*time.Millisecond)
+		assert.Equal(t, "bar", store.Get("foo"))
+		time.Sleep(60 * time.Millisecond)
+		assert.Nil(t, store.Get("foo"))
+	})
+
+	t.Run("expired items are pruned", func(t *testing.T) {
+		store := cache.NewArrayStore()
+		store.Add("foo", "bar", time.Millisecond)
+		store.Forever("baz", "qux")
+		time.Sleep(5 * time.Millisecond)
+		assert.Equal(t, 1, store.Prune())
+		assert.Equal(t, "qux", store.Get("baz"))
+	})
+
+	t.Run("add only stores missing items", func(t *testing.T) {
+		store := cache.NewArrayStore()
+		assert.True(t, store.Add("foo", "bar", 10))
+		assert.False(t, store.Add("foo", "baz", 10))
+		assert.Equal(t, "bar", store.Get("foo"))
+	})
+
+	t.Run("values can be incremented and decremented", func(t *testing.T) {
+		store := cache.NewArrayStore()
+		assert.Equal(t, 1, store.Increment("foo"))
+		assert.Equal(t, 11, store.Increment("foo", 10))
+		assert.Equal(t, 9, store.Decrement("foo", 2))
+		assert.Equal(t, -1, store.Decrement("bar"))
+	})
+
+	t.Run("items can be forgotten and flushed", func(t *testing.T) {
+		store := cache.NewArrayStore()
+		store.Forever("foo", "bar")
+		store.Forever("baz", "qux")
+		assert.True(t, store.Forget("foo"))
+		assert.False(t, store.Forget("foo"))
+		assert.True(t, store.Flush())
+		assert.Nil(t, store.Get("baz"))
+	})
+
+	t.Run("serialized values are copies", func(t *testing.T) {
+		store := cache.NewArrayStore(true)
+		value := []int{1, 2, 3}
+		store.Forever("foo", value)
+		value[0] = 42
+		assert.Equal(t, []int{1, 2, 3}, store.Get("foo"))
+		assert.Equal(t, 2, store.Increment("bar", 2))
+	})
+
+	t.Run("unserializable values are rejected", func(t *testing.T) {
+		store := cache.NewArrayStore(true)
+		assert.False(t, store.Forever("foo", func() {}))
+		assert.Nil(t, store.Get("foo"))
+	})
+}
@@ -0,0 +1,195 @@
+package cache_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/garavel-core/framework/cache"
+	contracts "github.com/garavel-core/framework/contracts/cache"
+	"github.com/garavel-core/framework/events"
+	"github.com/garavel-core/framework/redis/redistest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManager(t *testing.T) {
+	newManager := func(t *testing.T) *cache.Manager {
+		return cache.NewManager(map[string]any{
+			"default": "memory",
+			"prefix":  "app",
+			"stores": map[string]any{
+				"memory": map[string]any{"driver": "array"},
+				"file":   map[string]any{"driver": "file", "path": t.TempDir()},
+				"custom": map[string]any{"driver": "mongo"},
+				"broken": map[string]any{"driver": "unknown"},
+			},
+		})
+	}
+
+	t.Run("the default store is resolved once", func(t *testing.T) {
+		manager := newManager(t)
+		repository, err := manager.Store()
+		assert.NoError(t, err)
+		assert.IsType(t, &cache.ArrayStore{}, repository.GetStore())
+		assert.Equal(t, "memory", repository.GetName())
+
+		again, _ := manager.Store("memory")
+		assert.Same(t, repository, again)
+	})
+
+	t.Run("named stores are resolved from their driver", func(t *testing.T) {
+		manager := newManager(t)
+		repository, err := manager.Store("file")
+		assert.NoError(t, err)
+		assert.IsType(t, &cache.FileStore{}, repository.GetStore())
+
+		null, err := manager.Store("null")
+		assert.NoError(t, err)
+		assert.IsType(t, &cache.NullStore{}, null.GetStore())
+	})
+
+	t.Run("undefined stores and drivers are reported", func(t *testing.T) {
+		manager := newManager(t)
+		_, err := manager.Store("missing")
+		assert.EqualError(t, err, "cache store [missing] is not defined")
+		_, err = manager.Store("broken")
+		assert.EqualError(t, err, "driver [unknown] is not supported")
+
+		manager.SetDefaultDriver("")
+		_, err = manager.Store()
+		assert.EqualError(t, err, "cache store [] is not defined")
+	})
+
+	t.Run("custom drivers can be registered", func(t *testing.T) {
+		manager := newManager(t)
+		manager.Extend("mongo", func(config map[string]any) (contracts.Store, error) {
+			assert.Equal(t, "custom", config["store"])
+			return cache.NewArrayStore(true), nil
+		})
+		repository, err := manager.Store("custom")
+		assert.NoError(t, err)
+		assert.True(t, repository.Forever("foo", "bar"))
+
+		manager.Extend("unknown", func(config map[string]any) (contracts.Store, error) {
+			return nil, errors.New("unreachable")
+		})
+		manager.SetDefaultDriver("broken")
+		_, err = manager.Store()
+		assert.EqualError(t, err, "unreachable")
+	})
+
+	t.Run("custom drivers can build on other stores", func(t *testing.T) {
+		manager := newManager(t)
+		manager.Extend("mongo", func(config map[string]any) (contracts.Store, error) {
+			memory, err := manager.Store("memory")
+
+			if err != nil {
+				return nil, err
+			}
+
+			return cache.NewTieredStore(cache.NewArrayStore(), memory.GetStore()), nil
+		})
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := manager.Store("custom")
+			done <- err
+		}()
+
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(3 * time.Second):
+			t.Fatal("resolving the custom store blocked")
+		}
+
+		custom, _ := manager.Store("custom")
+		memory, _ := manager.Store("memory")
+		custom.Forever("foo", "bar")
+		assert.Equal(t, "bar", memory.Get("foo"))
+	})
+
+	t.Run("forgotten drivers are resolved again", func(t *testing.T) {
+		manager := newManager(t)
+		first, _ := manager.Store()
+		manager.ForgetDriver()
+		second, _ := manager.Store()
+		assert.NotSame(t, first, second)
+	})
+
+	t.Run("purged redis stores reconnect", func(t *testing.T) {
+		server := redistest.NewServer()
+		defer server.Close()
+
+		manager := cache.NewManager(map[string]any{
+			"default": "redis",
+			"stores": map[string]any{
+				"redis": map[string]any{"driver": "redis", "address": server.Addr(), "prefix": "app"},
+			},
+		})
+
+		repository, err := manager.Store()
+		assert.NoError(t, err)
+		assert.Equal(t, "app:", repository.GetStore().GetPrefix())
+		assert.True(t, repository.Forever("foo", "bar"))
+
+		manager.Purge()
+		assert.Nil(t, repository.Get("foo"))
+
+		repository, _ = manager.Store()
+		assert.Equal(t, "bar", repository.Get("foo"))
+	})
+
+	t.Run("forgotten redis stores close their connection", func(t *testing.T) {
+		server := redistest.NewServer()
+		defer server.Close()
+
+		manager := cache.NewManager(map[string]any{
+			"default": "redis",
+			"stores": map[string]any{
+				"redis": map[string]any{"driver": "redis", "address": server.Addr()},
+			},
+		})
+
+		first, _ := manager.Store()
+		assert.True(t, first.Forever("foo", "bar"))
+
+		manager.ForgetDriver("redis")
+		assert.Nil(t, first.Get("foo"))
+
+		second, _ := manager.Store()
+		assert.NotSame(t, first, second)
+		assert.Equal(t, "bar", second.Get("foo"))
+	})
+
+	t.Run("the default store can change while stores are resolved", func(t *testing.T) {
+		manager := newManager(t)
+		done := make(chan struct{})
+
+		go func() {
+			defer close(done)
+
+			for i := 0; i < 100; i++ {
+				manager.SetDefaultDriver("file")
+				manager.SetDefaultDriver("memory")
+			}
+		}()
+
+		for i := 0; i < 100; i++ {
+			_, err := manager.Store()
+			assert.NoError(t, err)
+			assert.Contains(t, []string{"file", "memory"}, manager.GetDefaultDriver())
+		}
+
+		<-done
+	})
+
+	t.Run("repositories receive the event dispatcher", func(t *testing.T) {
+		manager := newManager(t)
+		dispatcher := events.NewDispatcher()
+		manager.SetEventDispatcher(dispatcher)
+		repository, _ := manager.Store()
+		assert.Same(t, dispatcher, repository.GetEventDispatcher())
+	})
+}
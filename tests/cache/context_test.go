@@ -0,0 +1,253 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/garavel-core/framework/cache"
+	contracts "github.com/garavel-core/framework/contracts/cache"
+	"github.com/garavel-core/framework/memcached"
+	"github.com/garavel-core/framework/redis"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Listen on a local address that accepts connections but never replies.
+func newSilentServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+
+			if err != nil {
+				return
+			}
+
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestContextStore(t *testing.T) {
+	stores := map[string]func(t *testing.T) contracts.Store{
+		"array": func(t *testing.T) contracts.Store {
+			return cache.NewArrayStore()
+		},
+		"redis": func(t *testing.T) contracts.Store {
+			store, _ := newRedisStore(t)
+			return store
+		},
+		"memcached": func(t *testing.T) contracts.Store {
+			store, _ := newMemcachedStore(t)
+			return store
+		},
+	}
+
+	for name, factory := range stores {
+		t.Run(name, func(t *testing.T) {
+			t.Run("items are stored and retrieved", func(t *testing.T) {
+				store := cache.AsContextStore(factory(t))
+				ctx := context.Background()
+
+				assert.NoError(t, store.PutCtx(ctx, "foo", "bar", 60))
+				value, found, err := store.GetCtx(ctx, "foo")
+				assert.NoError(t, err)
+				assert.True(t, found)
+				assert.Equal(t, "bar", value)
+
+				value, found, err = store.GetCtx(ctx, "missing")
+				assert.NoError(t, err)
+				assert.False(t, found)
+				assert.Nil(t, value)
+
+				assert.NoError(t, store.PutManyCtx(ctx, map[string]any{"a": "1", "b": "2"}, 60))
+				values, err := store.ManyCtx(ctx, []string{"a", "b", "c"})
+				assert.NoError(t, err)
+				assert.Equal(t, map[string]any{"a": "1", "b": "2", "c": nil}, values)
+
+				added, err := store.AddCtx(ctx, "foo", "baz", 60)
+				assert.NoError(t, err)
+				assert.False(t, added)
+				added, err = store.AddCtx(ctx, "new", "baz", 0)
+				assert.NoError(t, err)
+				assert.True(t, added)
+
+				assert.NoError(t, store.ForeverCtx(ctx, "count", 1))
+				count, err := store.IncrementCtx(ctx, "count", 4)
+				assert.NoError(t, err)
+				assert.Equal(t, int64(5), count)
+				count, err = store.DecrementCtx(ctx, "count", 2)
+				assert.NoError(t, err)
+				assert.Equal(t, int64(3), count)
+
+				_, err = store.IncrementCtx(ctx, "missing", 1)
+				assert.ErrorIs(t, err, cache.ErrMissing)
+				_, err = store.DecrementCtx(ctx, "missing", 1)
+				assert.ErrorIs(t, err, cache.ErrMissing)
+				_, found, _ = store.GetCtx(ctx, "missing")
+				assert.False(t, found)
+
+				forgotten, err := store.ForgetCtx(ctx, "foo")
+				assert.NoError(t, err)
+				assert.True(t, forgotten)
+
+				assert.NoError(t, store.FlushCtx(ctx))
+				_, found, _ = store.GetCtx(ctx, "new")
+				assert.False(t, found)
+			})
+
+			t.Run("cancelled contexts are reported", func(t *testing.T) {
+				store := cache.AsContextStore(factory(t))
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				_, _, err := store.GetCtx(ctx, "foo")
+				assert.ErrorIs(t, err, context.Canceled)
+				assert.ErrorIs(t, store.PutCtx(ctx, "foo", "bar", 60), context.Canceled)
+			})
+		})
+	}
+
+	t.Run("native stores are returned as they are", func(t *testing.T) {
+		store, _ := newRedisStore(t)
+
+		assert.Same(t, store, cache.AsContextStore(store))
+	})
+
+	t.Run("failed writes of adapted stores are reported", func(t *testing.T) {
+		store := cache.AsContextStore(failingStore{cache.NewArrayStore()})
+
+		assert.ErrorIs(t, store.PutCtx(context.Background(), "foo", "bar", 60), cache.ErrStoreFailed)
+	})
+
+	t.Run("network drivers surface real errors", func(t *testing.T) {
+		redisStore := cache.NewRedisStore(redis.NewConnection(redis.Options{Address: "127.0.0.1:1", DialTimeout: time.Second}))
+		memcachedStore := cache.NewMemcachedStore(memcached.NewClient(memcached.Options{Address: "127.0.0.1:1", DialTimeout: time.Second}))
+
+		for _, store := range []contracts.ContextStore{redisStore, memcachedStore} {
+			value, found, err := store.GetCtx(context.Background(), "foo")
+			assert.Error(t, err)
+			assert.False(t, found)
+			assert.Nil(t, value)
+		}
+
+		assert.Nil(t, redisStore.Get("foo"))
+		assert.Nil(t, memcachedStore.Get("foo"))
+	})
+
+	t.Run("network drivers give up when the context is done", func(t *testing.T) {
+		address := newSilentServer(t)
+		redisStore := cache.NewRedisStore(redis.NewConnection(redis.Options{Address: address}))
+		memcachedStore := cache.NewMemcachedStore(memcached.NewClient(memcached.Options{Address: address}))
+
+		for _, store := range []contracts.ContextStore{redisStore, memcachedStore} {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			_, _, err := store.GetCtx(ctx, "foo")
+			cancel()
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+			ctx, cancel = context.WithCancel(context.Background())
+			time.AfterFunc(50*time.Millisecond, cancel)
+			err = store.PutCtx(ctx, "foo", "bar", 60)
+			assert.ErrorIs(t, err, context.Canceled)
+		}
+	})
+
+	t.Run("memcached counters refuse the smallest step", func(t *testing.T) {
+		store, _ := newMemcachedStore(t)
+		store.Forever("count", 1)
+
+		_, err := store.IncrementCtx(context.Background(), "count", math.MinInt64)
+		assert.ErrorIs(t, err, cache.ErrOverflow)
+
+		_, err = store.DecrementCtx(context.Background(), "count", math.MinInt64)
+		assert.ErrorIs(t, err, cache.ErrOverflow)
+		assert.Equal(t, 1, store.Get("count"))
+	})
+}
+
+func TestRepositoryContext(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("items are read and written with errors", func(t *testing.T) {
+		repository := cache.NewRepository(cache.NewArrayStore())
+
+		assert.NoError(t, repository.PutCtx(ctx, "foo", "bar", 60))
+		value, found, err := repository.GetCtx(ctx, "foo")
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "bar", value)
+
+		has, err := repository.HasCtx(ctx, "missing")
+		assert.NoError(t, err)
+		assert.False(t, has)
+
+		value, found, err = repository.PullCtx(ctx, "foo")
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "bar", value)
+		assert.Nil(t, repository.Get("foo"))
+	})
+
+	t.Run("store failures are not reported as misses", func(t *testing.T) {
+		repository := cache.NewRepository(cache.NewRedisStore(redis.NewConnection(redis.Options{Address: "127.0.0.1:1"})))
+
+		_, found, err := repository.GetCtx(ctx, "foo")
+		assert.Error(t, err)
+		assert.False(t, found)
+
+		_, err = repository.RememberCtx(ctx, "foo", 60, func(ctx context.Context) (any, error) {
+			t.Fatal("the callback should not run when the store fails")
+			return nil, nil
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("remember stores the value unless the callback fails", func(t *testing.T) {
+		repository := cache.NewRepository(cache.NewArrayStore())
+		failure := errors.New("failure")
+
+		_, err := repository.RememberCtx(ctx, "foo", 60, func(ctx context.Context) (any, error) {
+			return nil, failure
+		})
+		assert.ErrorIs(t, err, failure)
+		assert.Nil(t, repository.Get("foo"))
+
+		value, err := repository.RememberForeverCtx(ctx, "foo", func(ctx context.Context) (any, error) {
+			return "bar", nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "bar", value)
+
+		value, err = repository.RememberCtx(ctx, "foo", 60, func(ctx context.Context) (any, error) {
+			return "baz", nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "bar", value)
+	})
+
+	t.Run("counters and clearing use the scope of the repository", func(t *testing.T) {
+		repository := cache.NewRepository(cache.NewArrayStore())
+		users := repository.Namespace("users")
+
+		repository.Forever("global", "kept")
+		assert.NoError(t, users.ForeverCtx(ctx, "visits", 1))
+		visits, err := users.IncrementCtx(ctx, "visits", 2)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), visits)
+
+		assert.NoError(t, users.ClearCtx(ctx))
+		assert.Nil(t, users.Get("visits"))
+		assert.Equal(t, "kept", repository.Get("global"))
+	})
+}
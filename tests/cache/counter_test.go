@@ -0,0 +1,295 @@
+package cache_test
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/garavel-core/framework/cache"
+	contracts "github.com/garavel-core/framework/contracts/cache"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// A store hiding the counter support of the store it wraps.
+type plainStore struct {
+	contracts.Store
+}
+
+// Get the stores every counter conformance test runs against.
+func counterStores() map[string]func(t *testing.T) contracts.Store {
+	return map[string]func(t *testing.T) contracts.Store{
+		"array": func(t *testing.T) contracts.Store {
+			return cache.NewArrayStore()
+		},
+		"serialized array": func(t *testing.T) contracts.Store {
+			return cache.NewArrayStore(true)
+		},
+		"memory": func(t *testing.T) contracts.Store {
+			return cache.NewMemoryStore()
+		},
+		"file": func(t *testing.T) contracts.Store {
+			return cache.NewFileStore(t.TempDir())
+		},
+		"redis": func(t *testing.T) contracts.Store {
+			store, _ := newRedisStore(t, "app")
+			return store
+		},
+		"memcached": func(t *testing.T) contracts.Store {
+			store, _ := newMemcachedStore(t, "app")
+			return store
+		},
+		"tiered": func(t *testing.T) contracts.Store {
+			return cache.NewTieredStore(cache.NewArrayStore(), cache.NewArrayStore())
+		},
+		"failover": func(t *testing.T) contracts.Store {
+			return cache.NewFailoverStore(cache.NewArrayStore(), cache.NewArrayStore())
+		},
+		"transforming": func(t *testing.T) contracts.Store {
+			return cache.NewTransformingStore(cache.NewArrayStore())
+		},
+	}
+}
+
+func TestCounters(t *testing.T) {
+	for name, factory := range counterStores() {
+		t.Run(name, func(t *testing.T) {
+			t.Run("missing items start at zero", func(t *testing.T) {
+				repository := cache.NewRepository(factory(t))
+
+				result, err := repository.IncrementBy("counter", 5)
+				assert.NoError(t, err)
+				assert.Equal(t, int64(5), result)
+
+				result, err = repository.IncrementBy("counter", -8)
+				assert.NoError(t, err)
+				assert.Equal(t, int64(-3), result)
+				assert.Equal(t, -3, repository.Get("counter"))
+
+				result, err = repository.DecrementBy("other", 2)
+				assert.NoError(t, err)
+				assert.Equal(t, int64(-2), result)
+			})
+
+			t.Run("floored decrements stop at zero", func(t *testing.T) {
+				repository := cache.NewRepository(factory(t))
+				repository.Forever("counter", 3)
+
+				result, err := repository.DecrementBy("counter", 5, true)
+				assert.NoError(t, err)
+				assert.Equal(t, int64(0), result)
+				assert.Equal(t, 0, repository.Get("counter"))
+
+				result, err = repository.DecrementBy("missing", 1, true)
+				assert.NoError(t, err)
+				assert.Equal(t, int64(0), result)
+
+				result, err = repository.DecrementBy("counter", -4, true)
+				assert.NoError(t, err)
+				assert.Equal(t, int64(4), result)
+
+				repository.Forever("negative", -2)
+
+				result, err = repository.DecrementBy("negative", 1, true)
+				assert.NoError(t, err)
+				assert.Equal(t, int64(-2), result)
+
+				result, err = repository.DecrementBy("negative", 1)
+				assert.NoError(t, err)
+				assert.Equal(t, int64(-3), result)
+			})
+
+			t.Run("items that are not numbers are left untouched", func(t *testing.T) {
+				repository := cache.NewRepository(factory(t))
+				repository.Forever("string", "abc")
+				repository.Forever("float", 2.5)
+
+				_, err := repository.IncrementBy("string", 1)
+				assert.ErrorIs(t, err, cache.ErrNotNumeric)
+
+				_, err = repository.DecrementBy("string", 1, true)
+				assert.ErrorIs(t, err, cache.ErrNotNumeric)
+
+				_, err = repository.IncrementFloat("string", 1)
+				assert.ErrorIs(t, err, cache.ErrNotNumeric)
+				assert.Equal(t, "abc", repository.Get("string"))
+
+				_, err = repository.IncrementBy("float", 1)
+				assert.ErrorIs(t, err, cache.ErrNotNumeric)
+				assert.Equal(t, 2.5, repository.Get("float"))
+			})
+
+			t.Run("integral floats count as integers", func(t *testing.T) {
+				repository := cache.NewRepository(factory(t))
+				repository.Forever("counter", 3.0)
+
+				result, err := repository.IncrementBy("counter", 1)
+				assert.NoError(t, err)
+				assert.Equal(t, int64(4), result)
+			})
+
+			t.Run("overflows are refused", func(t *testing.T) {
+				repository := cache.NewRepository(factory(t))
+				repository.Forever("max", math.MaxInt64)
+				repository.Forever("min", math.MinInt64)
+				repository.Forever("float", math.MaxFloat64)
+
+				_, err := repository.IncrementBy("max", 1)
+				assert.ErrorIs(t, err, cache.ErrOverflow)
+				assert.Equal(t, math.MaxInt64, repository.Get("max"))
+
+				_, err = repository.DecrementBy("min", 1)
+				assert.ErrorIs(t, err, cache.ErrOverflow)
+				assert.Equal(t, math.MinInt64, repository.Get("min"))
+
+				_, err = repository.DecrementBy("missing", math.MinInt64)
+				assert.ErrorIs(t, err, cache.ErrOverflow)
+
+				_, err = repository.IncrementFloat("float", math.MaxFloat64)
+				assert.ErrorIs(t, err, cache.ErrOverflow)
+				assert.Equal(t, math.MaxFloat64, repository.Get("float"))
+			})
+
+			t.Run("float counters accept integers and floats", func(t *testing.T) {
+				repository := cache.NewRepository(factory(t))
+				repository.Forever("integer", 2)
+
+				result, err := repository.IncrementFloat("float", 1.5)
+				assert.NoError(t, err)
+				assert.Equal(t, 1.5, result)
+
+				result, err = repository.IncrementFloat("float", 1)
+				assert.NoError(t, err)
+				assert.Equal(t, 2.5, result)
+				assert.Equal(t, 2.5, repository.Get("float"))
+
+				result, err = repository.IncrementFloat("integer", -0.5)
+				assert.NoError(t, err)
+				assert.Equal(t, 1.5, result)
+			})
+
+			t.Run("concurrent changes are not lost", func(t *testing.T) {
+				repository := cache.NewRepository(factory(t))
+				repository.Forever("floored", 50)
+
+				var wg sync.WaitGroup
+
+				for i := 0; i < 8; i++ {
+					wg.Add(1)
+
+					go func() {
+						defer wg.Done()
+
+						for j := 0; j < 25; j++ {
+							_, err := repository.IncrementBy("counter", 1)
+							assert.NoError(t, err)
+
+							_, err = repository.DecrementBy("floored", 1, true)
+							assert.NoError(t, err)
+						}
+					}()
+				}
+
+				wg.Wait()
+
+				assert.Equal(t, 200, repository.Get("counter"))
+				assert.Equal(t, 0, repository.Get("floored"))
+			})
+
+			t.Run("counters use the item keys of namespaces", func(t *testing.T) {
+				repository := cache.NewRepository(factory(t))
+				namespace := repository.Namespace("users")
+
+				result, err := namespace.IncrementBy("counter", 2)
+				assert.NoError(t, err)
+				assert.Equal(t, int64(2), result)
+				assert.Equal(t, 3, namespace.Increment("counter"))
+				assert.Equal(t, 3, namespace.Get("counter"))
+				assert.Nil(t, repository.Get("counter"))
+			})
+		})
+	}
+}
+
+func TestCounterExpiration(t *testing.T) {
+	for name, factory := range counterStores() {
+		name, factory := name, factory
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			repository := cache.NewRepository(factory(t))
+			repository.Put("counter", 1, time.Second)
+
+			result, err := repository.IncrementBy("counter", 2)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(3), result)
+
+			result, err = repository.DecrementBy("counter", 1, true)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(2), result)
+
+			time.Sleep(2100 * time.Millisecond)
+
+			assert.Nil(t, repository.Get("counter"))
+		})
+	}
+}
+
+func TestCountersRequireCounterStores(t *testing.T) {
+	stores := map[string]contracts.Store{
+		"plain":     plainStore{cache.NewArrayStore()},
+		"decorated": cache.NewTransformingStore(plainStore{cache.NewArrayStore()}),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			repository := cache.NewRepository(store)
+			repository.Put("counter", 1, time.Minute)
+
+			_, err := repository.IncrementBy("counter", 1)
+			assert.ErrorIs(t, err, cache.ErrCountersNotSupported)
+
+			_, err = repository.DecrementBy("counter", 1, true)
+			assert.ErrorIs(t, err, cache.ErrCountersNotSupported)
+
+			_, err = repository.IncrementFloat("counter", 1)
+			assert.ErrorIs(t, err, cache.ErrCountersNotSupported)
+
+			assert.Equal(t, 1, repository.Get("counter"))
+		})
+	}
+}
+
+func TestDecoratorCounters(t *testing.T) {
+	t.Run("failover counters fail over without losing errors", func(t *testing.T) {
+		store := cache.NewFailoverStore(plainStore{&panickingStore{cache.NewArrayStore()}}, cache.NewArrayStore())
+
+		result, err := store.IncrementBy("counter", 2)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), result)
+
+		store.Put("string", "abc", 60)
+
+		_, err = store.IncrementBy("string", 1)
+		assert.ErrorIs(t, err, cache.ErrNotNumeric)
+		assert.False(t, store.Available(0))
+		assert.True(t, store.Available(1))
+
+		_, err = cache.NewFailoverStore().IncrementBy("counter", 1)
+		assert.ErrorIs(t, err, cache.ErrStoreFailed)
+	})
+
+	t.Run("tiered counters drop the local copy", func(t *testing.T) {
+		local := cache.NewArrayStore()
+		store := cache.NewTieredStore(local, cache.NewArrayStore())
+		store.Put("counter", 1, 60)
+
+		result, err := store.IncrementBy("counter", 2)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), result)
+		assert.Nil(t, local.Get("counter"))
+		assert.EqualValues(t, 3, store.Get("counter"))
+	})
+}
@@ -0,0 +1,170 @@
+package cache_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/garavel-core/framework/cache"
+	"github.com/garavel-core/framework/cache/cachetest"
+	"github.com/garavel-core/framework/internal/sqltest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Create a database store on a fake database with a cache table.
+func newDatabaseStore(t *testing.T, grammar cache.DatabaseGrammar, prefix ...string) (*cache.DatabaseStore, *sqltest.Database) {
+	database := sqltest.NewDatabase()
+	connection := database.Open()
+
+	t.Cleanup(func() { connection.Close() })
+
+	assert.NoError(t, cache.CreateCacheTable(connection, "cache", grammar))
+
+	return cache.NewDatabaseStore(connection, "cache", prefix...).SetGrammar(grammar).SetGarbageCollectionLottery(0, 100), database
+}
+
+func TestDatabaseStore(t *testing.T) {
+	grammars := map[string]cache.DatabaseGrammar{
+		"mysql":    cache.MySqlGrammar,
+		"postgres": cache.PostgresGrammar,
+		"sqlite":   cache.SQLiteGrammar,
+	}
+
+	for name, grammar := range grammars {
+		grammar := grammar
+
+		t.Run(name, func(t *testing.T) {
+			t.Run("items are stored and retrieved", func(t *testing.T) {
+				store, _ := newDatabaseStore(t, grammar, "app:")
+
+				assert.True(t, store.Put("foo", "bar", 60))
+				assert.True(t, store.PutMany(map[string]any{"baz": 1, "qux": []string{"a"}}, 60))
+				assert.Equal(t, "bar", store.Get("foo"))
+				assert.Equal(t, map[string]any{"foo": "bar", "baz": 1, "missing": nil}, store.Many([]string{"foo", "baz", "missing"}))
+				assert.Equal(t, []string{"a"}, store.Get("qux"))
+			})
+
+			t.Run("forget reports whether the item existed", func(t *testing.T) {
+				store, _ := newDatabaseStore(t, grammar)
+				store.Put("foo", "bar", 60)
+
+				assert.True(t, store.Forget("foo"))
+				assert.False(t, store.Forget("foo"))
+				assert.Nil(t, store.Get("foo"))
+			})
+
+			t.Run("add takes over expired rows only", func(t *testing.T) {
+				clock := cachetest.NewClock()
+				store, database := newDatabaseStore(t, grammar)
+				store.SetClock(clock.Now)
+
+				assert.True(t, store.Add("foo", "first", 1))
+				assert.False(t, store.Add("foo", "second", 1))
+				assert.Equal(t, "first", store.Get("foo"))
+
+				clock.Advance(2 * time.Second)
+
+				assert.Equal(t, 1, database.Rows("cache"))
+				assert.True(t, store.Add("foo", "third"))
+				assert.Equal(t, "third", store.Get("foo"))
+			})
+
+			t.Run("sub-second ttls are rounded up", func(t *testing.T) {
+				clock := cachetest.NewClock(time.Unix(100, 900*int64(time.Millisecond)))
+				store, _ := newDatabaseStore(t, grammar)
+				store.SetClock(clock.Now)
+
+				assert.True(t, store.Add("foo", "bar", 700*time.Millisecond))
+				assert.Equal(t, "bar", store.Get("foo"))
+
+				clock.Advance(600 * time.Millisecond)
+				assert.Equal(t, "bar", store.Get("foo"))
+
+				clock.Advance(500 * time.Millisecond)
+				assert.Nil(t, store.Get("foo"))
+			})
+
+			t.Run("counters of missing items fail", func(t *testing.T) {
+				store, _ := newDatabaseStore(t, grammar)
+
+				assert.Equal(t, false, store.Increment("missing"))
+
+				store.Put("counter", 1, 60)
+				assert.Equal(t, 3, store.Increment("counter", 2))
+				assert.Equal(t, 2, store.Decrement("counter"))
+			})
+
+			t.Run("concurrent counters are not lost", func(t *testing.T) {
+				store, _ := newDatabaseStore(t, grammar)
+
+				var wg sync.WaitGroup
+
+				for i := 0; i < 8; i++ {
+					wg.Add(1)
+
+					go func() {
+						defer wg.Done()
+
+						for j := 0; j < 25; j++ {
+							_, err := store.IncrementBy("counter", 1)
+							assert.NoError(t, err)
+						}
+					}()
+				}
+
+				wg.Wait()
+
+				assert.Equal(t, 200, store.Get("counter"))
+			})
+
+			t.Run("prune removes expired rows", func(t *testing.T) {
+				clock := cachetest.NewClock()
+				store, database := newDatabaseStore(t, grammar)
+				store.SetClock(clock.Now)
+
+				store.Put("expiring", "value", 1)
+				store.Forever("forever", "value")
+
+				clock.Advance(2 * time.Second)
+
+				assert.True(t, store.Prune())
+				assert.Equal(t, 1, database.Rows("cache"))
+				assert.Equal(t, "value", store.Get("forever"))
+			})
+
+			t.Run("flush removes every row", func(t *testing.T) {
+				store, database := newDatabaseStore(t, grammar)
+				store.Put("foo", "bar", 60)
+				store.Forever("baz", "qux")
+
+				assert.True(t, store.Flush())
+				assert.Equal(t, 0, database.Rows("cache"))
+			})
+		})
+	}
+
+	t.Run("statements follow the grammar", func(t *testing.T) {
+		statements := func(grammar cache.DatabaseGrammar) string {
+			store, database := newDatabaseStore(t, grammar)
+			store.Put("foo", "bar", 60)
+			store.IncrementBy("counter", 1)
+
+			return strings.Join(database.Statements(), "\n")
+		}
+
+		mysql := statements(cache.MySqlGrammar)
+		assert.Contains(t, mysql, "create table if not exists `cache` (`key` varchar(255)")
+		assert.Contains(t, mysql, "on duplicate key update `value` = values(`value`)")
+		assert.Contains(t, mysql, "where `key` = ? for update")
+
+		postgres := statements(cache.PostgresGrammar)
+		assert.Contains(t, postgres, `values ($1, $2, $3) on conflict ("key") do update set "value" = excluded."value"`)
+		assert.Contains(t, postgres, `where "key" = $1 for update`)
+
+		sqlite := statements(cache.SQLiteGrammar)
+		assert.Contains(t, sqlite, `values (?, ?, ?) on conflict ("key")`)
+		assert.NotContains(t, sqlite, "for update")
+	})
+}
@@ -0,0 +1,267 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/garavel-core/framework/cache"
+	"github.com/garavel-core/framework/events"
+	"github.com/garavel-core/framework/redis"
+	"github.com/garavel-core/framework/redis/redistest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// A store panicking on every read.
+type panickingStore struct {
+	*cache.ArrayStore
+}
+
+func (s *panickingStore) Get(key string) any {
+	panic("connection reset")
+}
+
+func TestFailoverStore(t *testing.T) {
+	listen := func(store *cache.FailoverStore) (*[]cache.CacheFailedOver, *[]cache.CacheRecovered) {
+		failures := &[]cache.CacheFailedOver{}
+		recoveries := &[]cache.CacheRecovered{}
+		dispatcher := events.NewDispatcher()
+		events.ListenTo(dispatcher, func(event cache.CacheFailedOver) {
+			*failures = append(*failures, event)
+		})
+		events.ListenTo(dispatcher, func(event cache.CacheRecovered) {
+			*recoveries = append(*recoveries, event)
+		})
+		store.SetEventDispatcher(dispatcher)
+		return failures, recoveries
+	}
+
+	t.Run("calls are served by the first backend", func(t *testing.T) {
+		primary, secondary := cache.NewArrayStore(), cache.NewArrayStore()
+		store := cache.NewFailoverStore(primary, secondary)
+		failures, _ := listen(store)
+
+		assert.True(t, store.Put("foo", "bar", 10))
+		assert.Equal(t, "bar", store.Get("foo"))
+		assert.Nil(t, store.Get("missing"))
+		assert.Equal(t, "bar", primary.Get("foo"))
+		assert.Nil(t, secondary.Get("foo"))
+		assert.Empty(t, *failures)
+	})
+
+	t.Run("unreachable backends fail over", func(t *testing.T) {
+		server := redistest.NewServer()
+		connection := redis.NewConnection(redis.Options{Address: server.Addr(), DialTimeout: 100 * time.Millisecond})
+		t.Cleanup(func() { connection.Close() })
+		server.Close()
+
+		fallback := cache.NewArrayStore()
+		store := cache.NewFailoverStore(cache.NewRedisStore(connection), fallback).SetStoreNames("redis", "array")
+		failures, _ := listen(store)
+
+		assert.True(t, store.Put("foo", "bar", 10))
+		assert.Equal(t, "bar", fallback.Get("foo"))
+		assert.False(t, store.Available(0))
+		assert.Len(t, *failures, 1)
+		assert.Equal(t, "redis", (*failures)[0].StoreName)
+		assert.Equal(t, "foo", (*failures)[0].Key)
+		assert.Equal(t, 0, (*failures)[0].Backend)
+		assert.Error(t, (*failures)[0].Err)
+
+		// The failed backend is skipped during the cool-down.
+		assert.Equal(t, "bar", store.Get("foo"))
+		assert.Len(t, *failures, 1)
+	})
+
+	t.Run("misses of reachable backends are not failures", func(t *testing.T) {
+		redisStore, _ := newRedisStore(t)
+		store := cache.NewFailoverStore(redisStore, cache.NewArrayStore())
+		failures, _ := listen(store)
+
+		assert.Nil(t, store.Get("missing"))
+		assert.True(t, store.Add("added", "value", 10))
+		assert.False(t, store.Add("added", "value", 10))
+		assert.True(t, store.Available(0))
+		assert.Empty(t, *failures)
+	})
+
+	t.Run("misses are not followed by pings", func(t *testing.T) {
+		redisStore, server := newRedisStore(t)
+		store := cache.NewFailoverStore(redisStore, cache.NewArrayStore())
+
+		assert.Nil(t, store.Get("missing"))
+		assert.Equal(t, map[string]any{"missing": nil}, store.Many([]string{"missing"}))
+		assert.True(t, store.Add("added", "value", 10))
+		assert.False(t, store.Add("added", "value", 10))
+		assert.NotContains(t, server.Commands(), "PING")
+	})
+
+	t.Run("locks stay on the first backend", func(t *testing.T) {
+		primary := &panickingStore{ArrayStore: cache.NewArrayStore()}
+		secondary := cache.NewArrayStore()
+		store := cache.NewFailoverStore(primary, secondary)
+
+		store.Get("foo")
+		assert.False(t, store.Available(0))
+
+		lock := store.Lock("foo", 10)
+		assert.Equal(t, true, lock.Get())
+		assert.Equal(t, false, primary.Lock("foo", 10).Get())
+		assert.Equal(t, true, secondary.Lock("foo", 10).Get())
+	})
+
+	t.Run("panics fail over", func(t *testing.T) {
+		primary := &panickingStore{ArrayStore: cache.NewArrayStore()}
+		secondary := cache.NewArrayStore()
+		secondary.Put("foo", "bar", 10)
+		store := cache.NewFailoverStore(primary, secondary)
+		failures, _ := listen(store)
+
+		assert.Equal(t, "bar", store.Get("foo"))
+		assert.Len(t, *failures, 1)
+		assert.Contains(t, (*failures)[0].Err.Error(), "connection reset")
+	})
+
+	t.Run("failed writes fail over", func(t *testing.T) {
+		secondary := cache.NewArrayStore()
+		store := cache.NewFailoverStore(cache.NewNullStore(), secondary)
+		failures, _ := listen(store)
+
+		assert.True(t, store.Forever("foo", "bar"))
+		assert.Equal(t, "bar", secondary.Get("foo"))
+		assert.ErrorIs(t, (*failures)[0].Err, cache.ErrStoreFailed)
+	})
+
+	t.Run("backends recover after the cool-down", func(t *testing.T) {
+		primary := &panickingStore{ArrayStore: cache.NewArrayStore()}
+		store := cache.NewFailoverStore(primary, cache.NewArrayStore()).SetCooldown(50 * time.Millisecond)
+		_, recoveries := listen(store)
+
+		store.Get("foo")
+		assert.False(t, store.Available(0))
+		time.Sleep(60 * time.Millisecond)
+		assert.True(t, store.Available(0))
+
+		assert.True(t, store.Put("foo", "bar", 10))
+		assert.Equal(t, "bar", primary.ArrayStore.Get("foo"))
+		assert.Len(t, *recoveries, 1)
+		assert.Equal(t, 0, (*recoveries)[0].Backend)
+	})
+
+	t.Run("forget and flush reach every available backend", func(t *testing.T) {
+		primary, secondary := cache.NewArrayStore(), cache.NewArrayStore()
+		primary.Put("foo", "bar", 10)
+		secondary.Put("foo", "bar", 10)
+		store := cache.NewFailoverStore(primary, secondary)
+
+		assert.True(t, store.Forget("foo"))
+		assert.Nil(t, primary.Get("foo"))
+		assert.Nil(t, secondary.Get("foo"))
+
+		secondary.Put("baz", "qux", 10)
+		assert.True(t, store.Flush())
+		assert.Nil(t, secondary.Get("baz"))
+	})
+
+	t.Run("the manager creates failover stores", func(t *testing.T) {
+		dispatcher := events.NewDispatcher()
+		var failures []cache.CacheFailedOver
+		events.ListenTo(dispatcher, func(event cache.CacheFailedOver) {
+			failures = append(failures, event)
+		})
+
+		manager := cache.NewManager(map[string]any{
+			"default": "failover",
+			"stores": map[string]any{
+				"failover": map[string]any{"driver": "failover", "stores": []string{"null", "array"}, "cooldown": "1m"},
+				"array":    map[string]any{"driver": "array"},
+			},
+		}).SetEventDispatcher(dispatcher)
+
+		repository, err := manager.Store()
+		assert.NoError(t, err)
+		assert.True(t, repository.Put("foo", "bar", 10))
+		assert.Equal(t, "bar", repository.Get("foo"))
+		assert.Len(t, failures, 1)
+		assert.Equal(t, "null", failures[0].StoreName)
+
+		manager = cache.NewManager(map[string]any{
+			"stores": map[string]any{"failover": map[string]any{"driver": "failover"}},
+		})
+		_, err = manager.Store("failover")
+		assert.EqualError(t, err, "cache store [failover] requires a list of stores")
+	})
+
+	t.Run("the manager refuses failover cycles", func(t *testing.T) {
+		manager := cache.NewManager(map[string]any{
+			"stores": map[string]any{
+				"self":   map[string]any{"driver": "failover", "stores": []string{"self"}},
+				"first":  map[string]any{"driver": "failover", "stores": []string{"array", "second"}},
+				"second": map[string]any{"driver": "failover", "stores": []string{"first"}},
+				"array":  map[string]any{"driver": "array"},
+			},
+		})
+
+		_, err := manager.Store("self")
+		assert.EqualError(t, err, "cache store [self] fails over to itself")
+
+		_, err = manager.Store("first")
+		assert.EqualError(t, err, "cache store [first] fails over to itself")
+
+		_, err = manager.Store("second")
+		assert.EqualError(t, err, "cache store [second] fails over to itself")
+	})
+
+	t.Run("the manager shares backing stores", func(t *testing.T) {
+		server := redistest.NewServer()
+		t.Cleanup(server.Close)
+
+		manager := cache.NewManager(map[string]any{
+			"stores": map[string]any{
+				"failover": map[string]any{"driver": "failover", "stores": []string{"redis", "array"}},
+				"redis":    map[string]any{"driver": "redis", "address": server.Addr()},
+				"array":    map[string]any{"driver": "array"},
+			},
+		})
+
+		failover, err := manager.Store("failover")
+		assert.NoError(t, err)
+
+		redis, err := manager.Store("redis")
+		assert.NoError(t, err)
+
+		failover.Put("foo", "bar", 10)
+		assert.Equal(t, "bar", redis.Get("foo"))
+		assert.Same(t, redis.GetStore(), failover.GetStore().(*cache.FailoverStore).GetStores()[0])
+	})
+
+	t.Run("purging a backing store forgets the failover store", func(t *testing.T) {
+		server := redistest.NewServer()
+		t.Cleanup(server.Close)
+
+		manager := cache.NewManager(map[string]any{
+			"stores": map[string]any{
+				"failover": map[string]any{"driver": "failover", "stores": []string{"redis", "array"}},
+				"redis":    map[string]any{"driver": "redis", "address": server.Addr()},
+				"array":    map[string]any{"driver": "array"},
+			},
+		})
+
+		_, err := manager.Store("failover")
+		assert.NoError(t, err)
+
+		manager.Purge("redis")
+
+		failover, err := manager.Store("failover")
+		assert.NoError(t, err)
+		failures, _ := listen(failover.GetStore().(*cache.FailoverStore))
+
+		assert.True(t, failover.Put("foo", "bar", 10))
+		assert.Equal(t, "bar", failover.Get("foo"))
+		assert.Empty(t, *failures)
+
+		redis, _ := manager.Store("redis")
+		assert.Equal(t, "bar", redis.Get("foo"))
+		assert.Same(t, redis.GetStore(), failover.GetStore().(*cache.FailoverStore).GetStores()[0])
+	})
+}
@@ -0,0 +1,162 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/garavel-core/framework/cache"
+	"github.com/garavel-core/framework/cache/cachetest"
+	contracts "github.com/garavel-core/framework/contracts/cache"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// A test recording the failures reported to it instead of failing.
+type recordingT struct {
+	testing.TB
+	// The number of failures reported.
+	failures int
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...any) {
+	t.failures++
+}
+
+func TestFakeSatisfiesRepositoryContracts(t *testing.T) {
+	var _ contracts.Repository = cachetest.NewFake(t)
+	var _ contracts.ContextRepository = cachetest.NewFake(t)
+}
+
+func TestFakeAssertions(t *testing.T) {
+	fake := cachetest.NewFake(t)
+
+	fake.AssertNothingWritten()
+	fake.AssertMissing("user")
+
+	fake.Put("user", "Taylor", 10*time.Minute)
+	fake.Forever("settings", "dark")
+	fake.PutMany(map[string]any{"first": 1, "second": 2}, 90*time.Second)
+	fake.Add("added", true, 1500*time.Millisecond)
+
+	fake.AssertHas("user")
+	fake.AssertPut("user")
+	fake.AssertPut("user", 10*time.Minute)
+	fake.AssertPut("settings", 0)
+	fake.AssertPut("second", 90*time.Second)
+	fake.AssertPut("added", 2*time.Second)
+
+	fake.Forget("user")
+
+	fake.AssertForgotten("user")
+	fake.AssertMissing("user")
+}
+
+func TestFakeAssertionsReportFailures(t *testing.T) {
+	recorder := &recordingT{TB: t}
+	fake := cachetest.NewFake(recorder)
+
+	fake.Put("user", "Taylor", time.Minute)
+
+	assert.False(t, fake.AssertNothingWritten())
+	assert.False(t, fake.AssertMissing("user"))
+	assert.False(t, fake.AssertHas("missing"))
+	assert.False(t, fake.AssertPut("missing"))
+	assert.False(t, fake.AssertPut("user", time.Hour))
+	assert.False(t, fake.AssertForgotten("user"))
+	assert.False(t, fake.AssertRememberCalledOnce("user"))
+	assert.Equal(t, 7, recorder.failures)
+}
+
+func TestFakeAssertionsDoNotRecordCalls(t *testing.T) {
+	fake := cachetest.NewFake(t)
+
+	fake.Put("item", "value", time.Minute)
+	fake.Spy().Reset()
+
+	fake.AssertHas("item")
+	fake.AssertMissing("missing")
+
+	assert.Empty(t, fake.Spy().Calls())
+}
+
+func TestFakeCountsRememberCalls(t *testing.T) {
+	fake := cachetest.NewFake(t)
+	calls := 0
+
+	callback := func() any {
+		calls++
+		return "value"
+	}
+
+	assert.Equal(t, "value", fake.Remember("key", time.Minute, callback))
+	fake.AssertRememberCalledOnce("key")
+
+	assert.Equal(t, "value", fake.Remember("key", time.Minute, callback))
+	assert.Equal(t, 1, calls)
+
+	recorder := &recordingT{TB: t}
+	other := cachetest.NewFake(recorder)
+
+	other.Sear("forever", callback)
+	other.RememberForever("forever", callback)
+	other.AssertRememberCalledOnce("forever")
+	assert.Equal(t, 1, recorder.failures)
+
+	value, err := fake.RememberCtx(context.Background(), "ctx", time.Minute, func(ctx context.Context) (any, error) {
+		return "value", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "value", value)
+	fake.AssertRememberCalledOnce("ctx")
+	fake.AssertPut("ctx", time.Minute)
+}
+
+func TestFakeClockExpiresItems(t *testing.T) {
+	fake := cachetest.NewFake(t)
+
+	fake.Put("item", "value", time.Minute)
+
+	fake.Clock().Advance(59 * time.Second)
+	fake.AssertHas("item")
+
+	fake.Clock().Advance(2 * time.Second)
+	fake.AssertMissing("item")
+}
+
+func TestSpyStoreRecordsCallsInOrder(t *testing.T) {
+	spy := cachetest.NewSpyStore()
+	repository := cache.NewRepository(spy)
+
+	repository.Put("item", "value", time.Minute)
+	repository.Get("item")
+	repository.Increment("counter", 2)
+	repository.Forget("item")
+
+	assert.Equal(t, []cachetest.Call{
+		{Method: "Put", Args: []any{"item", "value", 60}, Result: true},
+		{Method: "Get", Args: []any{"item"}, Result: "value"},
+		{Method: "Increment", Args: []any{"counter", 2}, Result: 2},
+		{Method: "Forget", Args: []any{"item"}, Result: true},
+	}, spy.Calls())
+
+	assert.Len(t, spy.Writes(), 3)
+	assert.Len(t, spy.CallsTo("Get"), 1)
+
+	spy.Reset()
+	assert.Empty(t, spy.Calls())
+}
+
+func TestSpyStoreAddsToStoresWithoutAtomicAdd(t *testing.T) {
+	clock := cachetest.NewClock()
+	spy := cachetest.NewSpyStore(plainStore{cache.NewArrayStore().SetClock(clock.Now)})
+
+	assert.True(t, spy.Add("item", "first", 1500*time.Millisecond))
+	assert.False(t, spy.Add("item", "second"))
+	assert.Equal(t, "first", spy.Get("item"))
+
+	clock.Advance(2 * time.Second)
+	assert.Nil(t, spy.Get("item"))
+}
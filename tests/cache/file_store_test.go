@@ -0,0 +1,169 @@
+package cache_test
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/garavel-core/framework/cache"
+	"github.com/garavel-core/framework/cache/cachetest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileStore(t *testing.T) {
+	t.Run("items are stored in sharded directories", func(t *testing.T) {
+		directory := t.TempDir()
+		store := cache.NewFileStore(directory)
+		assert.True(t, store.Put("foo", "bar", 10))
+		assert.Equal(t, "bar", store.Get("foo"))
+
+		// sha1("foo") = 0beec7b5ea3f0fdbc95d0dd47f3c5bc275da8a33
+		contents, err := os.ReadFile(filepath.Join(directory, "0b", "ee", "0beec7b5ea3f0fdbc95d0dd47f3c5bc275da8a33"))
+		assert.NoError(t, err)
+		assert.Len(t, string(contents[:10]), 10)
+	})
+
+	t.Run("forever items use the maximum timestamp", func(t *testing.T) {
+		directory := t.TempDir()
+		store := cache.NewFileStore(directory)
+		store.Forever("foo", map[string]any{"bar": 1})
+		contents, _ := os.ReadFile(filepath.Join(directory, "0b", "ee", "0beec7b5ea3f0fdbc95d0dd47f3c5bc275da8a33"))
+		assert.Equal(t, "9999999999", string(contents[:10]))
+		assert.Equal(t, map[string]any{"bar": 1}, store.Get("foo"))
+	})
+
+	t.Run("expired items are removed", func(t *testing.T) {
+		directory := t.TempDir()
+		path := filepath.Join(directory, "0b", "ee", "0beec7b5ea3f0fdbc95d0dd47f3c5bc275da8a33")
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		os.WriteFile(path, []byte("0000000001garbage"), 0o644)
+		store := cache.NewFileStore(directory)
+		assert.Nil(t, store.Get("foo"))
+		assert.NoFileExists(t, path)
+	})
+
+	t.Run("add only stores missing items", func(t *testing.T) {
+		store := cache.NewFileStore(t.TempDir())
+		assert.True(t, store.Add("foo", "bar", 10))
+		assert.False(t, store.Add("foo", "baz", 10))
+		assert.Equal(t, "bar", store.Get("foo"))
+	})
+
+	t.Run("sub-second ttls are rounded up", func(t *testing.T) {
+		clock := cachetest.NewClock(time.Unix(100, 900*int64(time.Millisecond)))
+		repository := cache.NewRepository(cache.NewFileStore(t.TempDir()).SetClock(clock.Now)).SetClock(clock.Now)
+
+		assert.True(t, repository.Add("added", "value", 700*time.Millisecond))
+		assert.True(t, repository.Put("put", "value", 1))
+
+		clock.Advance(600 * time.Millisecond)
+		assert.Equal(t, "value", repository.Get("added"))
+		assert.Equal(t, "value", repository.Get("put"))
+
+		clock.Advance(500 * time.Millisecond)
+		assert.Nil(t, repository.Get("added"))
+		assert.Nil(t, repository.Get("put"))
+	})
+
+	t.Run("concurrent increments are not lost", func(t *testing.T) {
+		store := cache.NewFileStore(t.TempDir())
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				store.Increment("counter")
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, 50, store.Get("counter"))
+		assert.Equal(t, 45, store.Decrement("counter", 5))
+	})
+
+	t.Run("flush removes the cache directory", func(t *testing.T) {
+		directory := filepath.Join(t.TempDir(), "cache")
+		store := cache.NewFileStore(directory)
+		store.Forever("foo", "bar")
+		assert.True(t, store.Flush())
+		assert.NoDirExists(t, directory)
+		assert.Nil(t, store.Get("foo"))
+		assert.False(t, store.Flush())
+		assert.True(t, store.Forever("foo", "baz"))
+		assert.Equal(t, "baz", store.Get("foo"))
+	})
+
+	t.Run("expired items are only removed while still expired", func(t *testing.T) {
+		clock := cachetest.NewClock()
+		store := cache.NewFileStore(t.TempDir())
+		var rewrite atomic.Bool
+
+		// Write a fresh value right after the reader found the expired one.
+		store.SetClock(func() time.Time {
+			if rewrite.CompareAndSwap(true, false) {
+				store.Put("foo", "new", 60)
+			}
+
+			return clock.Now()
+		})
+
+		store.Put("foo", "old", 1)
+		clock.Advance(2 * time.Second)
+		rewrite.Store(true)
+
+		assert.Equal(t, "new", store.Get("foo"))
+		assert.Equal(t, "new", store.Get("foo"))
+	})
+
+	t.Run("lock files are removed", func(t *testing.T) {
+		directory := t.TempDir()
+		locks := t.TempDir()
+		store := cache.NewFileStore(directory)
+		separate := cache.NewFileStore(t.TempDir()).SetLockDirectory(locks)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				store.Increment("counter")
+				separate.Increment("counter")
+			}()
+		}
+		wg.Wait()
+
+		assert.True(t, store.Add("foo", "bar", 10))
+		assert.Equal(t, true, store.Lock("lock", 10).Get())
+		store.Lock("lock", 10).ForceRelease()
+		assert.Equal(t, 20, store.Get("counter"))
+		assert.Equal(t, 20, separate.Get("counter"))
+
+		for _, root := range []string{directory, locks} {
+			filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+				assert.NotEqual(t, ".lock", filepath.Ext(path), "lock file [%s] was left behind", path)
+
+				return nil
+			})
+		}
+	})
+
+	t.Run("repeated flushes do not collide", func(t *testing.T) {
+		parent := t.TempDir()
+		directory := filepath.Join(parent, "cache")
+		clock := cachetest.NewClock()
+		store := cache.NewFileStore(directory).SetClock(clock.Now)
+
+		for i := 0; i < 3; i++ {
+			store.Forever("foo", "bar")
+			assert.True(t, store.Flush())
+			assert.Nil(t, store.Get("foo"))
+		}
+
+		entries, err := os.ReadDir(parent)
+		assert.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+}
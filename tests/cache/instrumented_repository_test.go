@@ -0,0 +1,126 @@
+package cache_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/garavel-core/framework/cache"
+	contracts "github.com/garavel-core/framework/contracts/cache"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstrumentedRepository(t *testing.T) {
+	newRepository := func() (*cache.InstrumentedRepository, *cache.Metrics) {
+		metrics := cache.NewMetrics()
+		repository := cache.NewRepository(cache.NewArrayStore(), map[string]any{"store": "array"})
+
+		return cache.NewInstrumentedRepository(repository, metrics), metrics
+	}
+
+	t.Run("counts hits, misses, writes and deletes per key prefix", func(t *testing.T) {
+		repository, _ := newRepository()
+
+		repository.Put("users:1", "Taylor", 60)
+		repository.Forever("posts:1", "Hello")
+		assert.Equal(t, "Taylor", repository.Get("users:1"))
+		assert.Nil(t, repository.Get("users:2"))
+		assert.Equal(t, "default", repository.Get("users:3", "default"))
+		assert.True(t, repository.Forget("posts:1"))
+		assert.False(t, repository.Forget("posts:2"))
+
+		stats := repository.Stats()
+
+		assert.Equal(t, cache.Counters{Hits: 1, Misses: 2, Writes: 1}, stats.Prefixes[cache.StatsKey{Store: "array", Prefix: "users"}])
+		assert.Equal(t, cache.Counters{Writes: 1, Deletes: 1}, stats.Prefixes[cache.StatsKey{Store: "array", Prefix: "posts"}])
+		assert.Equal(t, cache.Counters{Hits: 1, Misses: 2, Writes: 2, Deletes: 1}, stats.Stores["array"])
+		assert.Equal(t, stats.Stores["array"], stats.Total)
+		assert.InDelta(t, 1.0/3, stats.Total.HitRatio(), 0.0001)
+	})
+
+	t.Run("counts remember as a miss and write only when the callback runs", func(t *testing.T) {
+		repository, _ := newRepository()
+		callback := func() any { return "computed" }
+
+		assert.Equal(t, "computed", repository.Remember("users:1", 60, callback))
+		assert.Equal(t, "computed", repository.RememberForever("users:1", callback))
+
+		assert.Equal(t, cache.Counters{Hits: 1, Misses: 1, Writes: 1}, repository.Stats().Total)
+	})
+
+	t.Run("counts many reads per key", func(t *testing.T) {
+		repository, _ := newRepository()
+
+		repository.SetMultiple(map[string]any{"users:1": "a", "posts:1": "b"}, 60)
+		values := repository.GetMultiple([]string{"users:1", "users:2"})
+
+		assert.Equal(t, map[string]any{"users:1": "a", "users:2": nil}, values)
+
+		stats := repository.Stats()
+		assert.Equal(t, cache.Counters{Hits: 1, Misses: 1, Writes: 1}, stats.Prefixes[cache.StatsKey{Store: "array", Prefix: "users"}])
+		assert.Equal(t, uint64(1), stats.Latencies[cache.LatencyKey{StatsKey: cache.StatsKey{Store: "array", Prefix: "users"}, Operation: "many"}].Count)
+		assert.Equal(t, uint64(1), stats.Latencies[cache.LatencyKey{StatsKey: cache.StatsKey{Store: "array", Prefix: "*"}, Operation: "put_many"}].Count)
+	})
+
+	t.Run("counts failed writes and panics as errors", func(t *testing.T) {
+		metrics := cache.NewMetrics()
+		repository := cache.NewInstrumentedRepository(cache.NewRepository(failingStore{cache.NewArrayStore()}), metrics).SetStoreName("failing")
+
+		assert.False(t, repository.Put("users:1", "Taylor", 60))
+		assert.Panics(t, func() {
+			repository.Remember("users:1", 60, func() any { panic("boom") })
+		})
+
+		stats := repository.Stats()
+		assert.Equal(t, uint64(2), stats.Stores["failing"].Errors)
+		assert.Equal(t, uint64(1), stats.Latencies[cache.LatencyKey{StatsKey: cache.StatsKey{Store: "failing", Prefix: "users"}, Operation: "remember"}].Count)
+	})
+
+	t.Run("records latencies in cumulative buckets", func(t *testing.T) {
+		metrics := cache.NewMetrics(1, 0.5)
+		repository := cache.NewInstrumentedRepository(cache.NewRepository(cache.NewArrayStore()), metrics)
+
+		repository.Get("users:1")
+		repository.Get("users:2")
+
+		histogram := metrics.Stats().Latencies[cache.LatencyKey{StatsKey: cache.StatsKey{Prefix: "users"}, Operation: "get"}]
+
+		assert.Equal(t, []float64{0.5, 1}, histogram.Buckets)
+		assert.Equal(t, []uint64{2, 2}, histogram.Counts)
+		assert.Equal(t, uint64(2), histogram.Count)
+	})
+
+	t.Run("renders the prometheus text format", func(t *testing.T) {
+		metrics := cache.NewMetrics(0.5, 1)
+		repository := cache.NewInstrumentedRepository(cache.NewRepository(cache.NewArrayStore()), metrics).SetStoreName(`main"store`)
+
+		repository.Put("users:1", "Taylor", 60)
+		repository.Get("users:1")
+
+		recorder := httptest.NewRecorder()
+		metrics.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+		body := recorder.Body.String()
+
+		assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", recorder.Header().Get("Content-Type"))
+		assert.Contains(t, body, "# TYPE cache_hits_total counter\n")
+		assert.Contains(t, body, "cache_hits_total{store=\"main\\\"store\",prefix=\"users\"} 1\n")
+		assert.Contains(t, body, "cache_writes_total{store=\"main\\\"store\",prefix=\"users\"} 1\n")
+		assert.Contains(t, body, "# TYPE cache_operation_duration_seconds histogram\n")
+		assert.Contains(t, body, "cache_operation_duration_seconds_bucket{store=\"main\\\"store\",prefix=\"users\",operation=\"get\",le=\"0.5\"} 1\n")
+		assert.Contains(t, body, "cache_operation_duration_seconds_bucket{store=\"main\\\"store\",prefix=\"users\",operation=\"get\",le=\"+Inf\"} 1\n")
+		assert.Contains(t, body, "cache_operation_duration_seconds_count{store=\"main\\\"store\",prefix=\"users\",operation=\"put\"} 1\n")
+		assert.True(t, strings.HasSuffix(body, "\n"))
+	})
+}
+
+// A store whose writes always fail.
+type failingStore struct {
+	*cache.ArrayStore
+}
+
+func (s failingStore) Put(key string, value any, seconds int) bool {
+	return false
+}
+
+var _ contracts.Store = failingStore{}
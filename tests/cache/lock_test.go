@@ -0,0 +1,174 @@
+package cache_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/garavel-core/framework/cache"
+	"github.com/garavel-core/framework/cache/cachetest"
+	contracts "github.com/garavel-core/framework/contracts/cache"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocks(t *testing.T) {
+	providers := map[string]func(t *testing.T) contracts.LockProvider{
+		"array": func(t *testing.T) contracts.LockProvider {
+			return cache.NewArrayStore()
+		},
+		"file": func(t *testing.T) contracts.LockProvider {
+			return cache.NewFileStore(t.TempDir())
+		},
+	}
+
+	for name, provider := range providers {
+		t.Run(name, func(t *testing.T) {
+			t.Run("locks can be acquired and released", func(t *testing.T) {
+				store := provider(t)
+				lock := store.Lock("foo", 10)
+				assert.Equal(t, true, lock.Get())
+				assert.Equal(t, false, store.Lock("foo", 10).Get())
+				assert.True(t, lock.Release())
+				assert.Equal(t, true, store.Lock("foo", 10).Get())
+			})
+
+			t.Run("owners are generated", func(t *testing.T) {
+				store := provider(t)
+				assert.Len(t, store.Lock("foo", 10).Owner(), 16)
+				assert.NotEqual(t, store.Lock("foo", 10).Owner(), store.Lock("foo", 10).Owner())
+				assert.Equal(t, "bar", store.Lock("foo", 10, "bar").Owner())
+			})
+
+			t.Run("locks can only be released by their owner", func(t *testing.T) {
+				store := provider(t)
+				lock := store.Lock("foo", 10)
+				assert.Equal(t, true, lock.Get())
+				assert.False(t, store.Lock("foo", 10).Release())
+				assert.Equal(t, false, store.Lock("foo", 10).Get())
+				assert.True(t, lock.Release())
+				assert.False(t, lock.Release())
+			})
+
+			t.Run("restored locks can be released", func(t *testing.T) {
+				store := provider(t)
+				lock := store.Lock("foo", 10)
+				assert.Equal(t, true, lock.Get())
+				assert.True(t, store.RestoreLock("foo", lock.Owner()).Release())
+				assert.Equal(t, true, store.Lock("foo", 10).Get())
+			})
+
+			t.Run("locks can be force released", func(t *testing.T) {
+				store := provider(t)
+				assert.Equal(t, true, store.Lock("foo", 10).Get())
+				store.Lock("foo", 10).ForceRelease()
+				assert.Equal(t, true, store.Lock("foo", 10).Get())
+			})
+
+			t.Run("the callback runs while the lock is held", func(t *testing.T) {
+				store := provider(t)
+				result := store.Lock("foo", 10).Get(func() any {
+					assert.Equal(t, false, store.Lock("foo", 10).Get())
+
+					return "bar"
+				})
+				assert.Equal(t, "bar", result)
+				assert.Equal(t, true, store.Lock("foo", 10).Get())
+			})
+
+			t.Run("the callback does not run without the lock", func(t *testing.T) {
+				store := provider(t)
+				store.Lock("foo", 10).Get()
+				result := store.Lock("foo", 10).Get(func() any {
+					t.Error("the callback should not run")
+
+					return nil
+				})
+				assert.Equal(t, false, result)
+			})
+
+			t.Run("blocking times out", func(t *testing.T) {
+				store := provider(t)
+				store.Lock("foo", 10).Get()
+				result, err := store.Lock("foo", 10).Block(1)
+				assert.ErrorIs(t, err, cache.ErrLockTimeout)
+				assert.Equal(t, false, result)
+			})
+
+			t.Run("blocking acquires released locks", func(t *testing.T) {
+				store := provider(t)
+				lock := store.Lock("foo", 10)
+				lock.Get()
+
+				go func() {
+					lock.Release()
+				}()
+
+				result, err := store.Lock("foo", 10).Block(5, func() any {
+					return "bar"
+				})
+				assert.NoError(t, err)
+				assert.Equal(t, "bar", result)
+			})
+
+			t.Run("only one goroutine holds the lock", func(t *testing.T) {
+				store := provider(t)
+
+				var wg sync.WaitGroup
+				var acquired int32
+
+				for i := 0; i < 20; i++ {
+					wg.Add(1)
+
+					go func() {
+						defer wg.Done()
+
+						if store.Lock("foo", 10).Get() == true {
+							atomic.AddInt32(&acquired, 1)
+						}
+					}()
+				}
+
+				wg.Wait()
+				assert.Equal(t, int32(1), acquired)
+			})
+		})
+	}
+
+	t.Run("expired file locks are released without blocking", func(t *testing.T) {
+		clock := cachetest.NewClock()
+		store := cache.NewFileStore(t.TempDir()).SetClock(clock.Now)
+		lock := store.Lock("foo", 1)
+		assert.Equal(t, true, lock.Get())
+		clock.Advance(2 * time.Second)
+
+		released := make(chan bool, 1)
+		go func() { released <- lock.Release() }()
+
+		select {
+		case result := <-released:
+			assert.False(t, result)
+		case <-time.After(3 * time.Second):
+			t.Fatal("releasing the expired lock blocked")
+		}
+
+		assert.Equal(t, true, store.Lock("foo", 10).Get())
+	})
+
+	t.Run("file locks use the lock directory", func(t *testing.T) {
+		directory := t.TempDir()
+		store := cache.NewFileStore(t.TempDir()).SetLockDirectory(directory)
+		assert.Equal(t, true, store.Lock("foo", 10).Get())
+		assert.Equal(t, false, cache.NewFileStore(directory).Lock("foo", 10).Get())
+	})
+
+	t.Run("locks in the lock directory follow the clock of the store", func(t *testing.T) {
+		clock := cachetest.NewClock()
+		store := cache.NewFileStore(t.TempDir()).SetLockDirectory(t.TempDir()).SetClock(clock.Now)
+		assert.Equal(t, true, store.Lock("foo", 10).Get())
+		assert.Equal(t, false, store.Lock("foo", 10).Get())
+		clock.Advance(11 * time.Second)
+		assert.Equal(t, true, store.Lock("foo", 10).Get())
+	})
+}
@@ -0,0 +1,144 @@
+package cache_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/garavel-core/framework/cache"
+	"github.com/garavel-core/framework/memcached"
+	"github.com/garavel-core/framework/memcached/memcachedtest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newMemcachedStore(t *testing.T, prefix ...string) (*cache.MemcachedStore, *memcachedtest.Server) {
+	server := memcachedtest.NewServer()
+	client := memcached.NewClient(memcached.Options{Address: server.Addr()})
+
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+
+	return cache.NewMemcachedStore(client, prefix...), server
+}
+
+// Listen on a local address answering every command with the given reply,
+// counting the connections it accepts.
+func newScriptedServer(t *testing.T, reply string) (string, *int32) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	t.Cleanup(func() { listener.Close() })
+
+	var connections int32
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+
+			if err != nil {
+				return
+			}
+
+			atomic.AddInt32(&connections, 1)
+
+			go func() {
+				defer conn.Close()
+
+				reader := bufio.NewReader(conn)
+
+				for {
+					if _, err := reader.ReadString('\n'); err != nil {
+						return
+					}
+
+					conn.Write([]byte(reply))
+				}
+			}()
+		}
+	}()
+
+	return listener.Addr().String(), &connections
+}
+
+func TestMemcachedStore(t *testing.T) {
+	t.Run("items can be set and retrieved with a prefix", func(t *testing.T) {
+		store, server := newMemcachedStore(t, "app")
+		assert.True(t, store.Put("foo", []string{"bar"}, 60))
+		assert.Equal(t, []string{"bar"}, store.Get("foo"))
+		assert.Equal(t, int64(60), server.Expiration("app:foo"))
+		assert.Nil(t, store.Get("missing"))
+	})
+
+	t.Run("long expirations are sent as timestamps", func(t *testing.T) {
+		store, server := newMemcachedStore(t)
+		seconds := 60 * 60 * 24 * 31
+		assert.True(t, store.Put("foo", "bar", seconds))
+		assert.InDelta(t, time.Now().Unix()+int64(seconds), server.Expiration("foo"), 2)
+		assert.Equal(t, "bar", store.Get("foo"))
+
+		store.Forever("baz", "qux")
+		assert.Equal(t, int64(0), server.Expiration("baz"))
+	})
+
+	t.Run("many returns nil for missing keys", func(t *testing.T) {
+		store, _ := newMemcachedStore(t)
+		assert.True(t, store.PutMany(map[string]any{"foo": "bar", "baz": 1}, 60))
+		assert.Equal(t, map[string]any{"foo": "bar", "baz": 1, "qux": nil}, store.Many([]string{"foo", "baz", "qux"}))
+	})
+
+	t.Run("add only stores missing items", func(t *testing.T) {
+		store, _ := newMemcachedStore(t)
+		assert.True(t, store.Add("foo", "bar", 60))
+		assert.False(t, store.Add("foo", "baz", 60))
+		assert.Equal(t, "bar", store.Get("foo"))
+	})
+
+	t.Run("values can be incremented and decremented", func(t *testing.T) {
+		store, _ := newMemcachedStore(t)
+		assert.Equal(t, false, store.Increment("foo"))
+		store.Forever("foo", 1)
+		assert.Equal(t, 6, store.Increment("foo", 5))
+		assert.Equal(t, 0, store.Decrement("foo", 10))
+		assert.Equal(t, 0, store.Get("foo"))
+	})
+
+	t.Run("items can be forgotten and flushed", func(t *testing.T) {
+		store, _ := newMemcachedStore(t)
+		store.Forever("foo", "bar")
+		assert.True(t, store.Forget("foo"))
+		assert.False(t, store.Forget("foo"))
+		store.Forever("baz", "qux")
+		assert.True(t, store.Flush())
+		assert.Nil(t, store.Get("baz"))
+	})
+
+	t.Run("connections are discarded after protocol errors", func(t *testing.T) {
+		address, connections := newScriptedServer(t, "VALUE foo 0 corrupt\r\n")
+		store := cache.NewMemcachedStore(memcached.NewClient(memcached.Options{Address: address}))
+
+		for i := 0; i < 2; i++ {
+			_, _, err := store.GetCtx(context.Background(), "foo")
+			assert.ErrorIs(t, err, memcached.ErrProtocol)
+		}
+
+		assert.Equal(t, int32(2), atomic.LoadInt32(connections))
+	})
+
+	t.Run("connections are reused after server errors", func(t *testing.T) {
+		address, connections := newScriptedServer(t, "SERVER_ERROR out of memory\r\n")
+		store := cache.NewMemcachedStore(memcached.NewClient(memcached.Options{Address: address}))
+
+		for i := 0; i < 2; i++ {
+			_, _, err := store.GetCtx(context.Background(), "foo")
+			assert.ErrorAs(t, err, new(memcached.ServerError))
+		}
+
+		assert.Equal(t, int32(1), atomic.LoadInt32(connections))
+	})
+}
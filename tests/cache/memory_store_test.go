@@ -0,0 +1,198 @@
+package cache_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/garavel-core/framework/cache"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	t.Run("items can be set, retrieved and forgotten", func(t *testing.T) {
+		store := cache.NewMemoryStore()
+
+		assert.True(t, store.Put("foo", "bar", 60))
+		assert.Equal(t, "bar", store.Get("foo"))
+		assert.Nil(t, store.Get("missing"))
+		assert.False(t, store.Add("foo", "baz"))
+		assert.True(t, store.Add("new", "baz"))
+		assert.Equal(t, 5, store.Increment("count", 5))
+		assert.Equal(t, 3, store.Decrement("count", 2))
+		assert.True(t, store.Forget("foo"))
+		assert.False(t, store.Forget("foo"))
+		assert.Equal(t, map[string]any{"new": "baz", "count": 3, "foo": nil}, store.Many([]string{"new", "count", "foo"}))
+
+		assert.True(t, store.Flush())
+		assert.Nil(t, store.Get("new"))
+		assert.Equal(t, 0, store.Stats().Entries)
+	})
+
+	t.Run("expired items are removed", func(t *testing.T) {
+		var reasons []cache.EvictionReason
+		store := cache.NewMemoryStore(cache.MemoryStoreOptions{
+			OnEvict: func(key string, value any, reason cache.EvictionReason) {
+				reasons = append(reasons, reason)
+			},
+		})
+
+		store.PutFor("foo", "bar", 10*time.Millisecond)
+		store.PutFor("baz", "qux", 10*time.Millisecond)
+		time.Sleep(20 * time.Millisecond)
+
+		assert.Nil(t, store.Get("foo"))
+		assert.Equal(t, 1, store.Prune())
+		assert.Equal(t, []cache.EvictionReason{cache.EvictedExpired, cache.EvictedExpired}, reasons)
+		assert.Equal(t, uint64(2), store.Stats().Expirations)
+	})
+
+	t.Run("lru evicts the least recently used item", func(t *testing.T) {
+		var evicted []string
+		store := cache.NewMemoryStore(cache.MemoryStoreOptions{
+			MaxEntries: 2,
+			Shards:     1,
+			Policy:     cache.LRU,
+			OnEvict: func(key string, value any, reason cache.EvictionReason) {
+				assert.Equal(t, cache.EvictedForCapacity, reason)
+				evicted = append(evicted, key)
+			},
+		})
+
+		store.Forever("a", 1)
+		store.Forever("b", 2)
+		store.Get("a")
+		store.Forever("c", 3)
+
+		assert.Equal(t, []string{"b"}, evicted)
+		assert.Equal(t, 1, store.Get("a"))
+		assert.Nil(t, store.Get("b"))
+		assert.Equal(t, 3, store.Get("c"))
+		assert.Equal(t, uint64(1), store.Stats().Evictions)
+	})
+
+	t.Run("lfu evicts the least frequently used item", func(t *testing.T) {
+		store := cache.NewMemoryStore(cache.MemoryStoreOptions{MaxEntries: 2, Shards: 1, Policy: cache.LFU})
+
+		store.Forever("a", 1)
+		store.Forever("b", 2)
+		store.Get("a")
+		store.Get("a")
+		store.Get("b")
+		store.Get("b")
+		store.Get("b")
+		store.Forever("c", 3)
+
+		assert.Nil(t, store.Get("a"))
+		assert.Equal(t, 2, store.Get("b"))
+		assert.Equal(t, 3, store.Get("c"))
+	})
+
+	t.Run("tinylfu only admits items used more often than the victim", func(t *testing.T) {
+		store := cache.NewMemoryStore(cache.MemoryStoreOptions{MaxEntries: 1, Policy: cache.TinyLFU})
+
+		store.Forever("hot", 1)
+
+		for i := 0; i < 5; i++ {
+			store.Get("hot")
+		}
+
+		assert.False(t, store.Forever("cold", 2))
+		assert.Equal(t, 1, store.Get("hot"))
+		assert.Equal(t, uint64(1), store.Stats().Rejections)
+
+		for i := 0; i < 10; i++ {
+			store.Get("popular")
+		}
+
+		assert.True(t, store.Forever("popular", 3))
+		assert.Nil(t, store.Get("hot"))
+		assert.Equal(t, 3, store.Get("popular"))
+	})
+
+	t.Run("the byte budget bounds the store", func(t *testing.T) {
+		store := cache.NewMemoryStore(cache.MemoryStoreOptions{
+			MaxBytes: 1000,
+			Shards:   1,
+			Sizer:    func(value any) int64 { return int64(len(value.(string))) },
+		})
+
+		for i := 0; i < 10; i++ {
+			assert.True(t, store.Forever(fmt.Sprint(i), strings.Repeat("x", 200)))
+		}
+
+		stats := store.Stats()
+		assert.LessOrEqual(t, stats.Bytes, int64(1000))
+		assert.Equal(t, 3, stats.Entries)
+		assert.Equal(t, uint64(7), stats.Evictions)
+
+		assert.False(t, store.Forever("9", strings.Repeat("x", 2000)))
+		assert.Nil(t, store.Get("9"))
+	})
+
+	t.Run("values are measured approximately by default", func(t *testing.T) {
+		store := cache.NewMemoryStore()
+
+		store.Forever("small", "x")
+		small := store.Stats().Bytes
+		store.Forever("large", map[string][]byte{"data": make([]byte, 4096)})
+
+		assert.Greater(t, store.Stats().Bytes-small, int64(4096))
+	})
+
+	t.Run("limits are shared by the shards", func(t *testing.T) {
+		store := cache.NewMemoryStore(cache.MemoryStoreOptions{MaxEntries: 100, Shards: 8})
+
+		for i := 0; i < 1000; i++ {
+			store.Forever(fmt.Sprint(i), i)
+		}
+
+		assert.LessOrEqual(t, store.Stats().Entries, 100)
+	})
+
+	t.Run("concurrent access is safe", func(t *testing.T) {
+		store := cache.NewMemoryStore(cache.MemoryStoreOptions{MaxEntries: 64, Policy: cache.TinyLFU})
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+
+				for j := 0; j < 500; j++ {
+					key := fmt.Sprint(j % 100)
+					store.Get(key)
+					store.Put(key, j, 60)
+					store.Increment("counter")
+				}
+			}(i)
+		}
+
+		wg.Wait()
+
+		stats := store.Stats()
+		assert.LessOrEqual(t, stats.Entries, 64)
+		assert.Equal(t, uint64(4000), stats.Hits+stats.Misses)
+	})
+
+	t.Run("the manager creates memory stores", func(t *testing.T) {
+		manager := cache.NewManager(map[string]any{
+			"stores": map[string]any{
+				"bounded": map[string]any{"driver": "memory", "max_entries": 10, "policy": "lfu"},
+				"broken":  map[string]any{"driver": "memory", "policy": "fifo"},
+			},
+		})
+
+		repository, err := manager.Store("bounded")
+		assert.NoError(t, err)
+		assert.Equal(t, cache.LFU, repository.GetStore().(*cache.MemoryStore).GetPolicy())
+
+		_, err = manager.Store("broken")
+		assert.EqualError(t, err, "eviction policy [fifo] is not supported")
+	})
+}
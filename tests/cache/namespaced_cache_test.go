@@ -0,0 +1,118 @@
+package cache_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/garavel-core/framework/cache"
+	contracts "github.com/garavel-core/framework/contracts/cache"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamespacedCache(t *testing.T) {
+	stores := map[string]func(t *testing.T) contracts.Store{
+		"array": func(t *testing.T) contracts.Store {
+			return cache.NewArrayStore()
+		},
+		"redis": func(t *testing.T) contracts.Store {
+			store, _ := newRedisStore(t)
+			return store
+		},
+		"memcached": func(t *testing.T) contracts.Store {
+			store, _ := newMemcachedStore(t, "app")
+			return store
+		},
+	}
+
+	for name, factory := range stores {
+		t.Run(name, func(t *testing.T) {
+			t.Run("items are scoped by their namespace", func(t *testing.T) {
+				repository := cache.NewRepository(factory(t))
+				users := repository.Namespace("users")
+				posts := repository.Namespace("posts")
+
+				assert.True(t, users.Put("1", "Taylor", 60))
+				assert.True(t, posts.Put("1", "Hello", 60))
+
+				assert.Equal(t, "Taylor", users.Get("1"))
+				assert.Equal(t, "Hello", posts.Get("1"))
+				assert.Nil(t, repository.Get("1"))
+				assert.Equal(t, "Taylor", repository.Namespace("users").Get("1"))
+			})
+
+			t.Run("flushing a namespace bumps its version", func(t *testing.T) {
+				repository := cache.NewRepository(factory(t))
+				users := repository.Namespace("users")
+				posts := repository.Namespace("posts")
+
+				repository.Forever("name", "global")
+				users.Forever("name", "Taylor")
+				posts.Forever("name", "Hello")
+				version := users.Version()
+
+				assert.True(t, users.Flush())
+
+				assert.NotEqual(t, version, users.Version())
+				assert.Nil(t, users.Get("name"))
+				assert.Equal(t, "Hello", posts.Get("name"))
+				assert.Equal(t, "global", repository.Get("name"))
+			})
+
+			t.Run("flushing an outer namespace flushes nested ones", func(t *testing.T) {
+				repository := cache.NewRepository(factory(t))
+				tenant := repository.Namespace("tenant:1")
+				users := tenant.Namespace("users")
+
+				users.Forever("1", "Taylor")
+				assert.Equal(t, "Taylor", users.Get("1"))
+
+				tenant.Flush()
+
+				assert.Nil(t, users.Get("1"))
+			})
+
+			t.Run("tags of a namespace stay within it", func(t *testing.T) {
+				repository := cache.NewRepository(factory(t))
+				users, _ := repository.Namespace("users").Tags("people")
+				posts, _ := repository.Namespace("posts").Tags("people")
+				global, _ := repository.Tags("people")
+
+				assert.True(t, users.Put("1", "Taylor", 60))
+				assert.Nil(t, posts.Get("1"))
+				assert.Nil(t, global.Get("1"))
+
+				posts.Flush()
+				assert.Equal(t, "Taylor", users.Get("1"))
+
+				repository.Namespace("users").Flush()
+				assert.Nil(t, users.Get("1"))
+			})
+
+			t.Run("counters and many use namespaced keys", func(t *testing.T) {
+				repository := cache.NewRepository(factory(t))
+				users := repository.Namespace("users")
+
+				users.Forever("visits", 1)
+				users.Increment("visits", 2)
+				users.PutMany(map[string]any{"a": "1", "b": "2"}, 60)
+
+				assert.EqualValues(t, 3, users.Get("visits"))
+				assert.Equal(t, map[string]any{"a": "1", "b": "2"}, users.Many([]string{"a", "b"}))
+				assert.Nil(t, repository.Get("visits"))
+			})
+
+			t.Run("overlong keys are hashed consistently", func(t *testing.T) {
+				repository := cache.NewRepository(factory(t))
+				users := repository.Namespace("users")
+				key := strings.Repeat("k", 300)
+
+				assert.True(t, users.Put(key, "long", 60))
+				assert.Equal(t, "long", users.Get(key))
+				assert.Nil(t, users.Get(strings.Repeat("k", 299)))
+				assert.True(t, users.Forget(key))
+				assert.Nil(t, users.Get(key))
+			})
+		})
+	}
+}
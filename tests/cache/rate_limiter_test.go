@@ -0,0 +1,257 @@
+package cache_test
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/garavel-core/framework/cache"
+	"github.com/garavel-core/framework/cache/cachetest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("hits are counted until the limit is reached", func(t *testing.T) {
+		limiter := cache.NewRateLimiter(cache.NewRepository(cache.NewArrayStore()))
+
+		assert.False(t, limiter.TooManyAttempts("login", 2))
+		assert.Equal(t, 1, limiter.Hit("login"))
+		assert.Equal(t, 2, limiter.Hit("login"))
+		assert.True(t, limiter.TooManyAttempts("login", 2))
+		assert.Equal(t, 2, limiter.Attempts("login"))
+		assert.Equal(t, 0, limiter.Remaining("login", 2))
+		assert.Equal(t, 3, limiter.RetriesLeft("login", 5))
+		assert.InDelta(t, 60, limiter.AvailableIn("login"), 1)
+	})
+
+	t.Run("attempt runs the callback within the limit", func(t *testing.T) {
+		limiter := cache.NewRateLimiter(cache.NewRepository(cache.NewArrayStore()))
+		calls := 0
+		callback := func() any {
+			calls++
+			return nil
+		}
+
+		assert.Equal(t, true, limiter.Attempt("send", 2, callback))
+		assert.Equal(t, "sent", limiter.Attempt("send", 2, func() any { return "sent" }))
+		assert.Equal(t, false, limiter.Attempt("send", 2, callback))
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("clear resets the attempts and the timer", func(t *testing.T) {
+		repository := cache.NewRepository(cache.NewArrayStore())
+		limiter := cache.NewRateLimiter(repository)
+		limiter.Hit("login")
+		limiter.Clear("login")
+
+		assert.Equal(t, 0, limiter.Attempts("login"))
+		assert.Equal(t, 0, limiter.AvailableIn("login"))
+		assert.False(t, repository.Has("login:timer"))
+	})
+
+	t.Run("attempts reset once the window decays", func(t *testing.T) {
+		limiter := cache.NewRateLimiter(cache.NewRepository(cache.NewArrayStore()))
+		limiter.Hit("login", 1)
+		assert.True(t, limiter.TooManyAttempts("login", 1))
+		time.Sleep(1100 * time.Millisecond)
+		assert.False(t, limiter.TooManyAttempts("login", 1))
+		assert.Equal(t, 1, limiter.Hit("login", 1))
+	})
+
+	t.Run("counters are shared through the store", func(t *testing.T) {
+		redisStore, _ := newRedisStore(t)
+		first := cache.NewRateLimiter(cache.NewRepository(redisStore))
+		second := cache.NewRateLimiter(cache.NewRepository(redisStore))
+
+		first.Hit("api")
+		second.Hit("api")
+		assert.Equal(t, 3, first.Hit("api"))
+		assert.Equal(t, 3, second.Attempts("api"))
+	})
+
+	t.Run("limits are built fluently", func(t *testing.T) {
+		assert.Equal(t, cache.Limit{MaxAttempts: 5, DecaySeconds: 60}, cache.PerMinute(5))
+		assert.Equal(t, cache.Limit{MaxAttempts: 5, DecaySeconds: 180}, cache.PerMinutes(3, 5))
+		assert.Equal(t, cache.Limit{MaxAttempts: 5, DecaySeconds: 7200}, cache.PerHour(5, 2))
+		assert.Equal(t, cache.Limit{MaxAttempts: 5, DecaySeconds: 86400}, cache.PerDay(5))
+		assert.Equal(t, cache.Limit{Key: "1", MaxAttempts: 5, DecaySeconds: 1, Sliding: true}, cache.PerSecond(5).By("1").SlidingWindow())
+		assert.True(t, cache.Unlimited().IsUnlimited())
+	})
+
+	t.Run("named limiters resolve limits per key", func(t *testing.T) {
+		limiter := cache.NewRateLimiter(cache.NewRepository(cache.NewArrayStore()))
+		limiter.For("uploads", func(args ...any) []cache.Limit {
+			if args[0] == "admin" {
+				return []cache.Limit{cache.Unlimited()}
+			}
+
+			return []cache.Limit{cache.PerMinute(2).By(fmt.Sprint(args[0])), cache.PerHour(3).By("global")}
+		})
+		assert.NotNil(t, limiter.Limiter("uploads"))
+
+		upload := func() any { return "uploaded" }
+
+		for _, user := range []string{"taylor", "taylor"} {
+			result, err := limiter.AttemptFor("uploads", upload, user)
+			assert.NoError(t, err)
+			assert.Equal(t, "uploaded", result)
+		}
+
+		// The per-minute limit of the user is exhausted.
+		result, _ := limiter.AttemptFor("uploads", upload, "taylor")
+		assert.Equal(t, false, result)
+
+		// The hourly limit shared by every user allows one more upload.
+		result, _ = limiter.AttemptFor("uploads", upload, "abigail")
+		assert.Equal(t, "uploaded", result)
+		result, _ = limiter.AttemptFor("uploads", upload, "james")
+		assert.Equal(t, false, result)
+
+		for i := 0; i < 5; i++ {
+			result, _ = limiter.AttemptFor("uploads", upload, "admin")
+			assert.Equal(t, "uploaded", result)
+		}
+
+		_, err := limiter.AttemptFor("downloads", upload)
+		assert.EqualError(t, err, "cache: rate limiter [downloads] is not defined")
+	})
+
+	t.Run("limits sharing a key are counted separately", func(t *testing.T) {
+		clock := cachetest.NewClock()
+		limiter := cache.NewRateLimiter(cache.NewRepository(cache.NewArrayStore().SetClock(clock.Now)).SetClock(clock.Now))
+		limiter.For("api", func(args ...any) []cache.Limit {
+			return []cache.Limit{cache.PerMinute(5).By("user"), cache.PerHour(7).By("user")}
+		})
+
+		passed := 0
+
+		for i := 0; i < 10; i++ {
+			if result, _ := limiter.AttemptFor("api", func() any { return nil }); result == true {
+				passed++
+			}
+		}
+
+		assert.Equal(t, 5, passed)
+
+		// The minute is over, the hourly limit only has two attempts left.
+		clock.Advance(61 * time.Second)
+		passed = 0
+
+		for i := 0; i < 10; i++ {
+			if result, _ := limiter.AttemptFor("api", func() any { return nil }); result == true {
+				passed++
+			}
+		}
+
+		assert.Equal(t, 2, passed)
+	})
+
+	t.Run("sliding windows count hits", func(t *testing.T) {
+		limiter := cache.NewRateLimiter(cache.NewRepository(cache.NewArrayStore())).SlidingWindow()
+
+		assert.Equal(t, 1, limiter.Hit("api", 3600))
+		assert.Equal(t, 2, limiter.Hit("api", 3600))
+		assert.True(t, limiter.TooManyAttempts("api", 2))
+		assert.False(t, limiter.TooManyAttempts("api", 3))
+		assert.Equal(t, 1, limiter.Remaining("api", 3))
+
+		available := limiter.AvailableIn("api", 2)
+		assert.Greater(t, available, 0)
+		assert.LessOrEqual(t, available, 7200)
+
+		limiter.Clear("api")
+		assert.Equal(t, 0, limiter.Attempts("api"))
+		assert.Equal(t, 0, limiter.AvailableIn("api", 2))
+	})
+
+	t.Run("sliding windows weigh the previous window", func(t *testing.T) {
+		repository := cache.NewRepository(cache.NewArrayStore())
+		limiter := cache.NewRateLimiter(repository).SlidingWindow()
+		limiter.Hit("api", 3600)
+		limiter.Clear("api")
+
+		now := time.Now()
+		window := now.Unix() / 3600
+		elapsed := now.Sub(time.Unix(window*3600, 0)).Seconds() / 3600
+		repository.Put("api:window", 3600, 7200)
+		repository.Put(fmt.Sprintf("api:%d", window-1), 100, 7200)
+
+		assert.InDelta(t, int(100*(1-elapsed)), limiter.Attempts("api"), 1)
+		assert.InDelta(t, int(100*(1-elapsed))+1, limiter.Hit("api", 3600), 1)
+
+		// The previous hits weigh less as the window slides, so the limit is
+		// reached again once enough of them have slid out.
+		// With one current hit, the previous hits may weigh 9 at most: 1 - 9/100.
+		expected := (0.91 - elapsed) * 3600
+		if expected < 0 {
+			expected = 0
+		}
+		assert.InDelta(t, expected, limiter.AvailableIn("api", 10), 2)
+	})
+
+	t.Run("concurrent attempts do not pass the limit together", func(t *testing.T) {
+		for name, limiter := range map[string]*cache.RateLimiter{
+			"fixed":   cache.NewRateLimiter(cache.NewRepository(cache.NewArrayStore())),
+			"sliding": cache.NewRateLimiter(cache.NewRepository(cache.NewArrayStore())).SlidingWindow(),
+		} {
+			var wg sync.WaitGroup
+			var passed int32
+
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+
+				go func() {
+					defer wg.Done()
+
+					if limiter.Attempt("send", 5, func() any { return nil }, 3600) == true {
+						atomic.AddInt32(&passed, 1)
+					}
+				}()
+			}
+
+			wg.Wait()
+
+			assert.Equal(t, int32(5), passed, name)
+			assert.Equal(t, 5, limiter.Attempts("send"), name)
+		}
+	})
+
+	t.Run("rejected named limits take back the other hits", func(t *testing.T) {
+		limiter := cache.NewRateLimiter(cache.NewRepository(cache.NewArrayStore()))
+		limiter.For("uploads", func(args ...any) []cache.Limit {
+			return []cache.Limit{cache.PerMinute(10).By("minute"), cache.PerHour(1).By("hour").SlidingWindow()}
+		})
+
+		result, _ := limiter.AttemptFor("uploads", func() any { return nil })
+		assert.Equal(t, true, result)
+		result, _ = limiter.AttemptFor("uploads", func() any { return nil })
+		assert.Equal(t, false, result)
+
+		assert.Equal(t, 1, limiter.Attempts("uploads:minute:fixed:10:60"))
+		assert.Equal(t, 1, limiter.SlidingWindow().Attempts("uploads:hour:sliding:1:3600"))
+	})
+
+	t.Run("sliding hits take three round trips", func(t *testing.T) {
+		spy := cachetest.NewSpyStore(cache.NewArrayStore())
+		limiter := cache.NewRateLimiter(cache.NewRepository(spy)).SlidingWindow()
+
+		limiter.Hit("api", 3600)
+		spy.Reset()
+
+		assert.Equal(t, 2, limiter.Hit("api", 3600))
+		assert.Len(t, spy.Calls(), 3)
+	})
+
+	t.Run("sliding windows without a decay do not fail", func(t *testing.T) {
+		clock := cachetest.NewClock()
+		repository := cache.NewRepository(cache.NewArrayStore().SetClock(clock.Now)).SetClock(clock.Now)
+		limiter := cache.NewRateLimiter(repository).SlidingWindow()
+
+		assert.False(t, limiter.ResetAttempts("api"))
+		assert.Equal(t, 1, limiter.Increment("api", 0, 1))
+		assert.Equal(t, 1, limiter.Attempts("api"))
+	})
+}
@@ -0,0 +1,99 @@
+package cache_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/garavel-core/framework/cache"
+	"github.com/garavel-core/framework/redis"
+	"github.com/garavel-core/framework/redis/redistest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newRedisStore(t *testing.T, prefix ...string) (*cache.RedisStore, *redistest.Server) {
+	server := redistest.NewServer()
+	connection := redis.NewConnection(redis.Options{Address: server.Addr()})
+
+	t.Cleanup(func() {
+		connection.Close()
+		server.Close()
+	})
+
+	return cache.NewRedisStore(connection, prefix...), server
+}
+
+func TestRedisStore(t *testing.T) {
+	t.Run("items can be set and retrieved with a prefix", func(t *testing.T) {
+		store, server := newRedisStore(t, "app")
+		assert.Equal(t, "app:", store.GetPrefix())
+		assert.True(t, store.Put("foo", map[string]any{"bar": "baz"}, 60))
+		assert.Equal(t, map[string]any{"bar": "baz"}, store.Get("foo"))
+		assert.Nil(t, store.Get("missing"))
+
+		connection := redis.NewConnection(redis.Options{Address: server.Addr()})
+		defer connection.Close()
+		reply, err := connection.Command("EXISTS", "app:foo")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), reply)
+	})
+
+	t.Run("many uses a single round trip", func(t *testing.T) {
+		store, server := newRedisStore(t)
+		assert.True(t, store.PutMany(map[string]any{"foo": "bar", "baz": 1}, 60))
+		assert.Equal(t, map[string]any{"foo": "bar", "baz": 1, "qux": nil}, store.Many([]string{"foo", "baz", "qux"}))
+		assert.Contains(t, server.Commands(), "MGET")
+	})
+
+	t.Run("add only stores missing items", func(t *testing.T) {
+		store, _ := newRedisStore(t)
+		assert.True(t, store.Add("foo", "bar", 60))
+		assert.False(t, store.Add("foo", "baz", 60))
+		assert.Equal(t, "bar", store.Get("foo"))
+	})
+
+	t.Run("values can be incremented and decremented", func(t *testing.T) {
+		store, _ := newRedisStore(t)
+		assert.Equal(t, 5, store.Increment("foo", 5))
+		store.Forever("bar", 10)
+		assert.Equal(t, 8, store.Decrement("bar", 2))
+		assert.Equal(t, 8, store.Get("bar"))
+		store.Forever("baz", "qux")
+		assert.Equal(t, false, store.Increment("baz"))
+	})
+
+	t.Run("floored decrements run as a single script", func(t *testing.T) {
+		store, server := newRedisStore(t, "app")
+
+		for i := 0; i < 20; i++ {
+			store.Forever("counter", 3)
+
+			var wg sync.WaitGroup
+			for j := 0; j < 2; j++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					result, err := store.DecrementBy("counter", 5, true)
+					assert.NoError(t, err)
+					assert.GreaterOrEqual(t, result, int64(0))
+				}()
+			}
+			wg.Wait()
+
+			assert.Equal(t, 0, store.Get("counter"))
+		}
+
+		assert.Contains(t, server.Commands(), "EVAL")
+		assert.NotContains(t, server.Commands(), "DECRBY")
+	})
+
+	t.Run("items can be forgotten and flushed", func(t *testing.T) {
+		store, _ := newRedisStore(t)
+		store.Forever("foo", "bar")
+		assert.True(t, store.Forget("foo"))
+		assert.False(t, store.Forget("foo"))
+		store.Forever("baz", "qux")
+		assert.True(t, store.Flush())
+		assert.Nil(t, store.Get("baz"))
+	})
+}
@@ -0,0 +1,55 @@
+package cache_test
+
+import (
+	"testing"
+
+	"github.com/garavel-core/framework/cache"
+	"github.com/garavel-core/framework/events"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryEvents(t *testing.T) {
+	newRepository := func() (*cache.Repository, *[]any) {
+		repository := cache.NewRepository(cache.NewArrayStore(), map[string]any{"store": "array"})
+		dispatcher := events.NewDispatcher()
+		fired := &[]any{}
+		dispatcher.Listen("github.com/garavel-core/framework/cache.*", func(event string, payload ...any) any {
+			*fired = append(*fired, payload[0])
+			return nil
+		})
+		repository.SetEventDispatcher(dispatcher)
+		return repository, fired
+	}
+
+	t.Run("hits and misses are dispatched", func(t *testing.T) {
+		repository, fired := newRepository()
+		repository.Get("foo")
+		repository.Forever("foo", "bar")
+		repository.Get("foo")
+
+		assert.Equal(t, []any{
+			cache.NewCacheMissed("array", "foo"),
+			cache.NewKeyWritten("array", "foo", "bar", 0),
+			cache.NewCacheHit("array", "foo", "bar"),
+		}, *fired)
+	})
+
+	t.Run("forgotten keys are dispatched", func(t *testing.T) {
+		repository, fired := newRepository()
+		repository.Forever("foo", "bar")
+		repository.Forget("foo")
+		repository.Forget("foo")
+
+		assert.Equal(t, cache.NewKeyForgotten("array", "foo"), (*fired)[1])
+		assert.Len(t, *fired, 2)
+	})
+
+	t.Run("tagged events carry their tags", func(t *testing.T) {
+		repository, fired := newRepository()
+		people, _ := repository.Tags("people")
+		people.Forever("foo", "bar")
+
+		assert.Contains(t, *fired, cache.NewKeyWritten("array", "foo", "bar", 0, "people"))
+	})
+}
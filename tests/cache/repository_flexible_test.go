@@ -0,0 +1,167 @@
+package cache_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/garavel-core/framework/cache"
+	"github.com/garavel-core/framework/cache/cachetest"
+	"github.com/garavel-core/framework/events"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryFlexible(t *testing.T) {
+	ttl := [2]any{100 * time.Millisecond, 400 * time.Millisecond}
+
+	t.Run("missing values are computed synchronously", func(t *testing.T) {
+		repository := cache.NewRepository(cache.NewArrayStore())
+		assert.Equal(t, "bar", repository.Flexible("foo", ttl, func() any {
+			return "bar"
+		}))
+		assert.Equal(t, "bar", repository.Get("foo"))
+	})
+
+	t.Run("fresh values are returned without calling the callback", func(t *testing.T) {
+		repository := cache.NewRepository(cache.NewArrayStore())
+		repository.Flexible("foo", ttl, func() any { return "bar" })
+		assert.Equal(t, "bar", repository.Flexible("foo", ttl, func() any {
+			t.Error("the callback should not run")
+
+			return "baz"
+		}))
+	})
+
+	t.Run("stale values are returned and refreshed in the background", func(t *testing.T) {
+		repository := cache.NewRepository(cache.NewArrayStore())
+		repository.Flexible("foo", ttl, func() any { return "bar" })
+		time.Sleep(150 * time.Millisecond)
+
+		refreshed := make(chan struct{})
+		assert.Equal(t, "bar", repository.Flexible("foo", ttl, func() any {
+			defer close(refreshed)
+
+			return "baz"
+		}))
+
+		select {
+		case <-refreshed:
+		case <-time.After(time.Second):
+			t.Fatal("the value was not refreshed")
+		}
+
+		assert.Eventually(t, func() bool {
+			return repository.Get("foo") == "baz"
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("a single goroutine refreshes stale values", func(t *testing.T) {
+		repository := cache.NewRepository(cache.NewArrayStore())
+		repository.Flexible("foo", ttl, func() any { return "bar" })
+		time.Sleep(150 * time.Millisecond)
+
+		var calls int32
+		release := make(chan struct{})
+		var wg sync.WaitGroup
+
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				assert.Equal(t, "bar", repository.Flexible("foo", ttl, func() any {
+					atomic.AddInt32(&calls, 1)
+					<-release
+
+					return "baz"
+				}))
+			}()
+		}
+
+		wg.Wait()
+		close(release)
+
+		assert.Eventually(t, func() bool {
+			return repository.Get("foo") == "baz"
+		}, time.Second, 10*time.Millisecond)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("expired values are computed synchronously", func(t *testing.T) {
+		repository := cache.NewRepository(cache.NewArrayStore())
+		repository.Flexible("foo", ttl, func() any { return "bar" })
+		time.Sleep(450 * time.Millisecond)
+
+		assert.Equal(t, "baz", repository.Flexible("foo", ttl, func() any {
+			return "baz"
+		}))
+	})
+
+	t.Run("file stores refresh under a lock", func(t *testing.T) {
+		repository := cache.NewRepository(cache.NewFileStore(t.TempDir()))
+		ttl := [2]any{1, 10}
+		repository.Flexible("foo", ttl, func() any { return "bar" })
+		time.Sleep(1100 * time.Millisecond)
+
+		assert.Equal(t, "bar", repository.Flexible("foo", ttl, func() any { return "baz" }))
+		assert.Eventually(t, func() bool {
+			return repository.Get("foo") == "baz"
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("panicking refreshes are reported and release the lock", func(t *testing.T) {
+		clock := cachetest.NewClock()
+		repository := cache.NewRepository(cache.NewArrayStore().SetClock(clock.Now)).SetClock(clock.Now)
+		failures := make(chan cache.CacheRefreshFailed, 1)
+		dispatcher := events.NewDispatcher()
+		events.ListenTo(dispatcher, func(event cache.CacheRefreshFailed) {
+			failures <- event
+		})
+		repository.SetEventDispatcher(dispatcher)
+
+		repository.Flexible("foo", ttl, func() any { return "bar" })
+		clock.Advance(150 * time.Millisecond)
+
+		assert.Equal(t, "bar", repository.Flexible("foo", ttl, func() any {
+			panic("boom")
+		}))
+
+		select {
+		case event := <-failures:
+			assert.Equal(t, "foo", event.Key)
+			assert.EqualError(t, event.Err, "cache: the refresh panicked: boom")
+		case <-time.After(time.Second):
+			t.Fatal("the panic was not reported")
+		}
+
+		assert.Equal(t, "bar", repository.Get("foo"))
+		assert.Equal(t, "bar", repository.Flexible("foo", ttl, func() any { return "baz" }))
+		assert.Eventually(t, func() bool {
+			return repository.Get("foo") == "baz"
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("unsupported ttls panic before anything is cached", func(t *testing.T) {
+		repository := cache.NewRepository(cache.NewArrayStore())
+
+		invalid := map[string][2]any{
+			`cache: the TTL is not supported: "soon"`:  {"soon", time.Minute},
+			`cache: the TTL is not supported: "later"`: {time.Second, "later"},
+		}
+
+		for message, ttl := range invalid {
+			assert.PanicsWithError(t, message, func() {
+				repository.Flexible("foo", ttl, func() any {
+					t.Error("the callback should not run")
+
+					return "bar"
+				})
+			})
+		}
+
+		assert.Nil(t, repository.Get("foo"))
+	})
+}
@@ -0,0 +1,143 @@
+package cache_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/garavel-core/framework/cache"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryRemember(t *testing.T) {
+	// Run the remember call from many goroutines at once and count the callback runs.
+	concurrently := func(remember func(callback func() any) any) (int32, []any) {
+		var calls int32
+		var wg sync.WaitGroup
+		var mutex sync.Mutex
+		var results []any
+		start := make(chan struct{})
+
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+				<-start
+
+				result := remember(func() any {
+					atomic.AddInt32(&calls, 1)
+					time.Sleep(100 * time.Millisecond)
+
+					return "bar"
+				})
+
+				mutex.Lock()
+				results = append(results, result)
+				mutex.Unlock()
+			}()
+		}
+
+		close(start)
+		wg.Wait()
+
+		return calls, results
+	}
+
+	t.Run("concurrent misses share one callback run", func(t *testing.T) {
+		repository := cache.NewRepository(cache.NewArrayStore())
+		calls, results := concurrently(func(callback func() any) any {
+			return repository.Remember("foo", 10, callback)
+		})
+		assert.Equal(t, int32(1), calls)
+		assert.Len(t, results, 20)
+
+		for _, result := range results {
+			assert.Equal(t, "bar", result)
+		}
+
+		assert.Equal(t, "bar", repository.Get("foo"))
+	})
+
+	t.Run("concurrent misses of remembered forever items share one callback run", func(t *testing.T) {
+		repository := cache.NewRepository(cache.NewArrayStore())
+		calls, _ := concurrently(func(callback func() any) any {
+			return repository.RememberForever("foo", callback)
+		})
+		assert.Equal(t, int32(1), calls)
+		assert.Equal(t, "bar", repository.Get("foo"))
+	})
+
+	t.Run("coalescing can be disabled", func(t *testing.T) {
+		repository := cache.NewRepository(cache.NewArrayStore()).CoalesceRemember(false)
+		calls, _ := concurrently(func(callback func() any) any {
+			return repository.Remember("foo", 10, callback)
+		})
+		assert.Greater(t, calls, int32(1))
+	})
+
+	t.Run("different keys are not coalesced", func(t *testing.T) {
+		repository := cache.NewRepository(cache.NewArrayStore())
+		var i int32
+		calls, _ := concurrently(func(callback func() any) any {
+			return repository.Remember(string(rune('a'+atomic.AddInt32(&i, 1))), 10, callback)
+		})
+		assert.Equal(t, int32(20), calls)
+	})
+
+	t.Run("panics reach every caller", func(t *testing.T) {
+		repository := cache.NewRepository(cache.NewArrayStore())
+		assert.PanicsWithValue(t, "boom", func() {
+			repository.Remember("foo", 10, func() any {
+				panic("boom")
+			})
+		})
+		assert.Equal(t, "bar", repository.Remember("foo", 10, func() any {
+			return "bar"
+		}))
+	})
+
+	t.Run("processes take turns under the remember lock", func(t *testing.T) {
+		directory := t.TempDir()
+		var calls int32
+		var wg sync.WaitGroup
+
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+
+			// Each repository stands in for a separate process sharing the cache.
+			repository := cache.NewRepository(cache.NewFileStore(directory)).CoalesceRemember(false).LockRemember(10, 5)
+
+			go func() {
+				defer wg.Done()
+
+				assert.Equal(t, "bar", repository.Remember("foo", 10, func() any {
+					atomic.AddInt32(&calls, 1)
+					time.Sleep(100 * time.Millisecond)
+
+					return "bar"
+				}))
+			}()
+		}
+
+		wg.Wait()
+		assert.Equal(t, int32(1), calls)
+	})
+
+	t.Run("the manager configures coalescing and the remember lock", func(t *testing.T) {
+		manager := cache.NewManager(map[string]any{
+			"default": "array",
+			"stores": map[string]any{
+				"array": map[string]any{"driver": "array", "coalesce": false, "remember_lock": 10},
+			},
+		})
+		repository, err := manager.Store()
+		assert.NoError(t, err)
+		calls, _ := concurrently(func(callback func() any) any {
+			return repository.Remember("foo", 10, callback)
+		})
+		assert.Equal(t, int32(1), calls)
+	})
+}
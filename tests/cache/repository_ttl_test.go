@@ -0,0 +1,98 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/garavel-core/framework/cache"
+	contracts "github.com/garavel-core/framework/contracts/cache"
+	"github.com/garavel-core/framework/events"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryTTL(t *testing.T) {
+	written := func(repository *cache.Repository) *[]int {
+		seconds := &[]int{}
+		dispatcher := events.NewDispatcher()
+		events.ListenTo(dispatcher, func(event cache.KeyWritten) {
+			*seconds = append(*seconds, event.Seconds)
+		})
+		repository.SetEventDispatcher(dispatcher)
+		return seconds
+	}
+
+	t.Run("ttl formats are resolved to seconds", func(t *testing.T) {
+		repository := cache.NewRepository(cache.NewFileStore(t.TempDir()))
+		seconds := written(repository)
+
+		assert.True(t, repository.Put("int", "value", 10))
+		assert.True(t, repository.Put("duration", "value", 2*time.Minute))
+		assert.True(t, repository.Put("time", "value", time.Now().Add(time.Hour)))
+		assert.True(t, repository.Put("string", "value", "10m"))
+		assert.True(t, repository.Put("fraction", "value", 1500*time.Millisecond))
+		assert.True(t, repository.Put("callback", "value", func(value any) time.Duration {
+			return time.Duration(len(value.(string))) * time.Second
+		}))
+
+		assert.Equal(t, []int{10, 120, 3600, 600, 2, 5}, *seconds)
+		assert.Equal(t, "value", repository.Get("time"))
+	})
+
+	t.Run("expired ttls remove the item", func(t *testing.T) {
+		repository := cache.NewRepository(cache.NewArrayStore())
+		repository.Forever("foo", "bar")
+		assert.True(t, repository.Put("foo", "baz", time.Now().Add(-time.Minute)))
+		assert.Nil(t, repository.Get("foo"))
+		assert.False(t, repository.Add("foo", "baz", 0))
+	})
+
+	t.Run("unsupported ttls leave the cache untouched", func(t *testing.T) {
+		repository := cache.NewRepository(cache.NewArrayStore())
+		repository.Forever("foo", "bar")
+
+		assert.False(t, repository.Put("foo", "baz", "10 minutes"))
+		assert.False(t, repository.Put("foo", "baz", struct{}{}))
+		assert.False(t, repository.PutMany(map[string]any{"foo": "baz"}, "soon"))
+		assert.False(t, repository.Add("new", "baz", "soon"))
+		assert.Equal(t, "bar", repository.Get("foo"))
+		assert.Nil(t, repository.Get("new"))
+
+		ctx := context.Background()
+		assert.ErrorIs(t, repository.PutCtx(ctx, "foo", "baz", "10 minutes"), cache.ErrInvalidTTL)
+		assert.ErrorIs(t, repository.PutManyCtx(ctx, map[string]any{"foo": "baz"}, "soon"), cache.ErrInvalidTTL)
+
+		_, err := repository.AddCtx(ctx, "new", "baz", "soon")
+		assert.ErrorIs(t, err, cache.ErrInvalidTTL)
+		assert.Equal(t, "bar", repository.Get("foo"))
+	})
+
+	t.Run("sub-second ttls are kept by precise stores", func(t *testing.T) {
+		stores := map[string]contracts.Store{"array": cache.NewArrayStore()}
+		stores["redis"], _ = newRedisStore(t)
+
+		for name, store := range stores {
+			t.Run(name, func(t *testing.T) {
+				repository := cache.NewRepository(store)
+				assert.True(t, repository.Put("foo", "bar", 100*time.Millisecond))
+				assert.True(t, repository.PutMany(map[string]any{"baz": "qux"}, "100ms"))
+				assert.True(t, repository.Add("quz", "qux", 100*time.Millisecond))
+				assert.Equal(t, "bar", repository.Get("foo"))
+				time.Sleep(150 * time.Millisecond)
+				assert.Nil(t, repository.Get("foo"))
+				assert.Nil(t, repository.Get("baz"))
+				assert.Nil(t, repository.Get("quz"))
+			})
+		}
+	})
+
+	t.Run("remember resolves callback ttls with the value", func(t *testing.T) {
+		repository := cache.NewRepository(cache.NewArrayStore())
+		seconds := written(repository)
+
+		value := repository.Remember("foo", func(value any) any { return value.(int) * 60 }, func() any { return 2 })
+		assert.Equal(t, 2, value)
+		assert.Equal(t, []int{120}, *seconds)
+	})
+}
@@ -0,0 +1,188 @@
+package cache_test
+
+import (
+	"encoding/gob"
+	"testing"
+	"time"
+
+	"github.com/garavel-core/framework/cache"
+	contracts "github.com/garavel-core/framework/contracts/cache"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type serializedProfile struct {
+	Name     string
+	Age      int
+	Tags     []string
+	Scores   map[string]float64
+	Created  time.Time
+	Manager  *serializedProfile
+	internal string
+}
+
+func init() {
+	for _, value := range []any{serializedProfile{}, &serializedProfile{}} {
+		if err := cache.RegisterType(value); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func TestSerializers(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 30, 0, 500, time.UTC)
+	profile := serializedProfile{
+		Name:    "Taylor",
+		Age:     38,
+		Tags:    []string{"admin", "owner"},
+		Scores:  map[string]float64{"go": 9.5},
+		Created: created,
+		Manager: &serializedProfile{Name: "Abigail"},
+	}
+
+	serializers := map[string]contracts.Serializer{
+		"gob":     cache.GobSerializer{},
+		"json":    cache.NewJSONSerializer(),
+		"msgpack": cache.NewMsgpackSerializer(),
+	}
+
+	for name, serializer := range serializers {
+		t.Run(name, func(t *testing.T) {
+			roundTrip := func(value any) any {
+				data, err := serializer.Serialize(value)
+				assert.NoError(t, err)
+				result, err := serializer.Unserialize(data)
+				assert.NoError(t, err)
+				return result
+			}
+
+			assert.Equal(t, 42, roundTrip(42))
+			assert.Equal(t, int64(-7), roundTrip(int64(-7)))
+			assert.Equal(t, uint8(200), roundTrip(uint8(200)))
+			assert.Equal(t, 1.5, roundTrip(1.5))
+			assert.Equal(t, "bar", roundTrip("bar"))
+			assert.Equal(t, true, roundTrip(true))
+			assert.Equal(t, []byte("bytes"), roundTrip([]byte("bytes")))
+			assert.Equal(t, []string{"a", "b"}, roundTrip([]string{"a", "b"}))
+			assert.Equal(t, 90*time.Second, roundTrip(90*time.Second))
+			assert.Nil(t, roundTrip(nil))
+
+			decoded, ok := roundTrip(created).(time.Time)
+			assert.True(t, ok)
+			assert.True(t, created.Equal(decoded))
+
+			result, ok := roundTrip(profile).(serializedProfile)
+			assert.True(t, ok)
+			assert.Equal(t, profile.Name, result.Name)
+			assert.Equal(t, profile.Age, result.Age)
+			assert.Equal(t, profile.Tags, result.Tags)
+			assert.Equal(t, profile.Scores, result.Scores)
+			assert.True(t, profile.Created.Equal(result.Created))
+			assert.Equal(t, "Abigail", result.Manager.Name)
+
+			// Gob flattens pointers, so they decode into the values they point to.
+			if name == "gob" {
+				assert.IsType(t, serializedProfile{}, roundTrip(&profile))
+			} else {
+				pointer, ok := roundTrip(&profile).(*serializedProfile)
+				assert.True(t, ok)
+				assert.Equal(t, "Taylor", pointer.Name)
+			}
+
+			_, err := serializer.Unserialize([]byte("garbage"))
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("corrupt msgpack lengths are rejected", func(t *testing.T) {
+		_, err := cache.NewMsgpackSerializer().Unserialize([]byte{0x92, 0xa0, 0xdd, 0x7f, 0xff, 0xff, 0xff})
+		assert.Error(t, err)
+	})
+
+	t.Run("unregistered types decode generically", func(t *testing.T) {
+		type unregistered struct {
+			Name  string
+			Count int
+		}
+
+		data, _ := cache.NewMsgpackSerializer().Serialize(unregistered{Name: "foo", Count: 3})
+		value, err := cache.NewMsgpackSerializer().Unserialize(data)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{"Name": "foo", "Count": int64(3)}, value)
+
+		data, _ = cache.NewJSONSerializer().Serialize(unregistered{Name: "foo", Count: 3})
+		value, err = cache.NewJSONSerializer().Unserialize(data)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{"Name": "foo", "Count": float64(3)}, value)
+	})
+
+	t.Run("conflicting gob registrations are reported", func(t *testing.T) {
+		type renamed struct{ Name string }
+
+		gob.RegisterName("renamed", renamed{})
+
+		err := cache.NewTypeRegistry().Register(&renamed{})
+		assert.ErrorContains(t, err, "cannot be registered")
+	})
+
+	t.Run("serializers use their own type registry", func(t *testing.T) {
+		type point struct{ X, Y int }
+
+		registry := cache.NewTypeRegistry()
+		assert.NoError(t, registry.Register(point{}, "point"))
+		serializer := cache.NewJSONSerializer(registry)
+		data, err := serializer.Serialize(point{X: 1, Y: 2})
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"type":"point","value":{"X":1,"Y":2}}`, string(data))
+
+		value, err := serializer.Unserialize(data)
+		assert.NoError(t, err)
+		assert.Equal(t, point{X: 1, Y: 2}, value)
+
+		_, err = cache.NewJSONSerializer(cache.NewTypeRegistry()).Unserialize(data)
+		assert.EqualError(t, err, "cache: type [point] is not registered")
+	})
+
+	t.Run("stores use the configured serializer", func(t *testing.T) {
+		stores := map[string]interface {
+			contracts.Store
+		}{
+			"file":  cache.NewFileStore(t.TempDir()).SetSerializer(cache.NewMsgpackSerializer()),
+			"array": cache.NewArrayStore(true).SetSerializer(cache.NewJSONSerializer()),
+		}
+
+		redisStore, _ := newRedisStore(t)
+		stores["redis"] = redisStore.SetSerializer(cache.NewMsgpackSerializer())
+
+		memcachedStore, _ := newMemcachedStore(t)
+		stores["memcached"] = memcachedStore.SetSerializer(cache.NewJSONSerializer())
+
+		for name, store := range stores {
+			assert.True(t, store.Put("profile", profile, 10), name)
+			result, ok := store.Get("profile").(serializedProfile)
+			assert.True(t, ok, name)
+			assert.Equal(t, "Taylor", result.Name, name)
+		}
+	})
+
+	t.Run("the manager configures the serializer", func(t *testing.T) {
+		manager := cache.NewManager(map[string]any{
+			"stores": map[string]any{
+				"file":  map[string]any{"driver": "file", "path": t.TempDir(), "serializer": "msgpack"},
+				"array": map[string]any{"driver": "array", "serialize": true, "serializer": cache.NewJSONSerializer()},
+				"bad":   map[string]any{"driver": "array", "serializer": "yaml"},
+			},
+		})
+
+		repository, err := manager.Store("file")
+		assert.NoError(t, err)
+		assert.IsType(t, &cache.MsgpackSerializer{}, repository.GetStore().(*cache.FileStore).GetSerializer())
+
+		repository, err = manager.Store("array")
+		assert.NoError(t, err)
+		assert.IsType(t, &cache.JSONSerializer{}, repository.GetStore().(*cache.ArrayStore).GetSerializer())
+
+		_, err = manager.Store("bad")
+		assert.EqualError(t, err, "serializer [yaml] is not supported")
+	})
+}
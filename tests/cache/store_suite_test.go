@@ -0,0 +1,87 @@
+package cache_test
+
+import (
+	"testing"
+
+	"github.com/garavel-core/framework/cache"
+	"github.com/garavel-core/framework/cache/cachetest"
+	contracts "github.com/garavel-core/framework/contracts/cache"
+)
+
+func TestStoreSuite(t *testing.T) {
+	t.Run("array", func(t *testing.T) {
+		cachetest.RunStoreSuite(t, func(t *testing.T, config cachetest.Config) contracts.Store {
+			return cache.NewArrayStore().SetClock(config.Clock.Now)
+		})
+	})
+
+	t.Run("serialized array", func(t *testing.T) {
+		cachetest.RunStoreSuite(t, func(t *testing.T, config cachetest.Config) contracts.Store {
+			return cache.NewArrayStore(true).SetClock(config.Clock.Now)
+		})
+	})
+
+	t.Run("memory", func(t *testing.T) {
+		cachetest.RunStoreSuite(t, func(t *testing.T, config cachetest.Config) contracts.Store {
+			return cache.NewMemoryStore().SetClock(config.Clock.Now)
+		})
+	})
+
+	t.Run("file", func(t *testing.T) {
+		cachetest.RunStoreSuite(t, func(t *testing.T, config cachetest.Config) contracts.Store {
+			return cache.NewFileStore(t.TempDir()).SetClock(config.Clock.Now)
+		})
+	})
+
+	t.Run("database", func(t *testing.T) {
+		cachetest.RunStoreSuite(t, func(t *testing.T, config cachetest.Config) contracts.Store {
+			store, _ := newDatabaseStore(t, cache.SQLiteGrammar, config.Prefix)
+
+			return store.SetClock(config.Clock.Now)
+		})
+	})
+
+	t.Run("redis", func(t *testing.T) {
+		cachetest.RunStoreSuite(t, func(t *testing.T, config cachetest.Config) contracts.Store {
+			store, server := newRedisStore(t, config.Prefix)
+			server.SetClock(config.Clock.Now)
+
+			return store.SetClock(config.Clock.Now)
+		})
+	})
+
+	t.Run("memcached", func(t *testing.T) {
+		cachetest.RunStoreSuite(t, func(t *testing.T, config cachetest.Config) contracts.Store {
+			store, server := newMemcachedStore(t, config.Prefix)
+			server.SetClock(config.Clock.Now)
+
+			return store.SetClock(config.Clock.Now)
+		})
+	})
+
+	t.Run("tiered", func(t *testing.T) {
+		cachetest.RunStoreSuite(t, func(t *testing.T, config cachetest.Config) contracts.Store {
+			local := cache.NewArrayStore().SetClock(config.Clock.Now)
+			remote := cache.NewArrayStore().SetClock(config.Clock.Now)
+
+			return cache.NewTieredStore(local, remote).SetClock(config.Clock.Now)
+		})
+	})
+
+	t.Run("failover", func(t *testing.T) {
+		cachetest.RunStoreSuite(t, func(t *testing.T, config cachetest.Config) contracts.Store {
+			primary := cache.NewArrayStore().SetClock(config.Clock.Now)
+			secondary := cache.NewArrayStore().SetClock(config.Clock.Now)
+
+			return cache.NewFailoverStore(primary, secondary).SetClock(config.Clock.Now)
+		})
+	})
+
+	t.Run("transforming", func(t *testing.T) {
+		cachetest.RunStoreSuite(t, func(t *testing.T, config cachetest.Config) contracts.Store {
+			inner := cache.NewArrayStore().SetClock(config.Clock.Now)
+
+			return cache.NewTransformingStore(inner).Compress(cache.GzipCompression, 16).SetClock(config.Clock.Now)
+		})
+	})
+}
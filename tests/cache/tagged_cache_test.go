@@ -0,0 +1,81 @@
+package cache_test
+
+import (
+	"testing"
+
+	"github.com/garavel-core/framework/cache"
+	contracts "github.com/garavel-core/framework/contracts/cache"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaggedCache(t *testing.T) {
+	stores := map[string]func(t *testing.T) contracts.Store{
+		"array": func(t *testing.T) contracts.Store {
+			return cache.NewArrayStore()
+		},
+		"redis": func(t *testing.T) contracts.Store {
+			store, _ := newRedisStore(t)
+			return store
+		},
+	}
+
+	for name, factory := range stores {
+		t.Run(name, func(t *testing.T) {
+			t.Run("items are scoped by their tags", func(t *testing.T) {
+				repository := cache.NewRepository(factory(t))
+				people, err := repository.Tags("people")
+				assert.NoError(t, err)
+				authors, _ := repository.Tags("people", "authors")
+
+				assert.True(t, people.Forever("name", "Taylor"))
+				assert.True(t, authors.Forever("name", "Anne"))
+
+				assert.Equal(t, "Taylor", people.Get("name"))
+				assert.Equal(t, "Anne", authors.Get("name"))
+				assert.Nil(t, repository.Get("name"))
+			})
+
+			t.Run("flushing a tag only removes tagged items", func(t *testing.T) {
+				repository := cache.NewRepository(factory(t))
+				people, _ := repository.Tags("people")
+				authors, _ := repository.Tags("people", "authors")
+				books, _ := repository.Tags("books")
+
+				repository.Forever("name", "untagged")
+				people.Forever("name", "Taylor")
+				authors.Forever("name", "Anne")
+				books.Forever("name", "Dune")
+
+				assert.True(t, authors.Flush())
+
+				assert.Nil(t, people.Get("name"))
+				assert.Nil(t, authors.Get("name"))
+				assert.Equal(t, "Dune", books.Get("name"))
+				assert.Equal(t, "untagged", repository.Get("name"))
+			})
+
+			t.Run("many and put many use tagged keys", func(t *testing.T) {
+				repository := cache.NewRepository(factory(t))
+				people, _ := repository.Tags("people")
+				assert.True(t, people.SetMultiple(map[string]any{"foo": "bar", "baz": "qux"}))
+				assert.Equal(t, map[string]any{"foo": "bar", "baz": "qux", "quz": "default"}, people.GetMultiple([]string{"foo", "baz", "quz"}, "default"))
+				assert.Nil(t, repository.Get("foo"))
+			})
+
+			t.Run("tagged values can be incremented", func(t *testing.T) {
+				repository := cache.NewRepository(factory(t))
+				people, _ := repository.Tags("people")
+				assert.Equal(t, 1, people.Increment("votes"))
+				assert.Equal(t, 3, people.Increment("votes", 2))
+				assert.Equal(t, 3, people.Get("votes"))
+			})
+		})
+	}
+
+	t.Run("stores without tags support return an error", func(t *testing.T) {
+		repository := cache.NewRepository(cache.NewFileStore(t.TempDir()))
+		_, err := repository.Tags("people")
+		assert.ErrorIs(t, err, cache.ErrTaggingNotSupported)
+	})
+}
@@ -0,0 +1,132 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/garavel-core/framework/cache"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// A remote store recording the keys it is asked for.
+type recordingStore struct {
+	*cache.ArrayStore
+	gets  []string
+	manys [][]string
+}
+
+func (s *recordingStore) Get(key string) any {
+	s.gets = append(s.gets, key)
+
+	return s.ArrayStore.Get(key)
+}
+
+func (s *recordingStore) Many(keys []string) map[string]any {
+	s.manys = append(s.manys, keys)
+
+	return s.ArrayStore.Many(keys)
+}
+
+func TestTieredStore(t *testing.T) {
+	newTieredStore := func() (*cache.TieredStore, *cache.ArrayStore, *recordingStore) {
+		local := cache.NewArrayStore()
+		remote := &recordingStore{ArrayStore: cache.NewArrayStore()}
+
+		return cache.NewTieredStore(local, remote), local, remote
+	}
+
+	t.Run("reads are answered locally once backfilled", func(t *testing.T) {
+		store, local, remote := newTieredStore()
+		remote.Put("foo", "bar", 60)
+
+		assert.Equal(t, "bar", store.Get("foo"))
+		assert.Equal(t, "bar", local.Get("foo"))
+		assert.Equal(t, "bar", store.Get("foo"))
+		assert.Equal(t, []string{"foo"}, remote.gets)
+	})
+
+	t.Run("backfilled values expire after the local ttl", func(t *testing.T) {
+		store, local, remote := newTieredStore()
+		store.SetLocalTTL(50 * time.Millisecond)
+		remote.Put("foo", "bar", 60)
+
+		store.Get("foo")
+		time.Sleep(60 * time.Millisecond)
+		assert.Nil(t, local.Get("foo"))
+
+		remote.Put("foo", "baz", 60)
+		assert.Equal(t, "baz", store.Get("foo"))
+	})
+
+	t.Run("many fetches only local misses", func(t *testing.T) {
+		store, local, remote := newTieredStore()
+		local.Put("a", 1, 60)
+		remote.Put("b", 2, 60)
+
+		assert.Equal(t, map[string]any{"a": 1, "b": 2, "c": nil}, store.Many([]string{"a", "b", "c"}))
+		assert.Equal(t, [][]string{{"b", "c"}}, remote.manys)
+		assert.Equal(t, 2, local.Get("b"))
+
+		store.Many([]string{"a", "b"})
+		assert.Len(t, remote.manys, 1)
+	})
+
+	t.Run("writes reach both levels", func(t *testing.T) {
+		store, local, remote := newTieredStore()
+		assert.True(t, store.Put("foo", "bar", 10))
+		assert.True(t, store.PutMany(map[string]any{"a": 1}, 10))
+		assert.True(t, store.Forever("forever", "value"))
+		assert.True(t, store.Add("added", "value", 10))
+		assert.False(t, store.Add("added", "other", 10))
+
+		for _, key := range []string{"foo", "a", "forever", "added"} {
+			assert.NotNil(t, local.Get(key), key)
+			assert.NotNil(t, remote.ArrayStore.Get(key), key)
+		}
+
+		assert.True(t, store.Forget("foo"))
+		assert.Nil(t, local.Get("foo"))
+		assert.Nil(t, remote.ArrayStore.Get("foo"))
+
+		assert.True(t, store.Flush())
+		assert.Nil(t, local.Get("a"))
+		assert.Nil(t, remote.ArrayStore.Get("a"))
+	})
+
+	t.Run("the local copy never outlives the remote one", func(t *testing.T) {
+		store, local, _ := newTieredStore()
+		repository := cache.NewRepository(store)
+		repository.Put("foo", "bar", 30*time.Millisecond)
+		time.Sleep(40 * time.Millisecond)
+		assert.Nil(t, local.Get("foo"))
+		assert.Nil(t, repository.Get("foo"))
+	})
+
+	t.Run("counters are kept remotely", func(t *testing.T) {
+		store, local, remote := newTieredStore()
+		assert.Equal(t, 1, store.Increment("count"))
+		assert.Equal(t, 3, store.Increment("count", 2))
+		assert.Equal(t, 2, store.Decrement("count"))
+		assert.Equal(t, 2, local.Get("count"))
+		assert.Equal(t, 2, remote.ArrayStore.Get("count"))
+	})
+
+	t.Run("the store works behind a repository over redis", func(t *testing.T) {
+		redisStore, server := newRedisStore(t)
+		repository := cache.NewRepository(cache.NewTieredStore(cache.NewArrayStore(), redisStore))
+
+		repository.Put("foo", "bar", 10)
+		before := len(server.Commands())
+
+		for i := 0; i < 5; i++ {
+			assert.Equal(t, "bar", repository.Get("foo"))
+		}
+
+		assert.Len(t, server.Commands(), before)
+
+		tagged, _ := repository.Tags("people")
+		tagged.Put("name", "Taylor", 10)
+		assert.Equal(t, "Taylor", tagged.Get("name"))
+	})
+}
@@ -0,0 +1,135 @@
+package cache_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/garavel-core/framework/cache"
+	"github.com/garavel-core/framework/encryption"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformingStore(t *testing.T) {
+	key := bytes.Repeat([]byte("k"), 32)
+	large := strings.Repeat("payload ", 200)
+
+	t.Run("large values are compressed", func(t *testing.T) {
+		for _, compression := range []cache.Compression{cache.GzipCompression, cache.FlateCompression} {
+			inner := cache.NewArrayStore()
+			store := cache.NewTransformingStore(inner).Compress(compression, 256)
+
+			assert.True(t, store.Put("large", large, 10))
+			assert.True(t, store.Put("small", "small", 10))
+			assert.Equal(t, large, store.Get("large"))
+			assert.Equal(t, "small", store.Get("small"))
+
+			raw := inner.Get("large").([]byte)
+			assert.Equal(t, byte(compression), raw[0])
+			assert.Less(t, len(raw), len(large)/4)
+			assert.Equal(t, byte(cache.NoCompression), inner.Get("small").([]byte)[0])
+		}
+	})
+
+	t.Run("values are encrypted", func(t *testing.T) {
+		encrypter, _ := encryption.NewEncrypter(key)
+		redisStore, _ := newRedisStore(t)
+		store := cache.NewTransformingStore(redisStore).Encrypt(encrypter).Compress(cache.GzipCompression, 256)
+
+		assert.True(t, store.Put("email", "taylor@example.com", 10))
+		assert.True(t, store.Put("large", large, 10))
+		assert.Equal(t, "taylor@example.com", store.Get("email"))
+		assert.Equal(t, large, store.Get("large"))
+		assert.Equal(t, map[string]any{"email": "taylor@example.com", "missing": nil}, store.Many([]string{"email", "missing"}))
+
+		raw := redisStore.Get("email").([]byte)
+		assert.False(t, bytes.Contains(raw, []byte("taylor")))
+	})
+
+	t.Run("rotated keys still read older values", func(t *testing.T) {
+		inner := cache.NewArrayStore()
+		old, _ := encryption.NewEncrypter(key)
+		cache.NewTransformingStore(inner).Encrypt(old).Forever("foo", "bar")
+
+		newKey := bytes.Repeat([]byte("n"), 32)
+		rotated, _ := encryption.NewEncrypter(newKey, key)
+		assert.Equal(t, "bar", cache.NewTransformingStore(inner).Encrypt(rotated).Get("foo"))
+
+		// Without the old key the value is treated as missing.
+		fresh, _ := encryption.NewEncrypter(newKey)
+		assert.Nil(t, cache.NewTransformingStore(inner).Encrypt(fresh).Get("foo"))
+	})
+
+	t.Run("encrypted values are bound to their key", func(t *testing.T) {
+		inner := cache.NewArrayStore()
+		encrypter, _ := encryption.NewEncrypter(key)
+		store := cache.NewTransformingStore(inner).Encrypt(encrypter)
+
+		assert.True(t, store.Forever("alice", "admin"))
+		inner.Forever("bob", inner.Get("alice"))
+		assert.Nil(t, store.Get("bob"))
+
+		// Flipping the header to claim another compression breaks the value too.
+		raw := append([]byte(nil), inner.Get("alice").([]byte)...)
+		raw[0] |= byte(cache.GzipCompression)
+		inner.Forever("alice", raw)
+		assert.Nil(t, store.Get("alice"))
+	})
+
+	t.Run("decompression is limited", func(t *testing.T) {
+		inner := cache.NewArrayStore()
+		store := cache.NewTransformingStore(inner).Compress(cache.GzipCompression, 0)
+
+		assert.True(t, store.Forever("large", large))
+		assert.Equal(t, large, store.Get("large"))
+
+		store.LimitDecompression(len(large) / 2)
+		assert.Nil(t, store.Get("large"))
+	})
+
+	t.Run("counters are kept in plain text", func(t *testing.T) {
+		inner := cache.NewArrayStore()
+		encrypter, _ := encryption.NewEncrypter(key)
+		store := cache.NewTransformingStore(inner).Encrypt(encrypter)
+
+		assert.Equal(t, 3, store.Increment("counter", 3))
+		assert.Equal(t, 3, inner.Get("counter"))
+		assert.Equal(t, 3, store.Get("counter"))
+	})
+
+	t.Run("the store is transparent to repositories", func(t *testing.T) {
+		encrypter, _ := encryption.NewEncrypter(key)
+		store := cache.NewTransformingStore(cache.NewFileStore(t.TempDir())).Encrypt(encrypter).Compress(cache.FlateCompression, 0)
+		repository := cache.NewRepository(store)
+
+		assert.True(t, repository.Put("foo", map[string]any{"bar": "baz"}, 10))
+		assert.Equal(t, map[string]any{"bar": "baz"}, repository.Get("foo"))
+		assert.True(t, repository.Add("added", "value", 10))
+		assert.False(t, repository.Add("added", "other", 10))
+		assert.Equal(t, 5, repository.Increment("counter", 5))
+		assert.Equal(t, 5, repository.Get("counter"))
+		assert.Equal(t, "value", repository.Remember("remembered", time.Minute, func() any { return "value" }))
+
+		tagged, err := repository.Tags("people")
+		assert.NoError(t, err)
+		tagged.Put("name", "Taylor", 10)
+		assert.Equal(t, "Taylor", tagged.Get("name"))
+		tagged.Flush()
+		assert.Nil(t, tagged.Get("name"))
+
+		lock := store.Lock("processing", 10)
+		assert.Equal(t, true, lock.Get())
+		assert.Equal(t, false, store.Lock("processing", 10).Get())
+		assert.True(t, lock.Release())
+	})
+
+	t.Run("sub-second ttls reach precise stores", func(t *testing.T) {
+		repository := cache.NewRepository(cache.NewTransformingStore(cache.NewArrayStore()))
+		assert.True(t, repository.Put("foo", "bar", 50*time.Millisecond))
+		assert.Equal(t, "bar", repository.Get("foo"))
+		time.Sleep(60 * time.Millisecond)
+		assert.Nil(t, repository.Get("foo"))
+	})
+}
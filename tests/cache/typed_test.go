@@ -0,0 +1,144 @@
+package cache_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/garavel-core/framework/cache"
+	contracts "github.com/garavel-core/framework/contracts/cache"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type typedUser struct {
+	ID    int
+	Name  string
+	Roles []string
+}
+
+// Create a typed cache over the given repository.
+func newTyped[T any](t *testing.T, repository *cache.Repository, serializer ...contracts.Serializer) *cache.Typed[T] {
+	typed, err := cache.NewTyped[T](repository, serializer...)
+	assert.NoError(t, err)
+
+	return typed
+}
+
+func TestTyped(t *testing.T) {
+	stores := map[string]func(t *testing.T) contracts.Store{
+		"array": func(t *testing.T) contracts.Store {
+			return cache.NewArrayStore()
+		},
+		"serializing array": func(t *testing.T) contracts.Store {
+			return cache.NewArrayStore(true)
+		},
+		"file": func(t *testing.T) contracts.Store {
+			return cache.NewFileStore(t.TempDir())
+		},
+		"redis": func(t *testing.T) contracts.Store {
+			store, _ := newRedisStore(t)
+			return store
+		},
+	}
+	serializers := map[string]contracts.Serializer{"gob": cache.GobSerializer{}, "json": cache.NewJSONSerializer(), "msgpack": cache.NewMsgpackSerializer()}
+	user := typedUser{ID: 1, Name: "Taylor", Roles: []string{"admin"}}
+
+	for name, store := range stores {
+		for serializerName, serializer := range serializers {
+			t.Run(name+" with "+serializerName, func(t *testing.T) {
+				users := newTyped[typedUser](t, cache.NewRepository(store(t)), serializer)
+
+				value, found, err := users.Get("user")
+				assert.NoError(t, err)
+				assert.False(t, found)
+				assert.Equal(t, typedUser{}, value)
+
+				stored, err := users.Put("user", user, 10)
+				assert.NoError(t, err)
+				assert.True(t, stored)
+
+				value, found, err = users.Get("user")
+				assert.NoError(t, err)
+				assert.True(t, found)
+				assert.Equal(t, user, value)
+
+				values, err := users.Many([]string{"user", "missing"})
+				assert.NoError(t, err)
+				assert.Equal(t, map[string]typedUser{"user": user}, values)
+			})
+		}
+	}
+
+	t.Run("remember stores the result of the callback", func(t *testing.T) {
+		users := newTyped[typedUser](t, cache.NewRepository(cache.NewArrayStore()))
+		value, err := users.Remember("user", 10, func() (typedUser, error) {
+			return user, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, user, value)
+
+		value, err = users.Remember("user", 10, func() (typedUser, error) {
+			t.Error("the callback should not run")
+
+			return typedUser{}, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, user, value)
+	})
+
+	t.Run("errors of the callback are not stored", func(t *testing.T) {
+		users := newTyped[typedUser](t, cache.NewRepository(cache.NewArrayStore()))
+		failure := errors.New("failure")
+		_, err := users.RememberForever("user", func() (typedUser, error) {
+			return typedUser{}, failure
+		})
+		assert.ErrorIs(t, err, failure)
+		assert.False(t, users.Has("user"))
+	})
+
+	t.Run("items not written by a typed cache are reported", func(t *testing.T) {
+		repository := cache.NewRepository(cache.NewArrayStore())
+		repository.Put("user", map[string]any{"ID": 1}, 10)
+		users := newTyped[typedUser](t, repository)
+
+		_, found, err := users.Get("user")
+		assert.True(t, found)
+		assert.ErrorIs(t, err, cache.ErrNotEncoded)
+
+		value, err := users.Remember("user", 10, func() (typedUser, error) {
+			return user, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, user, value)
+	})
+
+	t.Run("scalar types round-trip", func(t *testing.T) {
+		counts := newTyped[int64](t, cache.NewRepository(cache.NewFileStore(t.TempDir())))
+		counts.Forever("count", 42)
+		value, found, err := counts.Pull("count")
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, int64(42), value)
+		assert.False(t, counts.Has("count"))
+	})
+
+	t.Run("pointers round-trip", func(t *testing.T) {
+		users := newTyped[*typedUser](t, cache.NewRepository(cache.NewFileStore(t.TempDir())))
+		_, err := users.Put("user", &user, 10)
+		assert.NoError(t, err)
+
+		value, found, err := users.Get("user")
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, &user, value)
+	})
+
+	t.Run("items of another type are reported", func(t *testing.T) {
+		repository := cache.NewRepository(cache.NewArrayStore())
+		newTyped[string](t, repository).Put("user", "Taylor", 10)
+
+		_, found, err := newTyped[typedUser](t, repository).Get("user")
+		assert.True(t, found)
+		assert.ErrorContains(t, err, "got a string")
+	})
+}
@@ -0,0 +1,91 @@
+package encryption_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/garavel-core/framework/encryption"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncrypter(t *testing.T) {
+	t.Run("values round-trip", func(t *testing.T) {
+		for _, size := range []int{16, 24, 32} {
+			key, err := encryption.GenerateKey(size)
+			assert.NoError(t, err)
+			encrypter, err := encryption.NewEncrypter(key)
+			assert.NoError(t, err)
+
+			payload, err := encrypter.Encrypt([]byte("secret"))
+			assert.NoError(t, err)
+			assert.False(t, bytes.Contains(payload, []byte("secret")))
+
+			value, err := encrypter.Decrypt(payload)
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("secret"), value)
+		}
+	})
+
+	t.Run("payloads are never reused", func(t *testing.T) {
+		encrypter, _ := encryption.NewEncrypter(bytes.Repeat([]byte("a"), 32))
+		first, _ := encrypter.Encrypt([]byte("secret"))
+		second, _ := encrypter.Encrypt([]byte("secret"))
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("invalid keys are rejected", func(t *testing.T) {
+		_, err := encryption.NewEncrypter([]byte("short"))
+		assert.Error(t, err)
+		_, err = encryption.NewEncrypter(bytes.Repeat([]byte("a"), 32), []byte("short"))
+		assert.Error(t, err)
+		assert.False(t, encryption.Supported([]byte("short")))
+	})
+
+	t.Run("tampered payloads fail to decrypt", func(t *testing.T) {
+		encrypter, _ := encryption.NewEncrypter(bytes.Repeat([]byte("a"), 32))
+		payload, _ := encrypter.Encrypt([]byte("secret"))
+		payload[len(payload)-1] ^= 1
+		_, err := encrypter.Decrypt(payload)
+		assert.ErrorIs(t, err, encryption.ErrDecrypt)
+		_, err = encrypter.Decrypt([]byte("x"))
+		assert.ErrorIs(t, err, encryption.ErrDecrypt)
+	})
+
+	t.Run("previous keys still decrypt", func(t *testing.T) {
+		oldKey := bytes.Repeat([]byte("a"), 32)
+		newKey := bytes.Repeat([]byte("b"), 16)
+		old, _ := encryption.NewEncrypter(oldKey)
+		payload, _ := old.Encrypt([]byte("secret"))
+
+		rotated, err := encryption.NewEncrypter(newKey, oldKey)
+		assert.NoError(t, err)
+		value, err := rotated.Decrypt(payload)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("secret"), value)
+
+		assert.Equal(t, newKey, rotated.GetKey())
+		assert.Equal(t, [][]byte{oldKey}, rotated.GetPreviousKeys())
+		assert.Equal(t, [][]byte{newKey, oldKey}, rotated.GetAllKeys())
+
+		// Values are encrypted with the new key only.
+		payload, _ = rotated.Encrypt([]byte("secret"))
+		_, err = old.Decrypt(payload)
+		assert.ErrorIs(t, err, encryption.ErrDecrypt)
+	})
+
+	t.Run("additional data is authenticated", func(t *testing.T) {
+		encrypter, _ := encryption.NewEncrypter(bytes.Repeat([]byte("a"), 32))
+		payload, err := encrypter.Encrypt([]byte("secret"), []byte("users:1"))
+		assert.NoError(t, err)
+
+		value, err := encrypter.Decrypt(payload, []byte("users:1"))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("secret"), value)
+
+		_, err = encrypter.Decrypt(payload, []byte("users:2"))
+		assert.ErrorIs(t, err, encryption.ErrDecrypt)
+		_, err = encrypter.Decrypt(payload)
+		assert.ErrorIs(t, err, encryption.ErrDecrypt)
+	})
+}
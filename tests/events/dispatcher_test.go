@@ -0,0 +1,100 @@
+package events_test
+
+import (
+	"testing"
+
+	contracts "github.com/garavel-core/framework/contracts/events"
+	"github.com/garavel-core/framework/events"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type orderShipped struct {
+	ID int
+}
+
+type orderSubscriber struct {
+	handled []string
+}
+
+func (s *orderSubscriber) Subscribe(dispatcher contracts.Dispatcher) {
+	dispatcher.Listen("order.created", func(event string, payload ...any) any {
+		s.handled = append(s.handled, event)
+		return nil
+	})
+}
+
+func TestDispatcher(t *testing.T) {
+	t.Run("listeners receive the payload", func(t *testing.T) {
+		dispatcher := events.NewDispatcher()
+		dispatcher.Listen("foo", func(event string, payload ...any) any {
+			return payload[0].(string) + "!"
+		})
+		assert.True(t, dispatcher.HasListeners("foo"))
+		assert.Equal(t, []any{"bar!"}, dispatcher.Dispatch("foo", "bar"))
+	})
+
+	t.Run("wildcard listeners match event names", func(t *testing.T) {
+		dispatcher := events.NewDispatcher()
+		var names []string
+		dispatcher.Listen("order.*", func(event string, payload ...any) any {
+			names = append(names, event)
+			return nil
+		})
+		dispatcher.Dispatch("order.created")
+		dispatcher.Dispatch("order.shipped")
+		dispatcher.Dispatch("user.created")
+		assert.Equal(t, []string{"order.created", "order.shipped"}, names)
+		assert.True(t, dispatcher.HasListeners("order.paid"))
+	})
+
+	t.Run("returning false halts propagation", func(t *testing.T) {
+		dispatcher := events.NewDispatcher()
+		called := 0
+		dispatcher.Listen("foo", func(event string, payload ...any) any {
+			called++
+			return false
+		})
+		dispatcher.Listen("foo", func(event string, payload ...any) any {
+			called++
+			return nil
+		})
+		dispatcher.Dispatch("foo")
+		assert.Equal(t, 1, called)
+	})
+
+	t.Run("until returns the first response", func(t *testing.T) {
+		dispatcher := events.NewDispatcher()
+		dispatcher.Listen("foo", func(event string, payload ...any) any { return nil })
+		dispatcher.Listen("foo", func(event string, payload ...any) any { return "first" })
+		dispatcher.Listen("foo", func(event string, payload ...any) any { return "second" })
+		assert.Equal(t, "first", dispatcher.Until("foo"))
+	})
+
+	t.Run("event objects are dispatched by type name", func(t *testing.T) {
+		dispatcher := events.NewDispatcher()
+		var shipped []int
+		events.ListenTo(dispatcher, func(event orderShipped) {
+			shipped = append(shipped, event.ID)
+		})
+		dispatcher.Dispatch(orderShipped{ID: 7})
+		assert.Equal(t, []int{7}, shipped)
+		assert.Equal(t, "github.com/garavel-core/framework/tests/events_test.orderShipped", events.Name(orderShipped{}))
+	})
+
+	t.Run("subscribers register their listeners", func(t *testing.T) {
+		dispatcher := events.NewDispatcher()
+		subscriber := &orderSubscriber{}
+		dispatcher.Subscribe(subscriber)
+		dispatcher.Dispatch("order.created")
+		assert.Equal(t, []string{"order.created"}, subscriber.handled)
+	})
+
+	t.Run("listeners can be forgotten", func(t *testing.T) {
+		dispatcher := events.NewDispatcher()
+		dispatcher.Listen("foo.*", func(event string, payload ...any) any { return "wild" })
+		assert.Equal(t, []any{"wild"}, dispatcher.Dispatch("foo.bar"))
+		dispatcher.Forget("foo.*")
+		assert.Empty(t, dispatcher.Dispatch("foo.bar"))
+	})
+}
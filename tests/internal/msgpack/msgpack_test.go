@@ -0,0 +1,206 @@
+package msgpack_test
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/garavel-core/framework/internal/msgpack"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshal(t *testing.T) {
+	encodings := []struct {
+		value any
+		bytes []byte
+	}{
+		{nil, []byte{0xc0}},
+		{true, []byte{0xc3}},
+		{5, []byte{0x05}},
+		{-5, []byte{0xfb}},
+		{200, []byte{0xcc, 0xc8}},
+		{-200, []byte{0xd1, 0xff, 0x38}},
+		{70000, []byte{0xce, 0x00, 0x01, 0x11, 0x70}},
+		{1.5, []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{"abc", []byte{0xa3, 'a', 'b', 'c'}},
+		{[]byte{1, 2}, []byte{0xc4, 0x02, 0x01, 0x02}},
+		{[]int{1, 2}, []byte{0x92, 0x01, 0x02}},
+		{map[string]int{"b": 2, "a": 1}, []byte{0x82, 0xa1, 'a', 0x01, 0xa1, 'b', 0x02}},
+		{time.Unix(1, 2), []byte{0xc7, 0x0c, 0xff, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 1}},
+	}
+
+	for _, encoding := range encodings {
+		data, err := msgpack.Marshal(encoding.value)
+		assert.NoError(t, err)
+		assert.Equal(t, encoding.bytes, data, "%v", encoding.value)
+	}
+
+	t.Run("structs honor tags", func(t *testing.T) {
+		type tagged struct {
+			Name    string `msgpack:"name"`
+			Skipped string `msgpack:"-"`
+			Empty   int    `msgpack:",omitempty"`
+		}
+
+		data, err := msgpack.Marshal(tagged{Name: "foo", Skipped: "bar"})
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0x81, 0xa4, 'n', 'a', 'm', 'e', 0xa3, 'f', 'o', 'o'}, data)
+
+		var decoded tagged
+		assert.NoError(t, msgpack.Unmarshal(data, &decoded))
+		assert.Equal(t, tagged{Name: "foo"}, decoded)
+	})
+
+	t.Run("long values use wider headers", func(t *testing.T) {
+		values := make([]any, 70000)
+
+		for i := range values {
+			values[i] = int64(i)
+		}
+
+		data, err := msgpack.Marshal(values)
+		assert.NoError(t, err)
+		assert.Equal(t, byte(0xdd), data[0])
+
+		var decoded []any
+		assert.NoError(t, msgpack.Unmarshal(data, &decoded))
+		assert.Equal(t, values, decoded)
+	})
+
+	t.Run("invalid data is rejected", func(t *testing.T) {
+		var value any
+		assert.ErrorIs(t, msgpack.Unmarshal([]byte{0xa3, 'a'}, &value), msgpack.ErrShortData)
+		assert.Error(t, msgpack.Unmarshal([]byte{0xc1}, &value))
+		assert.Error(t, msgpack.Unmarshal([]byte{0x01, 0x02}, &value))
+
+		var number int8
+		assert.Error(t, msgpack.Unmarshal([]byte{0xcc, 0xc8}, &number))
+	})
+}
+
+func TestUnmarshal(t *testing.T) {
+	decodings := []struct {
+		bytes []byte
+		value any
+	}{
+		{[]byte{0xc0}, nil},
+		{[]byte{0xc2}, false},
+		{[]byte{0x7f}, int64(127)},
+		{[]byte{0xe0}, int64(-32)},
+		{[]byte{0xcd, 0x01, 0x00}, int64(256)},
+		{[]byte{0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, uint64(math.MaxUint64)},
+		{[]byte{0xd2, 0xff, 0xff, 0xff, 0xfe}, int64(-2)},
+		{[]byte{0xca, 0x3f, 0xc0, 0x00, 0x00}, float32(1.5)},
+		{[]byte{0xd9, 0x01, 'a'}, "a"},
+		{[]byte{0xc4, 0x01, 0x07}, []byte{0x07}},
+		{[]byte{0x92, 0x01, 0xa1, 'b'}, []any{int64(1), "b"}},
+		{[]byte{0x81, 0xa1, 'a', 0x01}, map[string]any{"a": int64(1)}},
+		{[]byte{0x81, 0x01, 0xa1, 'a'}, map[any]any{int64(1): "a"}},
+		{[]byte{0xd6, 0xff, 0x00, 0x00, 0x00, 0x01}, time.Unix(1, 0)},
+	}
+
+	for _, decoding := range decodings {
+		var value any
+		assert.NoError(t, msgpack.Unmarshal(decoding.bytes, &value), "%x", decoding.bytes)
+		assert.Equal(t, decoding.value, value, "%x", decoding.bytes)
+	}
+
+	t.Run("values are converted to the target type", func(t *testing.T) {
+		var numbers []uint8
+		assert.NoError(t, msgpack.Unmarshal([]byte{0x92, 0x01, 0x02}, &numbers))
+		assert.Equal(t, []uint8{1, 2}, numbers)
+
+		var scores map[string]float64
+		assert.NoError(t, msgpack.Unmarshal([]byte{0x81, 0xa1, 'a', 0x01}, &scores))
+		assert.Equal(t, map[string]float64{"a": 1}, scores)
+
+		var pointer *string
+		assert.NoError(t, msgpack.Unmarshal([]byte{0xa1, 'a'}, &pointer))
+		assert.Equal(t, "a", *pointer)
+
+		var mismatched string
+		assert.Error(t, msgpack.Unmarshal([]byte{0x01}, &mismatched))
+		assert.Error(t, msgpack.Unmarshal([]byte{0x01}, mismatched))
+	})
+
+	t.Run("lengths beyond the data are rejected", func(t *testing.T) {
+		corrupt := [][]byte{
+			{0x92, 0xa0, 0xdd, 0x7f, 0xff, 0xff, 0xff},
+			{0xdd, 0xff, 0xff, 0xff, 0xff},
+			{0xdc, 0xff, 0xff, 0x01},
+			{0xdf, 0x7f, 0xff, 0xff, 0xff},
+			{0xde, 0x00, 0x02, 0x01, 0x01},
+			{0x9f},
+			{0x8f, 0x01},
+			{0xc6, 0xff, 0xff, 0xff, 0xff},
+			{0xdb, 0x7f, 0xff, 0xff, 0xff},
+			{0xc9, 0x7f, 0xff, 0xff, 0xff, 0xff},
+		}
+
+		for _, data := range corrupt {
+			var value any
+			assert.ErrorIs(t, msgpack.Unmarshal(data, &value), msgpack.ErrShortData, "%x", data)
+		}
+	})
+
+	t.Run("nesting is limited", func(t *testing.T) {
+		var value any
+
+		nested := append(bytes.Repeat([]byte{0x91}, msgpack.MaxDepth), 0xc0)
+		assert.NoError(t, msgpack.Unmarshal(nested, &value))
+
+		nested = append(bytes.Repeat([]byte{0x91}, msgpack.MaxDepth+1), 0xc0)
+		assert.ErrorIs(t, msgpack.Unmarshal(nested, &value), msgpack.ErrTooDeep)
+
+		nested = append(bytes.Repeat([]byte{0x81, 0xa0}, msgpack.MaxDepth+1), 0xc0)
+		assert.ErrorIs(t, msgpack.Unmarshal(nested, &value), msgpack.ErrTooDeep)
+	})
+}
+
+func FuzzUnmarshal(f *testing.F) {
+	seeds := []any{nil, true, int64(-1), uint64(math.MaxUint64), 1.5, "abc", []byte{1}, []any{int64(1), "a"}, map[string]any{"a": []any{nil}}, time.Unix(1, 2)}
+
+	for _, seed := range seeds {
+		data, err := msgpack.Marshal(seed)
+
+		if err != nil {
+			f.Fatal(err)
+		}
+
+		f.Add(data)
+	}
+
+	f.Add([]byte{0x92, 0xa0, 0xdd, 0x7f, 0xff, 0xff, 0xff})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var value any
+
+		if msgpack.Unmarshal(data, &value) != nil {
+			return
+		}
+
+		// Whatever decodes must encode again, into data that decodes and
+		// encodes into the very same bytes.
+		encoded, err := msgpack.Marshal(value)
+
+		if err != nil {
+			t.Fatalf("cannot encode %#v: %v", value, err)
+		}
+
+		var decoded any
+
+		if err := msgpack.Unmarshal(encoded, &decoded); err != nil {
+			t.Fatalf("cannot decode %x: %v", encoded, err)
+		}
+
+		again, err := msgpack.Marshal(decoded)
+
+		if err != nil {
+			t.Fatalf("cannot encode %#v: %v", decoded, err)
+		}
+
+		assert.Equal(t, encoded, again)
+	})
+}